@@ -0,0 +1,147 @@
+package deep
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+
+	"github.com/brunoga/deep/v5/condition"
+	icore "github.com/brunoga/deep/v5/internal/core"
+	"github.com/brunoga/deep/v5/internal/engine"
+)
+
+// Atomic makes [Apply] all-or-nothing: if any operation fails, every
+// operation already applied is rolled back and the target is left exactly as
+// it was. Rollback does not clone the whole target; only the values touched by
+// each operation are saved before it runs.
+func Atomic() ApplyOption {
+	return func(c *applyConfig) { c.atomic = true }
+}
+
+// checkGuard evaluates the global guard of a patch against target.
+func checkGuard[T any](target *T, guard *condition.Condition, logger *slog.Logger) error {
+	if guard == nil {
+		return nil
+	}
+	ok, err := evaluate(target, guard)
+	if err != nil {
+		return fmt.Errorf("global condition evaluation failed: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("global condition not met")
+	}
+	return nil
+}
+
+// evaluate reports whether c holds for target, dispatching to the generated
+// EvaluateCondition method when there is one.
+func evaluate[T any](target *T, c *condition.Condition) (bool, error) {
+	if evaluator, ok := any(target).(interface {
+		EvaluateCondition(condition.Condition) (bool, error)
+	}); ok {
+		return evaluator.EvaluateCondition(*c)
+	}
+	return condition.Evaluate(reflect.ValueOf(target).Elem(), c)
+}
+
+// applyStep applies a single operation to target, dispatching to the
+// generated Patch method when there is one.
+func applyStep[T any](target *T, op Operation, logger *slog.Logger) error {
+	if patcher, ok := any(target).(interface {
+		Patch(Patch[T], *slog.Logger) error
+	}); ok {
+		err := patcher.Patch(Patch[T]{Operations: []Operation{op}, Strict: op.Strict}, logger)
+		if ae, ok := err.(*ApplyError); ok && len(ae.Errors) == 1 {
+			return ae.Errors[0]
+		}
+		return err
+	}
+	return engine.ApplyOpReflectionValue(reflect.ValueOf(target).Elem(), op, logger)
+}
+
+// undoEntry records the value at path before an operation ran, or that there
+// was none.
+type undoEntry struct {
+	path    string
+	val     reflect.Value
+	existed bool
+}
+
+// snapshotOp saves every value op may modify. Structural changes to a slice
+// element save the whole slice, since they shift or reorder its elements.
+func snapshotOp(root reflect.Value, typ reflect.Type, op Operation) []undoEntry {
+	paths := []string{op.Path}
+	switch op.Kind {
+	case OpLog, OpTest:
+		return nil
+	case OpMove:
+		if from, ok := op.Old.(string); ok {
+			paths = append(paths, from)
+		}
+	}
+
+	var res []undoEntry
+	for _, path := range paths {
+		if op.Kind != OpReplace {
+			if parent, ok := parentPath(path); ok {
+				if pt, err := icore.TypeAtPath(typ, parent); err == nil {
+					for pt.Kind() == reflect.Pointer {
+						pt = pt.Elem()
+					}
+					if pt.Kind() == reflect.Slice {
+						path = parent
+					}
+				}
+			}
+		}
+		res = append(res, snapshotPath(root, path))
+	}
+	return res
+}
+
+// snapshotPath saves the value at path. If there is none, it records that the
+// map entry is missing or, failing that, saves the closest existing ancestor.
+func snapshotPath(root reflect.Value, path string) undoEntry {
+	for {
+		val, err := icore.DeepPath(path).Resolve(root)
+		if err == nil && val.IsValid() {
+			return undoEntry{path: path, val: icore.DeepCopyValue(val), existed: true}
+		}
+		parent, ok := parentPath(path)
+		if !ok {
+			return undoEntry{path: "/", val: icore.DeepCopyValue(root), existed: true}
+		}
+		pv, err := icore.DeepPath(parent).Resolve(root)
+		if err == nil && pv.IsValid() {
+			if pv, err = icore.Dereference(pv); err == nil && pv.Kind() == reflect.Map {
+				return undoEntry{path: path}
+			}
+		}
+		path = parent
+	}
+}
+
+// rollback restores the saved values in reverse order.
+func rollback(root reflect.Value, undo []undoEntry) {
+	for i := len(undo) - 1; i >= 0; i-- {
+		u := undo[i]
+		if u.existed {
+			_ = icore.DeepPath(u.path).Set(root, u.val)
+		} else {
+			_ = icore.DeepPath(u.path).Delete(root)
+		}
+	}
+}
+
+// parentPath returns the parent of path, or false for the root.
+func parentPath(path string) (string, bool) {
+	if path == "" || path == "/" {
+		return "", false
+	}
+	idx := strings.LastIndexByte(path, '/')
+	if idx <= 0 {
+		return "/", true
+	}
+	return path[:idx], true
+}
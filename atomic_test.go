@@ -0,0 +1,77 @@
+package deep_test
+
+import (
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+func TestApplyAtomicRollback(t *testing.T) {
+	orig := testmodels.User{
+		ID:    1,
+		Name:  "Alice",
+		Info:  testmodels.Detail{Age: 30, Address: "Home"},
+		Roles: []string{"user"},
+		Score: map[string]int{"a": 1},
+	}
+
+	p := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/full_name", Old: "Alice", New: "Bob"},
+		{Kind: deep.OpReplace, Path: "/info/Age", Old: 30, New: 31},
+		{Kind: deep.OpAdd, Path: "/roles/1", New: "admin"},
+		{Kind: deep.OpAdd, Path: "/score/b", New: 2},
+		{Kind: deep.OpRemove, Path: "/score/a", Old: 1},
+		{Kind: deep.OpReplace, Path: "/missing", New: 1},
+	}}
+
+	u := deep.Clone(orig)
+	if err := deep.Apply(&u, p, deep.Atomic()); err == nil {
+		t.Fatal("expected error")
+	}
+	if !deep.Equal(u, orig) {
+		t.Errorf("target modified after failed atomic apply: %+v", u)
+	}
+
+	// Without the failing operation everything is applied.
+	p.Operations = p.Operations[:len(p.Operations)-1]
+	if err := deep.Apply(&u, p, deep.Atomic()); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if u.Name != "Bob" || u.Info.Age != 31 || len(u.Roles) != 2 || u.Score["b"] != 2 {
+		t.Errorf("unexpected result: %+v", u)
+	}
+}
+
+func TestApplyAtomicRollbackReflection(t *testing.T) {
+	type Item struct {
+		ID   string `deep:"key"`
+		Name string
+	}
+	type Config struct {
+		Name  string
+		Items []Item
+		Tags  map[string]string
+	}
+
+	orig := Config{
+		Name:  "cfg",
+		Items: []Item{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}},
+		Tags:  map[string]string{"env": "prod"},
+	}
+
+	p := deep.Patch[Config]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/Name", Old: "cfg", New: "new"},
+		{Kind: deep.OpRemove, Path: "/Items/a", Old: Item{ID: "a", Name: "A"}},
+		{Kind: deep.OpMove, Path: "/Tags/stage", Old: "/Tags/env"},
+		{Kind: deep.OpReplace, Path: "/Name", Old: "wrong", New: "newer"},
+	}}
+
+	c := deep.Clone(orig)
+	if err := deep.Apply(&c, p.AsStrict(), deep.Atomic()); err == nil {
+		t.Fatal("expected strict check error")
+	}
+	if !deep.Equal(c, orig) {
+		t.Errorf("target modified after failed atomic apply: %+v", c)
+	}
+}
@@ -0,0 +1,543 @@
+package deep
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/brunoga/deep/v5/condition"
+	icore "github.com/brunoga/deep/v5/internal/core"
+)
+
+// binaryVersion is the first byte of every binary-encoded patch.
+const binaryVersion = 1
+
+// Header flags.
+const (
+	binaryStrict byte = 1 << iota
+	binaryFieldIndex
+	binarySigned
+)
+
+// Per-operation flags.
+const (
+	binaryHasOld byte = 1 << iota
+	binaryOldStatic
+	binaryOldPath
+	binaryHasNew
+	binaryNewStatic
+)
+
+// Condition flags.
+const (
+	binaryCondPresent byte = 1 << iota
+	binaryCondRef
+)
+
+// Kinds of condition values.
+const (
+	binaryNoValue byte = iota
+	binaryStaticValue
+	binaryDynamicValue
+)
+
+// BinaryOption configures [Patch.ToBinary].
+type BinaryOption func(*binaryConfig)
+
+type binaryConfig struct {
+	fieldIndex bool
+}
+
+// FieldIndexPaths encodes the struct field segments of paths as field
+// indexes instead of names. This is smaller for deeply nested structs but
+// requires both sides to use the same definition of T, as is the case for
+// types shared through generated code.
+func FieldIndexPaths() BinaryOption {
+	return func(c *binaryConfig) { c.fieldIndex = true }
+}
+
+// ToBinary returns a compact binary encoding of p that [Patch.UnmarshalBinary]
+// decodes back into an identical patch.
+//
+// Each distinct path is stored once. Values whose type matches the type at
+// their path in T are written without type information; other values are
+// tagged with their type. Dynamic values other than basic types, []any and
+// map[string]any are encoded with encoding/gob and must be registered with
+// gob.Register.
+func (p Patch[T]) ToBinary(opts ...BinaryOption) ([]byte, error) {
+	var cfg binaryConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	e := &binaryEncoder{typ: reflect.TypeOf((*T)(nil)).Elem(), index: map[string]int{}}
+	if err := e.condition(p.Guard); err != nil {
+		return nil, err
+	}
+	e.body.Uvarint(uint64(len(p.Operations)))
+	for i, op := range p.Operations {
+		if err := e.operation(op); err != nil {
+			return nil, fmt.Errorf("ops[%d]: %w", i, err)
+		}
+	}
+
+	var w icore.BinaryWriter
+	w.Byte(binaryVersion)
+	var flags byte
+	if p.Strict {
+		flags |= binaryStrict
+	}
+	if cfg.fieldIndex {
+		flags |= binaryFieldIndex
+	}
+	if p.Signature != nil {
+		flags |= binarySigned
+		e.body.String(p.Signature.KeyID)
+		e.body.Bytes(p.Signature.Value)
+	}
+	w.Byte(flags)
+	w.Uvarint(uint64(len(e.paths)))
+	for _, path := range e.paths {
+		writeBinaryPath(&w, e.typ, path, cfg.fieldIndex)
+	}
+	return append(w.Data(), e.body.Data()...), nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler using [Patch.ToBinary]
+// with default options.
+func (p Patch[T]) MarshalBinary() ([]byte, error) {
+	return p.ToBinary()
+}
+
+// UnmarshalBinary decodes a patch encoded by [Patch.ToBinary]. The options
+// used for encoding are recorded in the data.
+func (p *Patch[T]) UnmarshalBinary(data []byte) error {
+	r := icore.NewBinaryReader(data)
+	version, err := r.Byte()
+	if err != nil {
+		return err
+	}
+	if version != binaryVersion {
+		return fmt.Errorf("unsupported binary patch version %d", version)
+	}
+	flags, err := r.Byte()
+	if err != nil {
+		return err
+	}
+
+	d := &binaryDecoder{typ: reflect.TypeOf((*T)(nil)).Elem(), r: r}
+	n, err := r.Length()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		path, err := readBinaryPath(r, d.typ, flags&binaryFieldIndex != 0)
+		if err != nil {
+			return err
+		}
+		d.paths = append(d.paths, path)
+	}
+
+	res := Patch[T]{Strict: flags&binaryStrict != 0}
+	if res.Guard, err = d.condition(); err != nil {
+		return err
+	}
+	if n, err = r.Length(); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		op, err := d.operation()
+		if err != nil {
+			return fmt.Errorf("ops[%d]: %w", i, err)
+		}
+		res.Operations = append(res.Operations, op)
+	}
+	if flags&binarySigned != 0 {
+		sig := &Signature{}
+		if sig.KeyID, err = r.String(); err != nil {
+			return err
+		}
+		value, err := r.Bytes()
+		if err != nil {
+			return err
+		}
+		sig.Value = append([]byte(nil), value...)
+		res.Signature = sig
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d trailing bytes after binary patch", r.Len())
+	}
+	*p = res
+	return nil
+}
+
+// EncodingSizes compares the sizes of the binary and JSON encodings of a
+// patch, in bytes.
+type EncodingSizes struct {
+	Binary int
+	JSON   int
+}
+
+// Ratio returns the binary size as a fraction of the JSON size.
+func (s EncodingSizes) Ratio() float64 {
+	if s.JSON == 0 {
+		return 0
+	}
+	return float64(s.Binary) / float64(s.JSON)
+}
+
+// EncodedSizes encodes p with [Patch.ToBinary] and encoding/json and reports
+// the size of each.
+func (p Patch[T]) EncodedSizes(opts ...BinaryOption) (EncodingSizes, error) {
+	bin, err := p.ToBinary(opts...)
+	if err != nil {
+		return EncodingSizes{}, err
+	}
+	js, err := json.Marshal(p)
+	if err != nil {
+		return EncodingSizes{}, err
+	}
+	return EncodingSizes{Binary: len(bin), JSON: len(js)}, nil
+}
+
+type binaryEncoder struct {
+	typ   reflect.Type
+	paths []string
+	index map[string]int
+	body  icore.BinaryWriter
+}
+
+// path writes a reference to path, adding it to the path table if needed.
+func (e *binaryEncoder) path(path string) {
+	idx, ok := e.index[path]
+	if !ok {
+		idx = len(e.paths)
+		e.index[path] = idx
+		e.paths = append(e.paths, path)
+	}
+	e.body.Uvarint(uint64(idx))
+}
+
+// static reports whether v can be written without type information because
+// its type is exactly the type at path.
+func (e *binaryEncoder) static(path string, v any) bool {
+	typ, err := icore.TypeAtPath(e.typ, path)
+	return err == nil && reflect.TypeOf(v) == typ
+}
+
+func (e *binaryEncoder) value(v any, static bool) error {
+	if static {
+		return e.body.Value(reflect.ValueOf(v))
+	}
+	return e.body.Dynamic(v)
+}
+
+func (e *binaryEncoder) operation(op Operation) error {
+	var flags byte
+	from, oldIsPath := op.Old.(string)
+	oldIsPath = oldIsPath && (op.Kind == OpMove || op.Kind == OpCopy)
+	oldStatic := e.static(op.Path, op.Old)
+	newStatic := e.static(op.Path, op.New)
+	if op.Old != nil {
+		flags |= binaryHasOld
+		switch {
+		case oldIsPath:
+			flags |= binaryOldPath
+		case oldStatic:
+			flags |= binaryOldStatic
+		}
+	}
+	if op.New != nil {
+		flags |= binaryHasNew
+		if newStatic {
+			flags |= binaryNewStatic
+		}
+	}
+
+	e.body.Uvarint(uint64(op.Kind))
+	e.path(op.Path)
+	e.body.Byte(flags)
+	if op.Old != nil {
+		if oldIsPath {
+			e.path(from)
+		} else if err := e.value(op.Old, oldStatic); err != nil {
+			return err
+		}
+	}
+	if op.New != nil {
+		if err := e.value(op.New, newStatic); err != nil {
+			return err
+		}
+	}
+	if err := e.condition(op.If); err != nil {
+		return err
+	}
+	return e.condition(op.Unless)
+}
+
+func (e *binaryEncoder) condition(c *condition.Condition) error {
+	if c == nil {
+		e.body.Byte(0)
+		return nil
+	}
+	flags := binaryCondPresent
+	if c.Ref != "" {
+		flags |= binaryCondRef
+	}
+	e.body.Byte(flags)
+	e.body.String(c.Op)
+	e.path(c.Path)
+	if c.Ref != "" {
+		e.path(c.Ref)
+	}
+	switch {
+	case c.Value == nil:
+		e.body.Byte(binaryNoValue)
+	case e.static(c.Path, c.Value):
+		e.body.Byte(binaryStaticValue)
+		if err := e.value(c.Value, true); err != nil {
+			return err
+		}
+	default:
+		e.body.Byte(binaryDynamicValue)
+		if err := e.value(c.Value, false); err != nil {
+			return err
+		}
+	}
+	e.body.Uvarint(uint64(len(c.Sub)))
+	for _, sub := range c.Sub {
+		if err := e.condition(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type binaryDecoder struct {
+	typ   reflect.Type
+	r     *icore.BinaryReader
+	paths []string
+}
+
+func (d *binaryDecoder) path() (string, error) {
+	idx, err := d.r.Uvarint()
+	if err != nil {
+		return "", err
+	}
+	if idx >= uint64(len(d.paths)) {
+		return "", fmt.Errorf("invalid path reference %d", idx)
+	}
+	return d.paths[idx], nil
+}
+
+func (d *binaryDecoder) value(path string, static bool) (any, error) {
+	if !static {
+		return d.r.Dynamic()
+	}
+	typ, err := icore.TypeAtPath(d.typ, path)
+	if err != nil {
+		return nil, fmt.Errorf("decoding value at %s: %w", path, err)
+	}
+	v, err := d.r.Value(typ)
+	if err != nil {
+		return nil, fmt.Errorf("decoding value at %s: %w", path, err)
+	}
+	return v.Interface(), nil
+}
+
+func (d *binaryDecoder) operation() (Operation, error) {
+	var op Operation
+	kind, err := d.r.Uvarint()
+	if err != nil {
+		return op, err
+	}
+	op.Kind = OpKind(kind)
+	if op.Path, err = d.path(); err != nil {
+		return op, err
+	}
+	flags, err := d.r.Byte()
+	if err != nil {
+		return op, err
+	}
+	if flags&binaryHasOld != 0 {
+		if flags&binaryOldPath != 0 {
+			op.Old, err = d.path()
+		} else {
+			op.Old, err = d.value(op.Path, flags&binaryOldStatic != 0)
+		}
+		if err != nil {
+			return op, err
+		}
+	}
+	if flags&binaryHasNew != 0 {
+		if op.New, err = d.value(op.Path, flags&binaryNewStatic != 0); err != nil {
+			return op, err
+		}
+	}
+	if op.If, err = d.condition(); err != nil {
+		return op, err
+	}
+	op.Unless, err = d.condition()
+	return op, err
+}
+
+func (d *binaryDecoder) condition() (*condition.Condition, error) {
+	flags, err := d.r.Byte()
+	if err != nil || flags&binaryCondPresent == 0 {
+		return nil, err
+	}
+	c := &condition.Condition{}
+	if c.Op, err = d.r.String(); err != nil {
+		return nil, err
+	}
+	if c.Path, err = d.path(); err != nil {
+		return nil, err
+	}
+	if flags&binaryCondRef != 0 {
+		if c.Ref, err = d.path(); err != nil {
+			return nil, err
+		}
+	}
+	kind, err := d.r.Byte()
+	if err != nil {
+		return nil, err
+	}
+	if kind != binaryNoValue {
+		if c.Value, err = d.value(c.Path, kind == binaryStaticValue); err != nil {
+			return nil, err
+		}
+	}
+	n, err := d.r.Length()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		sub, err := d.condition()
+		if err != nil {
+			return nil, err
+		}
+		c.Sub = append(c.Sub, sub)
+	}
+	return c, nil
+}
+
+// writeBinaryPath writes an entry of the path table. With fieldIndex set,
+// JSON Pointer paths are written segment by segment, replacing struct field
+// names and json tags by the index of the field; any other path is written as
+// a plain string.
+//
+// Segment encoding: 0 followed by the key, or index<<2|1 for a field named by
+// its Go name, or index<<2|2 for a field named by its json tag.
+func writeBinaryPath(w *icore.BinaryWriter, typ reflect.Type, path string, fieldIndex bool) {
+	parts := icore.ParsePath(path)
+	if !fieldIndex || len(parts) == 0 || joinBinaryPath(parts) != path {
+		w.Uvarint(0)
+		w.String(path)
+		return
+	}
+	w.Uvarint(uint64(len(parts)) + 1)
+	for _, part := range parts {
+		var seg uint64
+		if f, ok := binaryPathField(typ, part.Key); ok {
+			seg = uint64(f.Index) << 2
+			if f.Name == part.Key {
+				seg |= 1
+			} else {
+				seg |= 2
+			}
+		}
+		w.Uvarint(seg)
+		if seg == 0 {
+			w.String(part.Key)
+		}
+		typ = binaryPathStep(typ, part.Key)
+	}
+}
+
+func readBinaryPath(r *icore.BinaryReader, typ reflect.Type, fieldIndex bool) (string, error) {
+	n, err := r.Uvarint()
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return r.String()
+	}
+	if !fieldIndex || n-1 > uint64(r.Len()) {
+		return "", fmt.Errorf("invalid path table entry")
+	}
+	parts := make([]icore.PathPart, n-1)
+	for i := range parts {
+		seg, err := r.Uvarint()
+		if err != nil {
+			return "", err
+		}
+		if seg == 0 {
+			if parts[i].Key, err = r.String(); err != nil {
+				return "", err
+			}
+		} else {
+			st := typ
+			for st != nil && st.Kind() == reflect.Pointer {
+				st = st.Elem()
+			}
+			idx := seg >> 2
+			if st == nil || st.Kind() != reflect.Struct || idx >= uint64(st.NumField()) {
+				return "", fmt.Errorf("invalid field index %d in path", idx)
+			}
+			f := icore.GetTypeInfo(st).Fields[idx]
+			parts[i].Key = f.Name
+			if seg&3 == 2 {
+				parts[i].Key = f.JSONTag
+			}
+		}
+		typ = binaryPathStep(typ, parts[i].Key)
+	}
+	return joinBinaryPath(parts), nil
+}
+
+func joinBinaryPath(parts []icore.PathPart) string {
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteByte('/')
+		b.WriteString(icore.EscapeKey(part.Key))
+	}
+	return b.String()
+}
+
+// binaryPathField returns the field of struct type typ that key names, the
+// same way path resolution matches it.
+func binaryPathField(typ reflect.Type, key string) (icore.FieldInfo, bool) {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return icore.FieldInfo{}, false
+	}
+	for _, f := range icore.GetTypeInfo(typ).Fields {
+		if f.Name == key || (f.JSONTag != "" && f.JSONTag == key) {
+			return f, true
+		}
+	}
+	return icore.FieldInfo{}, false
+}
+
+// binaryPathStep returns the type reached from typ through the path segment
+// key, or nil once the type is no longer known.
+func binaryPathStep(typ reflect.Type, key string) reflect.Type {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil {
+		return nil
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		if f, ok := binaryPathField(typ, key); ok {
+			return typ.Field(f.Index).Type
+		}
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return typ.Elem()
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package deep_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/condition"
+	"github.com/brunoga/deep/v5/crdt"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+type binaryDoc struct {
+	Name    string    `json:"name"`
+	When    time.Time `json:"when"`
+	Tags    []string  `json:"tags"`
+	Weights map[string]float64
+	Ptr     *testmodels.Detail
+	Any     any
+	hidden  int
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	p := deep.Patch[binaryDoc]{
+		Guard: &condition.Condition{Op: condition.And, Sub: []*condition.Condition{
+			{Path: "/name", Op: condition.Eq, Value: "a"},
+			{Path: "/Any/x", Op: condition.Exists},
+		}},
+		Strict: true,
+		Operations: []deep.Operation{
+			{Kind: deep.OpReplace, Path: "/name", Old: "a", New: "b"},
+			{Kind: deep.OpReplace, Path: "/when", Old: time.Time{}, New: now},
+			{Kind: deep.OpAdd, Path: "/tags/0", New: "x"},
+			{Kind: deep.OpReplace, Path: "/tags", Old: []string(nil), New: []string{"y", "z"}},
+			{Kind: deep.OpAdd, Path: "/Weights/k~1v", New: 1.5},
+			{Kind: deep.OpReplace, Path: "/Ptr", New: &testmodels.Detail{Age: 3, Address: "Home"}},
+			{Kind: deep.OpReplace, Path: "/Any", Old: map[string]any{"x": []any{1, "s", nil}}, New: int8(-4)},
+			{Kind: deep.OpReplace, Path: "/hidden", Old: 1, New: 2},
+			{Kind: deep.OpReplace, Path: "/name", New: int64(7), Unless: &condition.Condition{Path: "/name", Op: condition.Eq, Value: "b"}},
+			{Kind: deep.OpMove, Path: "/tags/1", Old: "/tags/0"},
+			{Kind: deep.OpCopy, Path: "/name", Old: "/tags/0", If: &condition.Condition{Path: "/tags", Op: condition.Exists}},
+			{Kind: deep.OpRemove, Path: "/Weights/k~1v", Old: 1.5},
+			{Kind: deep.OpLog, Path: "/", New: "done"},
+		},
+	}
+
+	for _, opts := range [][]deep.BinaryOption{nil, {deep.FieldIndexPaths()}} {
+		data, err := p.ToBinary(opts...)
+		if err != nil {
+			t.Fatalf("ToBinary failed: %v", err)
+		}
+		var got deep.Patch[binaryDoc]
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("round trip mismatch (%d options):\ngot  %+v\nwant %+v", len(opts), got, p)
+		}
+	}
+}
+
+func TestBinarySize(t *testing.T) {
+	u1 := testmodels.User{ID: 1, Name: "Alice", Info: testmodels.Detail{Age: 30, Address: "Home"}, Score: map[string]int{"a": 1}}
+	u2 := testmodels.User{ID: 2, Name: "Bob", Info: testmodels.Detail{Age: 31, Address: "Work"}, Score: map[string]int{"a": 2, "b": 3}}
+	p, err := deep.Diff(u1, u2)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	plain, err := p.EncodedSizes()
+	if err != nil {
+		t.Fatalf("EncodedSizes failed: %v", err)
+	}
+	indexed, err := p.EncodedSizes(deep.FieldIndexPaths())
+	if err != nil {
+		t.Fatalf("EncodedSizes failed: %v", err)
+	}
+	if plain.Binary >= plain.JSON || plain.Ratio() >= 1 {
+		t.Errorf("binary encoding not smaller than JSON: %+v", plain)
+	}
+	if indexed.Binary >= plain.Binary {
+		t.Errorf("field index paths not smaller: %d >= %d", indexed.Binary, plain.Binary)
+	}
+
+	data, _ := p.ToBinary(deep.FieldIndexPaths())
+	var got deep.Patch[testmodels.User]
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	u3 := deep.Clone(u1)
+	if err := deep.Apply(&u3, got); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !deep.Equal(u2, u3) {
+		t.Errorf("got %+v, want %+v", u3, u2)
+	}
+
+	if err := got.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
+
+func TestDeltaBinary(t *testing.T) {
+	nodeA := crdt.NewCRDT(testmodels.User{ID: 1, Name: "Alice"}, "node-a")
+	nodeB := crdt.NewCRDT(testmodels.User{ID: 1, Name: "Alice"}, "node-b")
+
+	delta := nodeA.Edit(func(u *testmodels.User) { u.Name = "Bob" })
+	data, err := delta.ToBinary(deep.FieldIndexPaths())
+	if err != nil {
+		t.Fatalf("ToBinary failed: %v", err)
+	}
+
+	var got crdt.Delta[testmodels.User]
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if got.Timestamp != delta.Timestamp {
+		t.Errorf("timestamp mismatch: %v != %v", got.Timestamp, delta.Timestamp)
+	}
+	if !nodeB.ApplyDelta(got) || nodeB.View().Name != "Bob" {
+		t.Errorf("decoded delta not applied: %+v", nodeB.View())
+	}
+}
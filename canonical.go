@@ -0,0 +1,178 @@
+package deep
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+
+	"github.com/brunoga/deep/v5/condition"
+	icore "github.com/brunoga/deep/v5/internal/core"
+)
+
+// ErrDigestMismatch is returned by [VerifyDigest] when a patch does not match
+// the digest it was sent with.
+var ErrDigestMismatch = errors.New("patch does not match its digest")
+
+// MarshalCanonical returns a deterministic JSON encoding of p, suitable for
+// byte comparison, hashing and signing. The JSON encoding of p is rewritten
+// as RFC 8785 prescribes: object keys are sorted, numbers are normalized (so
+// int 1 and float64 1.0 encode alike) and insignificant whitespace is
+// removed. Integers are kept exact, even beyond ±2^53, so that distinct
+// values never share an encoding.
+//
+// Operations whose order does not matter are sorted by path as well:
+// consecutive replaces, and adds and removes that do not shift slice
+// elements, are reordered when their paths, and the paths of their
+// conditions, are unrelated. Moves, copies, logs and operations on the root
+// keep their position.
+//
+// The result decodes, like any other encoding of p, with [Patch.UnmarshalJSON].
+func (p Patch[T]) MarshalCanonical() ([]byte, error) {
+	c := p
+	c.Operations = canonicalOrder(reflect.TypeOf((*T)(nil)).Elem(), p.Operations)
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return icore.CanonicalJSON(data)
+}
+
+// Digest returns the SHA-256 hash of the canonical encoding of p.
+func (p Patch[T]) Digest() ([sha256.Size]byte, error) {
+	data, err := p.MarshalCanonical()
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+// VerifyDigest decodes a patch from data, which may be its canonical encoding
+// or any other JSON encoding of it, and checks it against digest, as computed
+// by [Patch.Digest] on the sender. It returns the patch, or
+// [ErrDigestMismatch] if it was altered.
+func VerifyDigest[T any](data []byte, digest [sha256.Size]byte) (Patch[T], error) {
+	var p Patch[T]
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Patch[T]{}, err
+	}
+	got, err := p.Digest()
+	if err != nil {
+		return Patch[T]{}, err
+	}
+	if subtle.ConstantTimeCompare(got[:], digest[:]) != 1 {
+		return Patch[T]{}, ErrDigestMismatch
+	}
+	return p, nil
+}
+
+// canonicalOrder returns ops with each run of mutually independent,
+// commutable operations sorted by path.
+func canonicalOrder(typ reflect.Type, ops []Operation) []Operation {
+	t := transformer{typ: typ}
+	res := make([]Operation, 0, len(ops))
+	var run []Operation
+	flush := func() {
+		sort.SliceStable(run, func(i, j int) bool { return run[i].Path < run[j].Path })
+		res = append(res, run...)
+		run = run[:0]
+	}
+
+	for _, op := range ops {
+		if !t.commutable(op) {
+			flush()
+			res = append(res, op)
+			continue
+		}
+		for _, r := range run {
+			if !t.independent(r, op) {
+				flush()
+				break
+			}
+		}
+		run = append(run, op)
+	}
+	flush()
+	return res
+}
+
+// commutable reports whether op may be reordered with independent operations.
+func (t transformer) commutable(op Operation) bool {
+	switch op.Kind {
+	case OpReplace:
+	case OpAdd, OpRemove:
+		if t.sliceElement(op.Path) {
+			return false
+		}
+	default:
+		return false
+	}
+	return len(t.split(op.Path).canon) > 0
+}
+
+// sliceElement reports whether path addresses an element of a slice or array.
+func (t transformer) sliceElement(path string) bool {
+	parent, ok := parentPath(path)
+	if !ok {
+		return false
+	}
+	pt, err := icore.TypeAtPath(t.typ, parent)
+	if err != nil {
+		// Unknown types may be slices; do not reorder.
+		return true
+	}
+	for pt.Kind() == reflect.Pointer {
+		pt = pt.Elem()
+	}
+	return pt.Kind() == reflect.Slice || pt.Kind() == reflect.Array || pt.Kind() == reflect.Interface
+}
+
+// independent reports whether a and b touch unrelated values and neither's
+// conditions read a value the other writes.
+func (t transformer) independent(a, b Operation) bool {
+	related := func(x, y string) bool {
+		px, py := t.split(x), t.split(y)
+		return px.under(py) || py.under(px)
+	}
+	if related(a.Path, b.Path) {
+		return false
+	}
+	for _, pair := range [][2]Operation{{a, b}, {b, a}} {
+		for _, c := range []*condition.Condition{pair[0].If, pair[0].Unless} {
+			for _, path := range conditionPaths(c) {
+				if related(path, pair[1].Path) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// conditionPaths returns the paths read by c.
+func conditionPaths(c *condition.Condition) []string {
+	if c == nil {
+		return nil
+	}
+	switch c.Op {
+	case condition.And, condition.Or, condition.Not, condition.Any, condition.All, condition.None:
+		var res []string
+		for _, sub := range c.Sub {
+			res = append(res, conditionPaths(sub)...)
+		}
+		return res
+	}
+	res := []string{c.Path}
+	if c.Ref != "" {
+		res = append(res, c.Ref)
+	}
+	for i, path := range res {
+		if prefix, ok := icore.WildcardPrefix(path); ok {
+			// The condition reads every element under the wildcard.
+			res[i], _ = parentPath(prefix)
+		}
+	}
+	return res
+}
@@ -0,0 +1,81 @@
+package deep_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+func TestMarshalCanonical(t *testing.T) {
+	p1 := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/full_name", Old: "a", New: "b"},
+		{Kind: deep.OpAdd, Path: "/score/x", New: 1},
+		{Kind: deep.OpAdd, Path: "/roles/0", New: "admin"},
+		{Kind: deep.OpReplace, Path: "/id", New: 2.0},
+	}}
+	// The same changes, built differently: independent operations in another
+	// order and a number of another type.
+	p2 := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpAdd, Path: "/score/x", New: 1.0},
+		{Kind: deep.OpReplace, Path: "/full_name", Old: "a", New: "b"},
+		{Kind: deep.OpAdd, Path: "/roles/0", New: "admin"},
+		{Kind: deep.OpReplace, Path: "/id", New: 2},
+	}}
+
+	c1, err := p1.MarshalCanonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := p2.MarshalCanonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(c1) != string(c2) {
+		t.Errorf("canonical forms differ:\n%s\n%s", c1, c2)
+	}
+	want := `{"ops":[{"k":2,"n":"b","o":"a","p":"/full_name"},{"k":0,"n":1,"p":"/score/x"},{"k":0,"n":"admin","p":"/roles/0"},{"k":2,"n":2,"p":"/id"}]}`
+	if string(c1) != want {
+		t.Errorf("MarshalCanonical = %s, want %s", c1, want)
+	}
+
+	// Operations that shift slice elements are not reordered.
+	p3 := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpAdd, Path: "/roles/1", New: "b"},
+		{Kind: deep.OpAdd, Path: "/roles/0", New: "a"},
+	}}
+	c3, _ := p3.MarshalCanonical()
+	p3.Operations[0], p3.Operations[1] = p3.Operations[1], p3.Operations[0]
+	if c4, _ := p3.MarshalCanonical(); string(c3) == string(c4) {
+		t.Error("dependent slice operations were reordered")
+	}
+}
+
+func TestVerifyDigest(t *testing.T) {
+	p := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/full_name", Old: "a", New: "b"},
+		{Kind: deep.OpReplace, Path: "/info/Age", New: 31},
+	}}
+	digest, err := p.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Any encoding of the patch verifies.
+	data, _ := json.MarshalIndent(p, "", "  ")
+	got, err := deep.VerifyDigest[testmodels.User](data, digest)
+	if err != nil {
+		t.Fatalf("VerifyDigest failed: %v", err)
+	}
+	if len(got.Operations) != 2 || got.Operations[1].New != 31 {
+		t.Errorf("unexpected decoded patch: %+v", got)
+	}
+
+	p.Operations[1].New = 99
+	data, _ = json.Marshal(p)
+	if _, err := deep.VerifyDigest[testmodels.User](data, digest); !errors.Is(err, deep.ErrDigestMismatch) {
+		t.Errorf("expected ErrDigestMismatch, got %v", err)
+	}
+}
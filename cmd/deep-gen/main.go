@@ -87,6 +87,7 @@ func fieldApplyCase(f FieldInfo, p string) string {
 	} else {
 		fmt.Fprintf(&b, "\tcase \"/%s\":\n", f.Name)
 	}
+	b.WriteString(fieldTestCode(f, p))
 	if f.ReadOnly {
 		b.WriteString("\t\treturn true, fmt.Errorf(\"field %s is read-only\", op.Path)\n")
 		return b.String()
@@ -130,6 +131,25 @@ func fieldApplyCase(f FieldInfo, p string) string {
 	return b.String()
 }
 
+// fieldTestCode returns the OpTest check for a field. Numbers, strings and
+// booleans are compared in place; other values are left to reflection.
+func fieldTestCode(f FieldInfo, p string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\t\tif op.Kind == %sOpTest {\n", p)
+	if isNumericType(f.Type) || f.Type == "string" || f.Type == "bool" {
+		fail := fmt.Sprintf("return true, fmt.Errorf(\"%%w at %%s: expected %%v, got %%v\", %sErrTestFailed, op.Path, op.New, t.%s)", p, f.Name)
+		fmt.Fprintf(&b, "\t\t\tif v, ok := op.New.(%s); ok {\n", f.Type)
+		fmt.Fprintf(&b, "\t\t\t\tif t.%s != v { %s }\n\t\t\t\treturn true, nil\n\t\t\t}\n", f.Name, fail)
+		if isNumericType(f.Type) && f.Type != "float64" {
+			// Numbers decoded from JSON are float64.
+			b.WriteString("\t\t\tif v, ok := op.New.(float64); ok {\n")
+			fmt.Fprintf(&b, "\t\t\t\tif float64(t.%s) != v { %s }\n\t\t\t\treturn true, nil\n\t\t\t}\n", f.Name, fail)
+		}
+	}
+	b.WriteString("\t\t\treturn false, nil\n\t\t}\n")
+	return b.String()
+}
+
 // delegateCase returns the sub-path delegation block for the default: branch.
 func delegateCase(f FieldInfo, p string) string {
 	if f.Ignore || f.Atomic {
@@ -139,6 +159,7 @@ func delegateCase(f FieldInfo, p string) string {
 	if f.IsStruct {
 		fmt.Fprintf(&b, "\t\tif strings.HasPrefix(op.Path, \"/%s/\") {\n", f.JSONName)
 		if f.ReadOnly {
+			fmt.Fprintf(&b, "\t\t\tif op.Kind == %sOpTest { return false, nil }\n", p)
 			b.WriteString("\t\t\treturn true, fmt.Errorf(\"field %s is read-only\", op.Path)\n")
 		} else {
 			selfArg := "(&t." + f.Name + ")"
@@ -158,6 +179,7 @@ func delegateCase(f FieldInfo, p string) string {
 		vt := mapVal(f.Type)
 		fmt.Fprintf(&b, "\t\tif strings.HasPrefix(op.Path, \"/%s/\") {\n", f.JSONName)
 		if f.ReadOnly {
+			fmt.Fprintf(&b, "\t\t\tif op.Kind == %sOpTest { return false, nil }\n", p)
 			b.WriteString("\t\t\treturn true, fmt.Errorf(\"field %s is read-only\", op.Path)\n")
 		} else if isPtr(vt) {
 			fmt.Fprintf(&b, "\t\t\tparts := strings.Split(op.Path[len(\"/%s/\"):], \"/\")\n", f.JSONName)
@@ -169,6 +191,7 @@ func delegateCase(f FieldInfo, p string) string {
 		} else {
 			fmt.Fprintf(&b, "\t\t\tparts := strings.Split(op.Path[len(\"/%s/\"):], \"/\")\n", f.JSONName)
 			b.WriteString("\t\t\tkey := parts[0]\n")
+			fmt.Fprintf(&b, "\t\t\tif op.Kind == %sOpTest { return false, nil }\n", p)
 			fmt.Fprintf(&b, "\t\t\tif op.Kind == %sOpRemove {\n", p)
 			fmt.Fprintf(&b, "\t\t\t\tdelete(t.%s, key)\n\t\t\t\treturn true, nil\n\t\t\t}\n", f.Name)
 			fmt.Fprintf(&b, "\t\t\tif t.%s == nil { t.%s = make(%s) }\n", f.Name, f.Name, f.Type)
@@ -179,12 +202,14 @@ func delegateCase(f FieldInfo, p string) string {
 	return b.String()
 }
 
-// diffFieldCode returns the diff fragment for one field.
+// diffFieldCode returns the diff fragment for one field, skipped when the
+// field is left out of the diff with deep.IgnorePath.
 func diffFieldCode(f FieldInfo, p string, typeKeys map[string]string) string {
 	var b strings.Builder
 	if f.Ignore {
 		return ""
 	}
+	fmt.Fprintf(&b, "\tif !l.Ignored(%q) {\n", f.JSONName)
 	if (f.IsStruct || f.IsText) && !f.Atomic {
 		self, other := "(&t."+f.Name+")", "&other."+f.Name
 		if isPtr(f.Type) {
@@ -199,21 +224,37 @@ func diffFieldCode(f FieldInfo, p string, typeKeys map[string]string) string {
 		if needsGuard {
 			fmt.Fprintf(&b, "\tif %s != nil && %s != nil {\n", self, other)
 		}
-		fmt.Fprintf(&b, "\t\tsub%s := %s.Diff(%s)\n", f.Name, self, other)
+		fmt.Fprintf(&b, "\tif l.Descend(%q) {\n", f.JSONName)
+		if f.IsText {
+			// Text.Diff is hand-written and does not count its own operations.
+			fmt.Fprintf(&b, "\t\tsub%s := %s.Diff(%s)\n", f.Name, self, other)
+			b.WriteString("\t\tl.Ascend()\n")
+		} else {
+			fmt.Fprintf(&b, "\t\tsub%s, err := %s.DiffLimited(%s, l)\n", f.Name, self, other)
+			b.WriteString("\t\tl.Ascend()\n")
+			b.WriteString("\t\tif err != nil { return p, err }\n")
+			fmt.Fprintf(&b, "\t\tcounted += len(sub%s.Operations)\n", f.Name)
+		}
 		fmt.Fprintf(&b, "\t\tfor _, op := range sub%s.Operations {\n", f.Name)
 		fmt.Fprintf(&b, "\t\t\tif op.Path == \"\" || op.Path == \"/\" { op.Path = \"/%s\" } else { op.Path = \"/%s\" + op.Path }\n", f.JSONName, f.JSONName)
 		b.WriteString("\t\t\tp.Operations = append(p.Operations, op)\n\t\t}\n")
+		writeCoarseReplace(&b, f, p)
 		if needsGuard {
 			b.WriteString("\t}\n")
 		}
 	} else if f.IsCollection && !f.Atomic {
+		fmt.Fprintf(&b, "\tif l.Descend(%q) {\n", f.JSONName)
+		// skip is set when some elements are ignored and must be looked up.
+		b.WriteString("\tskip := l.IgnoresBelow()\n")
 		if strings.HasPrefix(f.Type, "map[") {
 			vt := mapVal(f.Type)
 			ptrVal := isPtr(vt)
 			fmt.Fprintf(&b, "\tif other.%s != nil {\n", f.Name)
 			fmt.Fprintf(&b, "\t\tfor k, v := range other.%s {\n", f.Name)
+			writeSkipElem(&b, "k")
 			fmt.Fprintf(&b, "\t\t\tif t.%s == nil {\n", f.Name)
 			fmt.Fprintf(&b, "\t\t\t\tp.Operations = append(p.Operations, %sOperation{Kind: %sOpReplace, Path: fmt.Sprintf(\"/%s/%%v\", k), New: v})\n", p, p, f.JSONName)
+			writeCountOp(&b)
 			b.WriteString("\t\t\t\tcontinue\n\t\t\t}\n")
 			fmt.Fprintf(&b, "\t\t\tif oldV, ok := t.%s[k]; !ok || ", f.Name)
 			if ptrVal {
@@ -223,11 +264,14 @@ func diffFieldCode(f FieldInfo, p string, typeKeys map[string]string) string {
 			}
 			fmt.Fprintf(&b, "\t\t\t\tkind := %sOpReplace\n\t\t\t\tif !ok { kind = %sOpAdd }\n", p, p)
 			fmt.Fprintf(&b, "\t\t\t\tp.Operations = append(p.Operations, %sOperation{Kind: kind, Path: fmt.Sprintf(\"/%s/%%v\", k), Old: oldV, New: v})\n", p, f.JSONName)
+			writeCountOp(&b)
 			b.WriteString("\t\t\t}\n\t\t}\n\t}\n")
 			fmt.Fprintf(&b, "\tif t.%s != nil {\n", f.Name)
 			fmt.Fprintf(&b, "\t\tfor k, v := range t.%s {\n", f.Name)
+			writeSkipElem(&b, "k")
 			fmt.Fprintf(&b, "\t\t\tif other.%s == nil || !contains(other.%s, k) {\n", f.Name, f.Name)
 			fmt.Fprintf(&b, "\t\t\t\tp.Operations = append(p.Operations, %sOperation{Kind: %sOpRemove, Path: fmt.Sprintf(\"/%s/%%v\", k), Old: v})\n", p, p, f.JSONName)
+			writeCountOp(&b)
 			b.WriteString("\t\t\t}\n\t\t}\n\t}\n")
 		} else {
 			// Slice
@@ -238,33 +282,65 @@ func diffFieldCode(f FieldInfo, p string, typeKeys map[string]string) string {
 				fmt.Fprintf(&b, "\totherByKey := make(map[any]int)\n")
 				fmt.Fprintf(&b, "\tfor i, v := range other.%s { otherByKey[v.%s] = i }\n", f.Name, keyField)
 				fmt.Fprintf(&b, "\tfor _, v := range t.%s {\n", f.Name)
+				writeSkipElem(&b, "v."+keyField)
 				fmt.Fprintf(&b, "\t\tif _, ok := otherByKey[v.%s]; !ok {\n", keyField)
 				fmt.Fprintf(&b, "\t\t\tp.Operations = append(p.Operations, %sOperation{Kind: %sOpRemove, Path: fmt.Sprintf(\"/%s/%%v\", v.%s), Old: v})\n", p, p, f.JSONName, keyField)
+				writeCountOp(&b)
 				b.WriteString("\t\t}\n\t}\n")
 				fmt.Fprintf(&b, "\ttByKey := make(map[any]int)\n")
 				fmt.Fprintf(&b, "\tfor i, v := range t.%s { tByKey[v.%s] = i }\n", f.Name, keyField)
 				fmt.Fprintf(&b, "\tfor _, v := range other.%s {\n", f.Name)
+				writeSkipElem(&b, "v."+keyField)
 				fmt.Fprintf(&b, "\t\tif _, ok := tByKey[v.%s]; !ok {\n", keyField)
 				fmt.Fprintf(&b, "\t\t\tp.Operations = append(p.Operations, %sOperation{Kind: %sOpAdd, Path: fmt.Sprintf(\"/%s/%%v\", v.%s), New: v})\n", p, p, f.JSONName, keyField)
+				writeCountOp(&b)
 				b.WriteString("\t\t}\n\t}\n")
 			} else {
 				fmt.Fprintf(&b, "\tif len(t.%s) != len(other.%s) {\n", f.Name, f.Name)
 				fmt.Fprintf(&b, "\t\tp.Operations = append(p.Operations, %sOperation{Kind: %sOpReplace, Path: \"/%s\", Old: t.%s, New: other.%s})\n", p, p, f.JSONName, f.Name, f.Name)
 				b.WriteString("\t} else {\n")
 				fmt.Fprintf(&b, "\t\tfor i := range t.%s {\n", f.Name)
+				writeSkipElem(&b, "i")
 				fmt.Fprintf(&b, "\t\t\tif t.%s[i] != other.%s[i] {\n", f.Name, f.Name)
 				fmt.Fprintf(&b, "\t\t\t\tp.Operations = append(p.Operations, %sOperation{Kind: %sOpReplace, Path: fmt.Sprintf(\"/%s/%%d\", i), Old: t.%s[i], New: other.%s[i]})\n", p, p, f.JSONName, f.Name, f.Name)
+				writeCountOp(&b)
 				b.WriteString("\t\t\t}\n\t\t}\n\t}\n")
 			}
 		}
+		b.WriteString("\tl.Ascend()\n")
+		writeCoarseReplace(&b, f, p)
 	} else {
 		fmt.Fprintf(&b, "\tif t.%s != other.%s {\n", f.Name, f.Name)
 		fmt.Fprintf(&b, "\t\tp.Operations = append(p.Operations, %sOperation{Kind: %sOpReplace, Path: \"/%s\", Old: t.%s, New: other.%s})\n", p, p, f.JSONName, f.Name, f.Name)
 		b.WriteString("\t}\n")
 	}
+	b.WriteString("\t}\n")
 	return b.String()
 }
 
+// writeCountOp counts the operation just appended inside a collection loop,
+// so that a bounded diff stops as soon as it exceeds its budget rather than
+// after the whole collection was compared.
+func writeCountOp(b *strings.Builder) {
+	b.WriteString("\t\t\t\tcounted++\n")
+	b.WriteString("\t\t\t\tif err := l.Count(1); err != nil { return p, err }\n")
+}
+
+// writeSkipElem skips the collection element at key inside a loop if it is
+// left out of the diff.
+func writeSkipElem(b *strings.Builder, key string) {
+	fmt.Fprintf(b, "\t\t\tif skip && l.Ignored(fmt.Sprint(%s)) { continue }\n", key)
+}
+
+// writeCoarseReplace closes the block opened by l.Descend with the fallback
+// used beyond the maximum depth of a bounded diff: the field is replaced as a
+// whole if it changed.
+func writeCoarseReplace(b *strings.Builder, f FieldInfo, p string) {
+	fmt.Fprintf(b, "\t} else if !%sEqual(t.%s, other.%s) {\n", p, f.Name, f.Name)
+	fmt.Fprintf(b, "\t\tp.Operations = append(p.Operations, %sOperation{Kind: %sOpReplace, Path: \"/%s\", Old: t.%s, New: other.%s})\n", p, p, f.JSONName, f.Name, f.Name)
+	b.WriteString("\t}\n")
+}
+
 // evalCondCase returns the case body for EvaluateCondition's path switch.
 func evalCondCase(f FieldInfo, pkgPrefix string) string {
 	var b strings.Builder
@@ -273,6 +349,9 @@ func evalCondCase(f FieldInfo, pkgPrefix string) string {
 	b.WriteString("\t\tif c.Op == \"exists\" { return true, nil }\n")
 	fmt.Fprintf(&b, "\t\tif c.Op == \"type\" { return condition.CheckType(t.%s, c.Value.(string)), nil }\n", n)
 	fmt.Fprintf(&b, "\t\tif c.Op == \"matches\" { return regexp.MatchString(c.Value.(string), fmt.Sprintf(\"%%v\", t.%s)) }\n", n)
+	if isNumericType(typ) || typ == "string" {
+		fmt.Fprintf(&b, "\t\tif c.Op == \"between\" { return condition.BetweenValues(t.%s, c.Value) }\n", n)
+	}
 
 	switch {
 	case isNumericType(typ):
@@ -308,6 +387,7 @@ func evalCondCase(f FieldInfo, pkgPrefix string) string {
 		b.WriteString("\t\t\t\t\t}\n\t\t\t\t}\n\t\t\t}\n\t\t\treturn false, nil\n\t\t}\n")
 
 	case typ == "string":
+		fmt.Fprintf(&b, "\t\tif strings.HasPrefix(c.Op, \"len\") { return condition.CompareLen(len(t.%s), c.Op, c.Value) }\n", n)
 		fmt.Fprintf(&b, "\t\t_sv, _ok := c.Value.(string)\n")
 		fmt.Fprintf(&b, "\t\tif !_ok { return false, fmt.Errorf(\"condition value type mismatch for field %s\") }\n", n)
 		b.WriteString("\t\tswitch c.Op {\n")
@@ -317,6 +397,9 @@ func evalCondCase(f FieldInfo, pkgPrefix string) string {
 		fmt.Fprintf(&b, "\t\tcase \"<\":  return t.%s < _sv, nil\n", n)
 		fmt.Fprintf(&b, "\t\tcase \">=\": return t.%s >= _sv, nil\n", n)
 		fmt.Fprintf(&b, "\t\tcase \"<=\": return t.%s <= _sv, nil\n", n)
+		fmt.Fprintf(&b, "\t\tcase \"startsWith\": return strings.HasPrefix(t.%s, _sv), nil\n", n)
+		fmt.Fprintf(&b, "\t\tcase \"endsWith\": return strings.HasSuffix(t.%s, _sv), nil\n", n)
+		fmt.Fprintf(&b, "\t\tcase \"contains\": return strings.Contains(t.%s, _sv), nil\n", n)
 		b.WriteString("\t\tcase \"in\":\n")
 		fmt.Fprintf(&b, "\t\t\tswitch vals := c.Value.(type) {\n\t\t\tcase []string:\n\t\t\t\tfor _, v := range vals { if t.%s == v { return true, nil } }\n", n)
 		fmt.Fprintf(&b, "\t\t\tcase []any:\n\t\t\t\tfor _, v := range vals { if sv, ok := v.(string); ok && t.%s == sv { return true, nil } }\n", n)
@@ -396,6 +479,61 @@ func equalFieldCode(f FieldInfo) string {
 	return b.String()
 }
 
+// hashValueCode returns the statement that writes expr, of type typ, to the
+// hasher named h.
+func hashValueCode(h, expr, typ string) string {
+	switch typ {
+	case "int", "int8", "int16", "int32", "int64", "rune":
+		return fmt.Sprintf("%s.Int64(int64(%s))", h, expr)
+	case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte":
+		return fmt.Sprintf("%s.Uint64(uint64(%s))", h, expr)
+	case "float32", "float64":
+		return fmt.Sprintf("%s.Float64(float64(%s))", h, expr)
+	case "string":
+		return fmt.Sprintf("%s.String(%s)", h, expr)
+	case "bool":
+		return fmt.Sprintf("%s.Bool(%s)", h, expr)
+	}
+	if isPtr(typ) {
+		return fmt.Sprintf("if %s == nil { %s.Bool(false) } else { %s.Bool(true); %s.Uint64(%s.Hash()) }", expr, h, h, h, expr)
+	}
+	return fmt.Sprintf("%s.Value(%s)", h, expr)
+}
+
+// hashFieldCode returns the hashing fragment for one field.
+func hashFieldCode(f FieldInfo) string {
+	var b strings.Builder
+	switch {
+	case f.IsStruct:
+		if isPtr(f.Type) {
+			fmt.Fprintf(&b, "\t%s\n", hashValueCode("h", "t."+f.Name, f.Type))
+		} else {
+			fmt.Fprintf(&b, "\th.Uint64((&t.%s).Hash())\n", f.Name)
+		}
+	case f.IsText:
+		fmt.Fprintf(&b, "\th.Value(t.%s)\n", f.Name)
+	case f.IsCollection:
+		fmt.Fprintf(&b, "\th.Uint64(uint64(len(t.%s)))\n", f.Name)
+		if strings.HasPrefix(f.Type, "[]") {
+			fmt.Fprintf(&b, "\tfor _, v := range t.%s {\n", f.Name)
+			fmt.Fprintf(&b, "\t\t%s\n", hashValueCode("h", "v", sliceElem(f.Type)))
+			b.WriteString("\t}\n")
+		} else if strings.HasPrefix(f.Type, "map[") {
+			// Entries are hashed separately and summed so that iteration
+			// order does not matter.
+			keyType := f.Type[len("map["):strings.Index(f.Type, "]")]
+			fmt.Fprintf(&b, "\t{\n\t\tvar sum uint64\n\t\tfor k, v := range t.%s {\n", f.Name)
+			b.WriteString("\t\t\te := _deepengine.NewHasher()\n")
+			fmt.Fprintf(&b, "\t\t\t%s\n", hashValueCode("e", "k", keyType))
+			fmt.Fprintf(&b, "\t\t\t%s\n", hashValueCode("e", "v", mapVal(f.Type)))
+			b.WriteString("\t\t\tsum += e.Sum()\n\t\t}\n\t\th.Uint64(sum)\n\t}\n")
+		}
+	default:
+		fmt.Fprintf(&b, "\t%s\n", hashValueCode("h", "t."+f.Name, f.Type))
+	}
+	return b.String()
+}
+
 // copyFieldInit returns the struct-literal initialiser fragment for one field (inside `res := &T{...}`).
 func copyFieldInit(f FieldInfo) string {
 	switch {
@@ -464,6 +602,7 @@ var tmplFuncs = template.FuncMap{
 	"diffFieldCode":  diffFieldCode,
 	"evalCondCase":   evalCondCase,
 	"equalFieldCode": equalFieldCode,
+	"hashFieldCode":  hashFieldCode,
 	"copyFieldInit":  copyFieldInit,
 	"copyFieldPost":  copyFieldPost,
 	"not":            func(b bool) bool { return !b },
@@ -512,11 +651,14 @@ func (t *{{.TypeName}}) Patch(p {{.P}}Patch[{{.TypeName}}], logger *slog.Logger)
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == {{.P}}OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -545,6 +687,9 @@ var applyOpTmpl = template.Must(template.New("applyOp").Funcs(tmplFuncs).Parse(
 
 	switch op.Path {
 	case "/":
+		if op.Kind == {{.P}}OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == {{.P}}OpReplace || op.Kind == {{.P}}OpRemove) {
 			if !{{.P}}Equal(*t, op.Old.({{.TypeName}})) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -569,15 +714,33 @@ var applyOpTmpl = template.Must(template.New("applyOp").Funcs(tmplFuncs).Parse(
 var diffTmpl = template.Must(template.New("diff").Funcs(tmplFuncs).Parse(
 	`// Diff compares t with other and returns a Patch.
 func (t *{{.TypeName}}) Diff(other *{{.TypeName}}) {{.P}}Patch[{{.TypeName}}] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *{{.TypeName}}) DiffLimited(other *{{.TypeName}}, l *_deepengine.DiffLimits) ({{.P}}Patch[{{.TypeName}}], error) {
 	p := {{.P}}Patch[{{.TypeName}}]{}
+	if err := l.Check(); err != nil {
+		return p, err
+	}
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
 {{range .Fields}}{{diffFieldCode . $.P $.TypeKeys}}{{end}}
-	return p
+	return p, l.Count(len(p.Operations) - counted)
 }
 
 `))
 
 var evalCondTmpl = template.Must(template.New("evalCond").Funcs(tmplFuncs).Parse(
-	`func (t *{{.TypeName}}) evaluateCondition(c condition.Condition) (bool, error) {
+	`// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *{{.TypeName}}) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
+}
+
+func (t *{{.TypeName}}) evaluateCondition(c condition.Condition) (bool, error) {
 	switch c.Op {
 	case "and":
 		for _, sub := range c.Sub {
@@ -598,6 +761,14 @@ var evalCondTmpl = template.Must(template.New("evalCond").Funcs(tmplFuncs).Parse
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -605,6 +776,9 @@ var evalCondTmpl = template.Must(template.New("evalCond").Funcs(tmplFuncs).Parse
 	{{if ne .JSONName .Name}}case "/{{.JSONName}}", "/{{.Name}}":{{else}}case "/{{.Name}}":{{end}}
 {{evalCondCase . $.P}}{{end}}{{end -}}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -619,6 +793,16 @@ func (t *{{.TypeName}}) Equal(other *{{.TypeName}}) bool {
 
 `))
 
+var hashTmpl = template.Must(template.New("hash").Funcs(tmplFuncs).Parse(
+	`// Hash returns a hash of t that is the same for values that are Equal.
+func (t *{{.TypeName}}) Hash() uint64 {
+	h := _deepengine.NewHasher()
+{{range .Fields}}{{if not .Ignore}}{{hashFieldCode .}}{{end}}{{end -}}
+	return h.Sum()
+}
+
+`))
+
 var copyTmpl = template.Must(template.New("copy").Funcs(tmplFuncs).Parse(
 	`// Clone returns a deep copy of t.
 func (t *{{.TypeName}}) Clone() *{{.TypeName}} {
@@ -652,6 +836,9 @@ func (g *Generator) writeHeader(allFields []FieldInfo) {
 		if !f.IsStruct && !f.IsCollection && !f.IsText {
 			needsRegexp = true
 		}
+		if f.Type == "string" {
+			needsStrings = true
+		}
 		if f.IsText {
 			needsCrdt = true
 		}
@@ -676,6 +863,7 @@ func (g *Generator) writeType(typeName string, fields []FieldInfo) {
 	must(diffTmpl.Execute(&g.buf, d))
 	must(evalCondTmpl.Execute(&g.buf, d))
 	must(equalTmpl.Execute(&g.buf, d))
+	must(hashTmpl.Execute(&g.buf, d))
 	must(copyTmpl.Execute(&g.buf, d))
 }
 
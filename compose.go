@@ -0,0 +1,296 @@
+package deep
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+
+	icore "github.com/brunoga/deep/v5/internal/core"
+	"github.com/brunoga/deep/v5/internal/engine"
+)
+
+// Compose squashes a sequence of patches into a single patch with the same
+// effect as applying them in order. Operations are folded together wherever
+// that is provably safe:
+//
+//   - successive adds and replaces of the same path collapse into one
+//     operation that keeps the original Old value and the final New value;
+//   - an add followed by a remove of the same path cancels out, as does a
+//     replace that restores the original value;
+//   - operations on a child path are dropped when a later operation replaces
+//     or removes the parent, whose Old value is rewound to include them;
+//   - operations on a child path of an earlier add or replace are folded into
+//     that operation's New value;
+//   - chains of moves and copies are rewritten into a single move or copy.
+//
+// Operations with If/Unless conditions, log operations and inserts or removes
+// in non-keyed slices are kept as they are, since their effect depends on the
+// state at the point they run. Old values are preserved, so the result can be
+// reversed with [Patch.Reverse].
+//
+// The result carries the Guard of the first patch; guards on later patches
+// refer to intermediate states and are not retained. It is strict only if
+// every input patch is.
+func Compose[T any](patches ...Patch[T]) Patch[T] {
+	res := Patch[T]{}
+	if len(patches) == 0 {
+		return res
+	}
+	res.Guard = patches[0].Guard
+	res.Strict = true
+
+	c := composer{typ: reflect.TypeOf((*T)(nil)).Elem()}
+	for _, p := range patches {
+		res.Strict = res.Strict && p.Strict
+		for _, op := range p.Operations {
+			c.push(op)
+		}
+	}
+
+	for _, o := range c.ops {
+		res.Operations = append(res.Operations, o.op)
+	}
+	return res
+}
+
+// composeOp is an operation queued by a composer, together with the canonical
+// form of its path and of every path it touches.
+type composeOp struct {
+	op     Operation
+	path   string
+	from   string
+	canon  []string
+	pinned bool
+}
+
+type composer struct {
+	typ reflect.Type
+	ops []composeOp
+}
+
+func (c *composer) wrap(op Operation) composeOp {
+	o := composeOp{op: op, path: icore.CanonicalPath(c.typ, op.Path)}
+	paths := []string{op.Path}
+	switch op.Kind {
+	case OpAdd, OpReplace, OpRemove:
+	case OpMove, OpCopy:
+		from, ok := op.Old.(string)
+		if !ok {
+			o.pinned = true
+			break
+		}
+		o.from = icore.CanonicalPath(c.typ, from)
+		paths = append(paths, from)
+	default:
+		o.pinned = true
+	}
+	if op.If != nil || op.Unless != nil {
+		o.pinned = true
+	}
+	for _, p := range paths {
+		anchor := p
+		if op.Kind != OpReplace {
+			anchor = shiftAnchor(c.typ, p)
+		}
+		if anchor != p {
+			o.pinned = true
+		}
+		o.canon = append(o.canon, icore.CanonicalPath(c.typ, anchor))
+	}
+	return o
+}
+
+func (o composeOp) overlaps(other composeOp) bool {
+	for _, a := range o.canon {
+		for _, b := range other.canon {
+			if pathsOverlap(a, b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// within reports whether every path touched by o lies at or below parent.
+func (o composeOp) within(parent string) bool {
+	for _, p := range o.canon {
+		if !isSubPath(p, parent) {
+			return false
+		}
+	}
+	return true
+}
+
+// push appends op to the queue, folding it into earlier operations where
+// possible. Only the most recent operation overlapping op is a candidate, so
+// that folding never reorders operations that affect each other.
+func (c *composer) push(op Operation) {
+	n := c.wrap(op)
+	if op.Kind == OpLog {
+		c.ops = append(c.ops, n)
+		return
+	}
+
+	for i := len(c.ops) - 1; i >= 0; i-- {
+		e := c.ops[i]
+		if !e.overlaps(n) {
+			continue
+		}
+		if e.pinned || n.pinned {
+			break
+		}
+
+		switch {
+		case isValueOp(e.op) && isValueOp(n.op):
+			switch {
+			case e.path == n.path:
+				// Removing an element of a keyed slice and adding it back
+				// moves it to the end, which a replace in place would not.
+				if e.op.Kind == OpRemove && n.op.Kind == OpAdd && keyedElement(c.typ, n.op.Path) {
+					break
+				}
+				if merged, keep, ok := combineSame(e.op, n.op); ok {
+					if keep {
+						c.ops[i] = c.wrap(merged)
+					} else {
+						c.remove(i)
+					}
+					return
+				}
+
+			case n.op.Kind != OpAdd && e.path != n.path && isSubPath(e.path, n.path) && e.within(n.path):
+				// The later operation overwrites the parent, so the child change
+				// disappears; rewind the parent's old value to before it.
+				if old, ok := applyRelative(n.op.Old, relativePath(e.path, n.path), reverseOp(e.op)); ok {
+					n.op.Old = old
+					c.remove(i)
+					continue
+				}
+
+			case e.op.Kind != OpRemove && isSubPath(n.path, e.path) && n.within(e.path):
+				// The earlier operation wrote the parent, so write the child
+				// change straight into its new value.
+				if val, ok := applyRelative(e.op.New, relativePath(n.path, e.path), n.op); ok {
+					c.ops[i].op.New = val
+					return
+				}
+			}
+
+		case e.op.Kind == OpMove || e.op.Kind == OpCopy:
+			if n.op.Kind != OpMove || n.from != e.path || !c.untouchedAfter(i, e.from) {
+				break
+			}
+			if e.op.Kind == OpMove && e.from == n.path {
+				// Moved away and back again.
+				c.remove(i)
+				return
+			}
+			c.ops[i] = c.wrap(Operation{Kind: e.op.Kind, Path: n.op.Path, Old: e.op.Old})
+			return
+		}
+		break
+	}
+
+	c.ops = append(c.ops, n)
+}
+
+func (c *composer) remove(i int) {
+	c.ops = append(c.ops[:i], c.ops[i+1:]...)
+}
+
+// untouchedAfter reports whether no queued operation after index i touches
+// path.
+func (c *composer) untouchedAfter(i int, path string) bool {
+	probe := composeOp{canon: []string{path}}
+	for _, o := range c.ops[i+1:] {
+		if o.overlaps(probe) {
+			return false
+		}
+	}
+	return true
+}
+
+func isValueOp(op Operation) bool {
+	return op.Kind == OpAdd || op.Kind == OpReplace || op.Kind == OpRemove
+}
+
+// combineSame merges two successive value operations on the same path. keep
+// is false when they cancel out; ok is false when they cannot be merged.
+func combineSame(e, n Operation) (merged Operation, keep, ok bool) {
+	switch {
+	case e.Kind == OpAdd && n.Kind == OpRemove:
+		return Operation{}, false, true
+	case e.Kind == OpAdd:
+		merged = Operation{Kind: OpAdd, Path: e.Path, Old: e.Old, New: n.New}
+	case e.Kind == OpReplace && n.Kind == OpRemove:
+		merged = Operation{Kind: OpRemove, Path: e.Path, Old: e.Old}
+	case e.Kind == OpReplace:
+		merged = Operation{Kind: OpReplace, Path: e.Path, Old: e.Old, New: n.New}
+	case e.Kind == OpRemove && n.Kind == OpAdd:
+		merged = Operation{Kind: OpReplace, Path: e.Path, Old: e.Old, New: n.New}
+	default:
+		return Operation{}, false, false
+	}
+	if merged.Kind == OpReplace && merged.Old != nil && engine.Equal(merged.Old, merged.New) {
+		return Operation{}, false, true
+	}
+	return merged, true, true
+}
+
+// keyedElement reports whether path addresses an element of a keyed slice.
+func keyedElement(typ reflect.Type, path string) bool {
+	idx := strings.LastIndexByte(path, '/')
+	if idx <= 0 {
+		return false
+	}
+	pt, err := icore.TypeAtPath(typ, path[:idx])
+	if err != nil {
+		return false
+	}
+	for pt.Kind() == reflect.Pointer {
+		pt = pt.Elem()
+	}
+	return pt.Kind() == reflect.Slice && icore.IsKeyedSlice(pt)
+}
+
+// reverseOp returns the value operation that undoes op.
+func reverseOp(op Operation) Operation {
+	switch op.Kind {
+	case OpAdd:
+		return Operation{Kind: OpRemove, Path: op.Path, Old: op.New}
+	case OpRemove:
+		return Operation{Kind: OpAdd, Path: op.Path, New: op.Old}
+	default:
+		return Operation{Kind: OpReplace, Path: op.Path, Old: op.New, New: op.Old}
+	}
+}
+
+// relativePath returns path, which lies below parent, relative to parent.
+func relativePath(path, parent string) string {
+	if parent == "/" {
+		return path
+	}
+	return path[len(parent):]
+}
+
+// applyRelative applies op, addressed by rel relative to val, to a copy of val
+// and returns the result. It reports false if val is nil or the operation
+// cannot be applied.
+func applyRelative(val any, rel string, op Operation) (any, bool) {
+	if val == nil {
+		return nil, false
+	}
+	if (op.Kind == OpAdd || op.Kind == OpReplace) && op.New == nil {
+		return nil, false
+	}
+	src := reflect.ValueOf(val)
+	v := reflect.New(src.Type()).Elem()
+	v.Set(icore.DeepCopyValue(src))
+
+	op.Path = rel
+	op.Strict = false
+	if err := engine.ApplyOpReflectionValue(v, op, slog.Default()); err != nil {
+		return nil, false
+	}
+	return v.Interface(), true
+}
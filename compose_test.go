@@ -0,0 +1,184 @@
+package deep_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+func TestComposeDiffHistory(t *testing.T) {
+	v0 := testmodels.User{ID: 1, Name: "Alice", Info: testmodels.Detail{Age: 30}, Score: map[string]int{"a": 1}}
+	v1 := deep.Clone(v0)
+	v1.Name = "Alicia"
+	v1.Score["b"] = 2
+	v2 := deep.Clone(v1)
+	v2.Name = "Allie"
+	v2.Info.Age = 31
+	delete(v2.Score, "b")
+	v3 := deep.Clone(v2)
+	v3.Info = testmodels.Detail{Age: 40, Address: "Home"}
+
+	var patches []deep.Patch[testmodels.User]
+	for _, pair := range [][2]testmodels.User{{v0, v1}, {v1, v2}, {v2, v3}} {
+		p, err := deep.Diff(pair[0], pair[1])
+		if err != nil {
+			t.Fatalf("Diff failed: %v", err)
+		}
+		patches = append(patches, p)
+	}
+
+	c := deep.Compose(patches...)
+	if len(c.Operations) != 3 {
+		t.Fatalf("expected 3 operations, got %v", c)
+	}
+
+	got := deep.Clone(v0)
+	if err := deep.Apply(&got, c); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !deep.Equal(got, v3) {
+		t.Errorf("composed apply mismatch: got %+v, want %+v", got, v3)
+	}
+
+	back := deep.Clone(v3)
+	if err := deep.Apply(&back, c.Reverse()); err != nil {
+		t.Fatalf("Apply reverse failed: %v", err)
+	}
+	if !deep.Equal(back, v0) {
+		t.Errorf("reverse mismatch: got %+v, want %+v", back, v0)
+	}
+}
+
+func TestComposeRules(t *testing.T) {
+	type Item struct {
+		ID int `deep:"key"`
+		V  string
+	}
+	type Doc struct {
+		A     string
+		B     string
+		M     map[string]int
+		Inner struct{ X, Y int }
+		Items []Item
+	}
+
+	tests := []struct {
+		name string
+		in   [][]deep.Operation
+		want []deep.Operation
+	}{
+		{
+			"ReplaceReplace",
+			[][]deep.Operation{
+				{{Kind: deep.OpReplace, Path: "/A", Old: "a", New: "b"}},
+				{{Kind: deep.OpReplace, Path: "/A", Old: "b", New: "c"}},
+			},
+			[]deep.Operation{{Kind: deep.OpReplace, Path: "/A", Old: "a", New: "c"}},
+		},
+		{
+			"ReplaceRestores",
+			[][]deep.Operation{
+				{{Kind: deep.OpReplace, Path: "/A", Old: "a", New: "b"}},
+				{{Kind: deep.OpReplace, Path: "/A", Old: "b", New: "a"}},
+			},
+			nil,
+		},
+		{
+			"AddRemove",
+			[][]deep.Operation{
+				{{Kind: deep.OpAdd, Path: "/M/k", New: 1}},
+				{{Kind: deep.OpRemove, Path: "/M/k", Old: 1}},
+			},
+			nil,
+		},
+		{
+			"RemoveAdd",
+			[][]deep.Operation{
+				{{Kind: deep.OpRemove, Path: "/M/k", Old: 1}},
+				{{Kind: deep.OpAdd, Path: "/M/k", New: 2}},
+			},
+			[]deep.Operation{{Kind: deep.OpReplace, Path: "/M/k", Old: 1, New: 2}},
+		},
+		{
+			// Adding an element of a keyed slice back after removing it
+			// moves it to the end, which a replace would not.
+			"RemoveAddKeyed",
+			[][]deep.Operation{
+				{{Kind: deep.OpRemove, Path: "/Items/1", Old: Item{ID: 1, V: "a"}}},
+				{{Kind: deep.OpAdd, Path: "/Items/1", New: Item{ID: 1, V: "b"}}},
+			},
+			[]deep.Operation{
+				{Kind: deep.OpRemove, Path: "/Items/1", Old: Item{ID: 1, V: "a"}},
+				{Kind: deep.OpAdd, Path: "/Items/1", New: Item{ID: 1, V: "b"}},
+			},
+		},
+		{
+			"ChildUnderLaterParent",
+			[][]deep.Operation{
+				{{Kind: deep.OpReplace, Path: "/Inner/X", Old: 1, New: 2}},
+				{{Kind: deep.OpReplace, Path: "/Inner", Old: struct{ X, Y int }{2, 0}, New: struct{ X, Y int }{5, 5}}},
+			},
+			[]deep.Operation{{Kind: deep.OpReplace, Path: "/Inner", Old: struct{ X, Y int }{1, 0}, New: struct{ X, Y int }{5, 5}}},
+		},
+		{
+			"ChildAfterParent",
+			[][]deep.Operation{
+				{{Kind: deep.OpAdd, Path: "/M", New: map[string]int{"a": 1}}},
+				{{Kind: deep.OpAdd, Path: "/M/b", New: 2}},
+			},
+			[]deep.Operation{{Kind: deep.OpAdd, Path: "/M", New: map[string]int{"a": 1, "b": 2}}},
+		},
+		{
+			"MoveChain",
+			[][]deep.Operation{
+				{{Kind: deep.OpMove, Path: "/M/b", Old: "/M/a"}},
+				{{Kind: deep.OpMove, Path: "/M/c", Old: "/M/b"}},
+			},
+			[]deep.Operation{{Kind: deep.OpMove, Path: "/M/c", Old: "/M/a"}},
+		},
+		{
+			"CopyThenMove",
+			[][]deep.Operation{
+				{{Kind: deep.OpCopy, Path: "/B", Old: "/A"}},
+				{{Kind: deep.OpMove, Path: "/M/x", Old: "/B"}},
+			},
+			[]deep.Operation{{Kind: deep.OpCopy, Path: "/M/x", Old: "/A"}},
+		},
+		{
+			"MoveBack",
+			[][]deep.Operation{
+				{{Kind: deep.OpMove, Path: "/M/b", Old: "/M/a"}},
+				{{Kind: deep.OpMove, Path: "/M/a", Old: "/M/b"}},
+			},
+			nil,
+		},
+		{
+			"InterveningRead",
+			[][]deep.Operation{
+				{{Kind: deep.OpReplace, Path: "/A", Old: "a", New: "b"}},
+				{{Kind: deep.OpCopy, Path: "/B", Old: "/A"}},
+				{{Kind: deep.OpReplace, Path: "/A", Old: "b", New: "c"}},
+			},
+			[]deep.Operation{
+				{Kind: deep.OpReplace, Path: "/A", Old: "a", New: "b"},
+				{Kind: deep.OpCopy, Path: "/B", Old: "/A"},
+				{Kind: deep.OpReplace, Path: "/A", Old: "b", New: "c"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patches []deep.Patch[Doc]
+			for _, ops := range tt.in {
+				patches = append(patches, deep.Patch[Doc]{Operations: ops})
+			}
+			got := deep.Compose(patches...)
+			if !reflect.DeepEqual(got.Operations, tt.want) {
+				t.Errorf("Compose() = %+v, want %+v", got.Operations, tt.want)
+			}
+		})
+	}
+}
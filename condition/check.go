@@ -0,0 +1,326 @@
+package condition
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+
+	icore "github.com/brunoga/deep/v5/internal/core"
+)
+
+// CheckError describes a condition that cannot work on the type it was
+// checked against.
+type CheckError struct {
+	Path string
+	Op   string
+	Err  error
+}
+
+func (e *CheckError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("condition %s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("condition %s on %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+// Check checks c against the structure of T without evaluating it, so that
+// conditions that could never hold, or would fail at evaluation, are rejected
+// when they are written. It resolves every path (and reference) of c in T and
+// checks that:
+//
+//   - the operator suits the kind of the value: ordering comparisons and
+//     between need numbers, strings or a type with a Compare method (such as
+//     time.Time); startsWith and endsWith need strings; contains needs a
+//     string or a slice; length comparisons need a string, slice, array or map
+//   - Value can be converted to the type at the path: numbers for numbers
+//     (integral for integers), strings for strings, nil for nillable types,
+//     each element for in, a [low, high] pair for between and an integer for
+//     length comparisons
+//   - a referenced path holds a value comparable with the one at the path
+//   - matches has a valid regular expression, type a known type name, and
+//     quantifiers a sub-condition with a wildcard path
+//
+// Paths below interface-typed values cannot be resolved statically and are
+// accepted. Check returns nil if it finds no problem, and otherwise every
+// problem joined with [errors.Join], each as a *[CheckError].
+func Check[T any](c *Condition) error {
+	return CheckAgainst(reflect.TypeOf((*T)(nil)).Elem(), c)
+}
+
+// CheckAgainst is like [Check] with the root type given at runtime.
+func CheckAgainst(typ reflect.Type, c *Condition) error {
+	var errs []error
+	check(typ, c, func(e *CheckError) { errs = append(errs, e) })
+	return errors.Join(errs...)
+}
+
+// check reports the problems of c, and of its sub-conditions, to report.
+func check(root reflect.Type, c *Condition, report func(*CheckError)) {
+	if c == nil {
+		return
+	}
+	fail := func(format string, args ...any) {
+		report(&CheckError{Path: c.Path, Op: c.Op, Err: fmt.Errorf(format, args...)})
+	}
+
+	switch c.Op {
+	case And, Or:
+		for _, sub := range c.Sub {
+			check(root, sub, report)
+		}
+		return
+	case Not:
+		if len(c.Sub) != 1 {
+			fail("requires exactly one sub-condition")
+		}
+		for _, sub := range c.Sub {
+			check(root, sub, report)
+		}
+		return
+	case Any, All, None:
+		if len(c.Sub) != 1 || c.Sub[0] == nil {
+			fail("requires exactly one sub-condition")
+		} else if _, ok := firstWildcard(c.Sub[0]); !ok {
+			fail("requires a sub-condition with a wildcard path")
+		}
+		for _, sub := range c.Sub {
+			check(root, sub, report)
+		}
+		return
+	}
+
+	typ, nillable, ok := resolveType(root, c.Path, fail)
+	var ref reflect.Type
+	if c.Ref != "" {
+		if c.Op == Between || c.Op == Exists {
+			fail("does not take a path reference")
+			return
+		}
+		var refOK bool
+		if ref, _, refOK = resolveType(root, c.Ref, func(format string, args ...any) {
+			report(&CheckError{Path: c.Ref, Op: c.Op, Err: fmt.Errorf(format, args...)})
+		}); !refOK {
+			return
+		}
+	}
+	if !ok {
+		return
+	}
+	if typ == nil {
+		// Below an interface: nothing more is known.
+		return
+	}
+
+	// value checks the right-hand side against want, the type it must
+	// convert to.
+	value := func(want reflect.Type) {
+		switch {
+		case ref != nil:
+			if !comparable(ref, want) {
+				fail("cannot compare %v with %v at %s", want, ref, c.Ref)
+			}
+		case c.Value == nil && nillable && want == typ:
+		case !convertible(c.Value, want):
+			fail("value %v (%T) does not convert to %v", c.Value, c.Value, want)
+		}
+	}
+
+	switch c.Op {
+	case Exists:
+	case Eq, Ne:
+		value(typ)
+	case Gt, Lt, Ge, Le:
+		if !ordered(typ) {
+			fail("%v is not ordered", typ)
+			return
+		}
+		value(typ)
+	case In:
+		if ref != nil {
+			if k := ref.Kind(); (k != reflect.Slice && k != reflect.Array) || !comparable(deref(ref.Elem()), typ) {
+				fail("%s at %s is not a list of %v", ref, c.Ref, typ)
+			}
+			return
+		}
+		list := reflect.ValueOf(c.Value)
+		if k := list.Kind(); k != reflect.Slice && k != reflect.Array {
+			fail("requires a list value, got %T", c.Value)
+			return
+		}
+		for i := 0; i < list.Len(); i++ {
+			if e := list.Index(i).Interface(); !convertible(e, typ) {
+				fail("element %v (%T) does not convert to %v", e, e, typ)
+			}
+		}
+	case Matches:
+		if ref != nil {
+			if ref.Kind() != reflect.String {
+				fail("pattern at %s is %v, not a string", c.Ref, ref)
+			}
+			return
+		}
+		pattern, ok := c.Value.(string)
+		if !ok {
+			fail("requires a string pattern, got %T", c.Value)
+			return
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			fail("invalid pattern: %v", err)
+		}
+	case Type:
+		switch c.Value {
+		case "string", "number", "boolean", "object", "array", "null":
+		default:
+			fail("unknown type name %v", c.Value)
+		}
+	case StartsWith, EndsWith:
+		if typ.Kind() != reflect.String {
+			fail("requires a string, got %v", typ)
+			return
+		}
+		value(typ)
+	case Contains:
+		switch typ.Kind() {
+		case reflect.String:
+			value(typ)
+		case reflect.Slice, reflect.Array:
+			value(deref(typ.Elem()))
+		default:
+			fail("requires a string or a slice, got %v", typ)
+		}
+	case Between:
+		if !ordered(typ) {
+			fail("%v is not ordered", typ)
+			return
+		}
+		bounds := reflect.ValueOf(c.Value)
+		if k := bounds.Kind(); (k != reflect.Slice && k != reflect.Array) || bounds.Len() != 2 {
+			fail("requires a [low, high] pair, got %v", c.Value)
+			return
+		}
+		for i := 0; i < 2; i++ {
+			if e := bounds.Index(i).Interface(); !convertible(e, typ) {
+				fail("bound %v (%T) does not convert to %v", e, e, typ)
+			}
+		}
+	default:
+		if _, ok := lenOp(c.Op); !ok {
+			fail("unknown operator")
+			return
+		}
+		switch typ.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		default:
+			fail("requires a string, slice, array or map, got %v", typ)
+			return
+		}
+		value(reflect.TypeOf(0))
+	}
+}
+
+// resolveType returns the dereferenced type at path in root, or nil if the
+// path continues below an interface, and whether the value there may be nil.
+// It reports a missing path to fail.
+func resolveType(root reflect.Type, path string, fail func(string, ...any)) (reflect.Type, bool, bool) {
+	typ, err := icore.TypeAtPath(root, path)
+	if errors.Is(err, icore.ErrInterfacePath) {
+		return nil, true, true
+	}
+	if err != nil {
+		fail("%v", err)
+		return nil, false, false
+	}
+	nillable := convertible(nil, typ)
+	typ = deref(typ)
+	if typ.Kind() == reflect.Interface {
+		return nil, true, true
+	}
+	return typ, nillable, true
+}
+
+func deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// ordered reports whether values of t can be compared with <.
+func ordered(t reflect.Type) bool {
+	if isNumber(t.Kind()) || t.Kind() == reflect.String {
+		return true
+	}
+	m, ok := t.MethodByName("Compare")
+	return ok && m.Type.NumIn() == 2 && m.Type.In(1) == t && m.Type.NumOut() == 1 && m.Type.Out(0).Kind() == reflect.Int
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// convertible reports whether v can stand for a value of type t in a
+// comparison, as evaluation converts it.
+func convertible(v any, t reflect.Type) bool {
+	if v == nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+			return true
+		}
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	vt := rv.Type()
+	if vt.AssignableTo(t) {
+		return true
+	}
+	switch {
+	case isNumber(t.Kind()):
+		if !isNumber(vt.Kind()) {
+			return false
+		}
+		f, isFloat := rv.Float, vt.Kind() == reflect.Float32 || vt.Kind() == reflect.Float64
+		switch t.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if isFloat {
+				return f() >= 0 && f() == math.Trunc(f())
+			}
+			return vt.Kind() < reflect.Uint && rv.Int() >= 0 || vt.Kind() >= reflect.Uint
+		default:
+			return !isFloat || f() == math.Trunc(f())
+		}
+	case t.Kind() == reflect.String:
+		return vt.Kind() == reflect.String
+	case t.Kind() == reflect.Bool:
+		return vt.Kind() == reflect.Bool
+	}
+	// Values of other types must be of the same underlying type.
+	return vt.ConvertibleTo(t) && !isNumber(vt.Kind()) && vt.Kind() != reflect.String
+}
+
+// comparable reports whether values of types a and b can be compared.
+func comparable(a, b reflect.Type) bool {
+	a, b = deref(a), deref(b)
+	switch {
+	case a.Kind() == reflect.Interface || b.Kind() == reflect.Interface:
+		return true
+	case isNumber(a.Kind()) && isNumber(b.Kind()):
+		return true
+	case a.Kind() == reflect.String || b.Kind() == reflect.String:
+		return a.Kind() == b.Kind()
+	}
+	return a.ConvertibleTo(b)
+}
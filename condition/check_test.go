@@ -0,0 +1,106 @@
+package condition
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type checkLine struct {
+	SKU string
+	Qty int
+}
+
+type checkOrder struct {
+	Status  string
+	Total   float64
+	Count   uint
+	Paid    bool
+	Created time.Time
+	Due     *time.Time
+	Note    *string
+	Tags    []string
+	Lines   []checkLine
+	Meta    map[string]int
+	Address struct{ City string }
+	Extra   any
+}
+
+func TestCheck(t *testing.T) {
+	for _, src := range []string{
+		`/Status == 'paid' && /Total > 10 && /Count >= 1 && /Paid == true`,
+		`/Count == 2.0 && /Total between [0, 99.5] && /Status in ['a', 'b']`,
+		`/Created < /Due && /Total > /Count && /Status != /Address/City`,
+		`/Note == null && /Tags != null && exists(/Meta/x)`,
+		`/Status matches '^p' && /Total type 'number' && /Status startsWith 'p'`,
+		`/Tags contains 'x' && /Status contains 'ai' && len(/Lines) > 0 && len(/Meta) == /Count`,
+		`any(/Lines/*/Qty > 0) && all(/Tags/* != '') && none(/Meta/* < 0)`,
+		`/Extra/anything/at/all > 'x' && /Extra == 1`,
+	} {
+		c, err := Parse(src)
+		if err != nil {
+			t.Fatalf("Parse(%s): %v", src, err)
+		}
+		if err := Check[checkOrder](c); err != nil {
+			t.Errorf("Check(%s): %v", src, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		src  string
+		path string
+	}{
+		{`/Missing == 1`, "/Missing"},
+		{`/Address > 1`, "/Address"},
+		{`/Paid < true`, "/Paid"},
+		{`/Total == 'ten'`, "/Total"},
+		{`/Count == -1`, "/Count"},
+		{`/Count == 1.5`, "/Count"},
+		{`/Status == 1`, "/Status"},
+		{`/Paid == null`, "/Paid"},
+		{`/Created > '2024-01-01'`, "/Created"},
+		{`/Status in [1, 'a']`, "/Status"},
+		{`/Status in 'a'`, "/Status"},
+		{`/Status matches 1`, "/Status"},
+		{`/Status matches '('`, "/Status"},
+		{`/Status type 'text'`, "/Status"},
+		{`/Total startsWith '1'`, "/Total"},
+		{`/Tags contains 1`, "/Tags"},
+		{`/Paid contains 'x'`, "/Paid"},
+		{`/Total between [1]`, "/Total"},
+		{`/Address between [1, 2]`, "/Address"},
+		{`len(/Total) > 1`, "/Total"},
+		{`len(/Tags) > 'x'`, "/Tags"},
+		{`/Status == /Total`, "/Status"},
+		{`/Total > /Nope`, "/Nope"},
+		{`any(/Status == 'a')`, ""},
+	} {
+		c, err := Parse(tc.src)
+		if err != nil {
+			t.Fatalf("Parse(%s): %v", tc.src, err)
+		}
+		err = Check[checkOrder](c)
+		var ce *CheckError
+		if !errors.As(err, &ce) {
+			t.Errorf("Check(%s): expected CheckError, got %v", tc.src, err)
+			continue
+		}
+		if ce.Path != tc.path {
+			t.Errorf("Check(%s): got path %q, want %q (%v)", tc.src, ce.Path, tc.path, err)
+		}
+	}
+
+	// Every problem is reported.
+	c, _ := Parse(`/Missing == 1 || /Address > 1 || /Paid > 1`)
+	err := Check[checkOrder](c)
+	if errs := err.(interface{ Unwrap() []error }).Unwrap(); len(errs) != 3 {
+		t.Errorf("expected 3 problems, got %d: %v", len(errs), err)
+	}
+
+	if err := Check[checkOrder](&Condition{Op: "nope", Path: "/Status"}); err == nil {
+		t.Error("expected an error for an unknown operator")
+	}
+	if err := Check[checkOrder](nil); err != nil {
+		t.Errorf("nil condition: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strings"
 
 	icore "github.com/brunoga/deep/v5/internal/core"
 )
@@ -23,22 +24,61 @@ const (
 	And     = "and"
 	Or      = "or"
 	Not     = "not"
+
+	StartsWith = "startsWith"
+	EndsWith   = "endsWith"
+	Contains   = "contains" // substring of a string, or element of a slice or array
+	Between    = "between"  // inclusive; Value is a two-element [low, high] slice
+
+	// Length comparisons compare the length of a string, slice, array or map
+	// with Value.
+	LenEq = "len=="
+	LenNe = "len!="
+	LenGt = "len>"
+	LenLt = "len<"
+	LenGe = "len>="
+	LenLe = "len<="
+
+	// Quantifiers take a single sub-condition with wildcard ("*") paths and
+	// range over the elements matched by its first wildcard; see [Evaluate].
+	Any  = "any"
+	All  = "all"
+	None = "none"
 )
 
 // Condition represents a serializable predicate for conditional application.
+//
+// A path segment "*" matches every element of a slice or array and every
+// value of a map, so a path like "/items/*/qty" may address several values.
 type Condition struct {
 	Path  string       `json:"p,omitempty"`
 	Op    string       `json:"o"` // see operator constants above
 	Value any          `json:"v,omitempty"`
+	Ref   string       `json:"r,omitempty"`     // Path of the value to compare with, in place of Value
 	Sub   []*Condition `json:"apply,omitempty"` // Sub-conditions for logical operators (and, or, not)
 }
 
 // Evaluate evaluates a condition against a root value.
+//
+// A condition on a wildcard path holds if it holds for every value the path
+// matches. The quantifiers [Any], [All] and [None] bind the first wildcard in
+// the paths of their sub-condition to one element at a time, so that
+//
+//	{Op: "any", Sub: [{Path: "/roles/*", Op: "==", Value: "admin"}]}
+//
+// holds if some role is "admin". Every path under the same wildcard prefix
+// refers to the same element, and wildcards beyond it are left to nested
+// quantifiers or to the every-value rule.
 func Evaluate(root reflect.Value, c *Condition) (bool, error) {
 	if c == nil {
 		return true, nil
 	}
 
+	switch c.Op {
+	case Any, All, None:
+		return evaluateQuantifier(root, c)
+	}
+
 	if c.Op == And {
 		for _, sub := range c.Sub {
 			ok, err := Evaluate(root, sub)
@@ -67,6 +107,10 @@ func Evaluate(root reflect.Value, c *Condition) (bool, error) {
 		}
 	}
 
+	if prefix, ok := firstWildcard(c); ok {
+		return evaluateEvery(root, c, prefix)
+	}
+
 	val, err := icore.DeepPath(c.Path).Resolve(root)
 	if err != nil {
 		if c.Op == Exists {
@@ -79,8 +123,23 @@ func Evaluate(root reflect.Value, c *Condition) (bool, error) {
 		return val.IsValid(), nil
 	}
 
+	value := c.Value
+	if c.Ref != "" {
+		if c.Op == Between {
+			return false, fmt.Errorf("between does not take a path reference")
+		}
+		ref, err := icore.DeepPath(c.Ref).Resolve(root)
+		if err != nil {
+			return false, fmt.Errorf("reference %s: %w", c.Ref, err)
+		}
+		value = nil
+		if ref.IsValid() {
+			value = ref.Interface()
+		}
+	}
+
 	if c.Op == Matches {
-		pattern, ok := c.Value.(string)
+		pattern, ok := value.(string)
 		if !ok {
 			return false, fmt.Errorf("matches requires string pattern")
 		}
@@ -92,7 +151,7 @@ func Evaluate(root reflect.Value, c *Condition) (bool, error) {
 	}
 
 	if c.Op == In {
-		v := reflect.ValueOf(c.Value)
+		v := reflect.ValueOf(value)
 		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
 			return false, fmt.Errorf("in requires slice or array")
 		}
@@ -105,14 +164,215 @@ func Evaluate(root reflect.Value, c *Condition) (bool, error) {
 	}
 
 	if c.Op == Type {
-		typeName, ok := c.Value.(string)
+		typeName, ok := value.(string)
 		if !ok {
 			return false, fmt.Errorf("type requires string value")
 		}
 		return CheckType(val.Interface(), typeName), nil
 	}
 
-	return icore.CompareValues(val, reflect.ValueOf(c.Value), c.Op, false)
+	if c.Op == StartsWith || c.Op == EndsWith {
+		s, ok := value.(string)
+		if !ok || !val.IsValid() || val.Kind() != reflect.String {
+			return false, fmt.Errorf("%s requires string values", c.Op)
+		}
+		if c.Op == StartsWith {
+			return strings.HasPrefix(val.String(), s), nil
+		}
+		return strings.HasSuffix(val.String(), s), nil
+	}
+
+	if c.Op == Contains {
+		return contains(val, value)
+	}
+
+	if c.Op == Between {
+		if !val.IsValid() {
+			return false, fmt.Errorf("between requires a value at %s", c.Path)
+		}
+		return BetweenValues(val.Interface(), value)
+	}
+
+	if op, ok := lenOp(c.Op); ok {
+		if !val.IsValid() {
+			return false, fmt.Errorf("%s requires a string, slice, array or map", c.Op)
+		}
+		switch val.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			return icore.CompareValues(reflect.ValueOf(val.Len()), reflect.ValueOf(value), op, false)
+		}
+		return false, fmt.Errorf("%s requires a string, slice, array or map, got %v", c.Op, val.Type())
+	}
+
+	return icore.CompareValues(val, reflect.ValueOf(value), c.Op, false)
+}
+
+// lenOp returns the comparison operator of a length comparison.
+func lenOp(op string) (string, bool) {
+	cmp, ok := strings.CutPrefix(op, "len")
+	if !ok {
+		return "", false
+	}
+	switch cmp {
+	case Eq, Ne, Gt, Lt, Ge, Le:
+		return cmp, true
+	}
+	return "", false
+}
+
+// contains reports whether val, a string or a slice or array, contains v.
+func contains(val reflect.Value, v any) (bool, error) {
+	if !val.IsValid() {
+		return false, fmt.Errorf("contains requires a string, slice or array")
+	}
+	switch val.Kind() {
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return false, fmt.Errorf("contains requires a string value for a string")
+		}
+		return strings.Contains(val.String(), s), nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			if ok, err := icore.CompareValues(val.Index(i), reflect.ValueOf(v), Eq, false); err == nil && ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("contains requires a string, slice or array, got %v", val.Type())
+}
+
+// BetweenValues reports whether low <= v <= high, where bounds is a
+// two-element [low, high] slice or array. It implements [Between] and is used
+// by generated code.
+func BetweenValues(v any, bounds any) (bool, error) {
+	val := reflect.ValueOf(v)
+	b := reflect.ValueOf(bounds)
+	if (b.Kind() != reflect.Slice && b.Kind() != reflect.Array) || b.Len() != 2 {
+		return false, fmt.Errorf("between requires a [low, high] pair")
+	}
+	low, high := b.Index(0), b.Index(1)
+	if low.Kind() == reflect.Interface {
+		low, high = low.Elem(), high.Elem()
+	}
+	ok, err := icore.CompareValues(val, low, Ge, false)
+	if err != nil || !ok {
+		return false, err
+	}
+	return icore.CompareValues(val, high, Le, false)
+}
+
+// CompareLen compares the length n with v using the length comparison op. It
+// is used by generated code.
+func CompareLen(n int, op string, v any) (bool, error) {
+	cmp, ok := lenOp(op)
+	if !ok {
+		return false, fmt.Errorf("unknown length comparison %s", op)
+	}
+	return icore.CompareValues(reflect.ValueOf(n), reflect.ValueOf(v), cmp, false)
+}
+
+// evaluateQuantifier evaluates an any, all or none condition.
+func evaluateQuantifier(root reflect.Value, c *Condition) (bool, error) {
+	if len(c.Sub) != 1 || c.Sub[0] == nil {
+		return false, fmt.Errorf("%s requires exactly one sub-condition", c.Op)
+	}
+	prefix, ok := firstWildcard(c.Sub[0])
+	if !ok {
+		return false, fmt.Errorf("%s requires a sub-condition with a wildcard path", c.Op)
+	}
+	paths, err := icore.DeepPath(prefix).Expand(root)
+	if err != nil {
+		return false, err
+	}
+	for _, path := range paths {
+		ok, err := Evaluate(root, bindWildcard(c.Sub[0], prefix, path))
+		switch c.Op {
+		case All:
+			// Like and, errors fail the condition.
+			if err != nil || !ok {
+				return false, err
+			}
+		case Any:
+			// Like or, elements that fail to evaluate do not match.
+			if err == nil && ok {
+				return true, nil
+			}
+		case None:
+			if err == nil && ok {
+				return false, nil
+			}
+		}
+	}
+	return c.Op != Any, nil
+}
+
+// evaluateEvery evaluates a non-logical condition with wildcard paths for
+// every element matched by prefix, its first wildcard.
+func evaluateEvery(root reflect.Value, c *Condition, prefix string) (bool, error) {
+	paths, err := icore.DeepPath(prefix).Expand(root)
+	if err != nil {
+		if c.Op == Exists {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, path := range paths {
+		ok, err := Evaluate(root, bindWildcard(c, prefix, path))
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// firstWildcard returns the wildcard prefix of the first path in c that has
+// one.
+func firstWildcard(c *Condition) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	if len(c.Sub) > 0 {
+		for _, sub := range c.Sub {
+			if prefix, ok := firstWildcard(sub); ok {
+				return prefix, true
+			}
+		}
+		return "", false
+	}
+	if prefix, ok := icore.WildcardPrefix(c.Path); ok {
+		return prefix, true
+	}
+	return icore.WildcardPrefix(c.Ref)
+}
+
+// bindWildcard returns a copy of c with the wildcard prefix replaced by path
+// in every path under it.
+func bindWildcard(c *Condition, prefix, path string) *Condition {
+	if c == nil {
+		return nil
+	}
+	res := *c
+	if len(c.Sub) > 0 {
+		res.Sub = make([]*Condition, len(c.Sub))
+		for i, sub := range c.Sub {
+			res.Sub[i] = bindWildcard(sub, prefix, path)
+		}
+		return &res
+	}
+	res.Path = bindPath(c.Path, prefix, path)
+	if c.Ref != "" {
+		res.Ref = bindPath(c.Ref, prefix, path)
+	}
+	return &res
+}
+
+func bindPath(p, prefix, path string) string {
+	if n := icore.NormalizePath(p); n == prefix || strings.HasPrefix(n, prefix+"/") {
+		return path + n[len(prefix):]
+	}
+	return p
 }
 
 // ToPredicate returns a JSON-serializable map representing the condition in
@@ -131,7 +391,7 @@ func (c *Condition) ToPredicate() map[string]any {
 		return map[string]any{
 			"op": "not",
 			"apply": []map[string]any{
-				{"op": "test", "path": c.Path, "value": c.Value},
+				(&Condition{Path: c.Path, Op: Eq, Value: c.Value, Ref: c.Ref}).ToPredicate(),
 			},
 		}
 	case Gt:
@@ -152,7 +412,14 @@ func (c *Condition) ToPredicate() map[string]any {
 		op = "matches"
 	case Type:
 		op = "type"
-	case And, Or, Not:
+	case StartsWith:
+		op = "starts"
+	case EndsWith:
+		op = "ends"
+	case Contains:
+		// "contains" is taken by In on the wire.
+		op = "includes"
+	case And, Or, Not, Any, All, None:
 		res := map[string]any{
 			"op": op,
 		}
@@ -164,11 +431,15 @@ func (c *Condition) ToPredicate() map[string]any {
 		return res
 	}
 
-	return map[string]any{
+	res := map[string]any{
 		"op":    op,
 		"path":  c.Path,
 		"value": c.Value,
 	}
+	if c.Ref != "" {
+		res["ref"] = c.Ref
+	}
+	return res
 }
 
 // FromPredicate parses a JSON Patch predicate wire-format map into a
@@ -180,10 +451,11 @@ func FromPredicate(m map[string]any) *Condition {
 	op, _ := m["op"].(string)
 	path, _ := m["path"].(string)
 	value := m["value"]
+	ref, _ := m["ref"].(string)
 
 	switch op {
 	case "test":
-		return &Condition{Path: path, Op: Eq, Value: value}
+		return &Condition{Path: path, Op: Eq, Value: value, Ref: ref}
 	case "not":
 		// Could be encoded != or a logical not.
 		// If it wraps a single test on the same path, treat as !=.
@@ -191,28 +463,36 @@ func FromPredicate(m map[string]any) *Condition {
 			if inner, ok := apply[0].(map[string]any); ok {
 				if inner["op"] == "test" {
 					innerPath, _ := inner["path"].(string)
-					return &Condition{Path: innerPath, Op: Ne, Value: inner["value"]}
+					innerRef, _ := inner["ref"].(string)
+					return &Condition{Path: innerPath, Op: Ne, Value: inner["value"], Ref: innerRef}
 				}
 			}
 		}
 		return &Condition{Op: Not, Sub: parseApply(m["apply"])}
 	case "more":
-		return &Condition{Path: path, Op: Gt, Value: value}
+		return &Condition{Path: path, Op: Gt, Value: value, Ref: ref}
 	case "more-or-equal":
-		return &Condition{Path: path, Op: Ge, Value: value}
+		return &Condition{Path: path, Op: Ge, Value: value, Ref: ref}
 	case "less":
-		return &Condition{Path: path, Op: Lt, Value: value}
+		return &Condition{Path: path, Op: Lt, Value: value, Ref: ref}
 	case "less-or-equal":
-		return &Condition{Path: path, Op: Le, Value: value}
+		return &Condition{Path: path, Op: Le, Value: value, Ref: ref}
 	case "defined":
 		return &Condition{Path: path, Op: Exists}
 	case "contains":
-		return &Condition{Path: path, Op: In, Value: value}
-	case And, Or:
+		return &Condition{Path: path, Op: In, Value: value, Ref: ref}
+	case "starts":
+		return &Condition{Path: path, Op: StartsWith, Value: value, Ref: ref}
+	case "ends":
+		return &Condition{Path: path, Op: EndsWith, Value: value, Ref: ref}
+	case "includes":
+		return &Condition{Path: path, Op: Contains, Value: value, Ref: ref}
+	case And, Or, Any, All, None:
 		return &Condition{Op: op, Sub: parseApply(m["apply"])}
 	default:
-		// log, matches, type — same op name, pass through
-		return &Condition{Path: path, Op: op, Value: value}
+		// log, matches, type, between, length comparisons — same op name,
+		// pass through
+		return &Condition{Path: path, Op: op, Value: value, Ref: ref}
 	}
 }
 
@@ -1,6 +1,7 @@
 package condition
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 )
@@ -76,3 +77,106 @@ func TestEvaluate(t *testing.T) {
 		}
 	}
 }
+
+func TestEvaluateQuantifiers(t *testing.T) {
+	type order struct {
+		ID    int
+		Items []struct{ Qty []int }
+	}
+	o := order{ID: 1, Items: []struct{ Qty []int }{{Qty: []int{1, 2}}, {Qty: []int{0}}}}
+	root := reflect.ValueOf(o)
+
+	leaf := func(path, op string, v any) *Condition { return &Condition{Path: path, Op: op, Value: v} }
+	tests := []struct {
+		c    *Condition
+		want bool
+	}{
+		{&Condition{Op: Any, Sub: []*Condition{leaf("/Items/*/Qty/*", "==", 0)}}, true},
+		{&Condition{Op: All, Sub: []*Condition{
+			{Op: Any, Sub: []*Condition{leaf("/Items/*/Qty/*", ">", 1)}},
+		}}, false},
+		{&Condition{Op: Any, Sub: []*Condition{
+			{Op: All, Sub: []*Condition{leaf("/Items/*/Qty/*", ">", 0)}},
+		}}, true},
+		{&Condition{Op: None, Sub: []*Condition{leaf("/Items/*/Qty/*", "<", 0)}}, true},
+		{leaf("/Items/*/Qty/*", ">=", 0), true},
+		{&Condition{Op: Exists, Path: "/ID/*"}, false},
+	}
+	for i, tt := range tests {
+		got, err := Evaluate(root, tt.c)
+		if err != nil {
+			t.Errorf("%d: Evaluate error: %v", i, err)
+		}
+		if got != tt.want {
+			t.Errorf("%d: Evaluate = %v, want %v", i, got, tt.want)
+		}
+	}
+
+	if _, err := Evaluate(root, &Condition{Op: Any, Sub: []*Condition{leaf("/ID", "==", 1)}}); err == nil {
+		t.Error("expected error for quantifier without wildcard")
+	}
+}
+
+func TestEvaluateStringAndLengthOps(t *testing.T) {
+	type doc struct {
+		Title string
+		Tags  []string
+		Pages map[string]int
+		Min   int
+		Max   int
+	}
+	root := reflect.ValueOf(doc{Title: "Go in Action", Tags: []string{"go"}, Pages: map[string]int{"a": 1}, Min: 1, Max: 3})
+
+	tests := []struct {
+		c    *Condition
+		want bool
+	}{
+		{&Condition{Op: StartsWith, Path: "/Title", Value: "Go"}, true},
+		{&Condition{Op: EndsWith, Path: "/Title", Value: "Go"}, false},
+		{&Condition{Op: Contains, Path: "/Title", Value: "in"}, true},
+		{&Condition{Op: Contains, Path: "/Tags", Value: "go"}, true},
+		{&Condition{Op: Between, Path: "/Min", Value: []any{0.0, 1.0}}, true},
+		{&Condition{Op: LenGt, Path: "/Pages", Value: 0}, true},
+		{&Condition{Op: LenEq, Path: "/Tags", Value: float64(2)}, false},
+		{&Condition{Op: Lt, Path: "/Min", Ref: "/Max"}, true},
+		{&Condition{Op: Ne, Path: "/Min", Ref: "/Max"}, true},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(root, tt.c)
+		if err != nil {
+			t.Errorf("Evaluate(%s %s) error: %v", tt.c.Op, tt.c.Path, err)
+		}
+		if got != tt.want {
+			t.Errorf("Evaluate(%s %s) = %v, want %v", tt.c.Op, tt.c.Path, got, tt.want)
+		}
+
+		// The predicate wire format keeps the operator and reference.
+		back := FromPredicate(roundTripPredicate(t, tt.c))
+		if back.Op != tt.c.Op || back.Ref != tt.c.Ref {
+			t.Errorf("predicate round trip of %s: got %s %q", tt.c.Op, back.Op, back.Ref)
+		}
+	}
+
+	for _, c := range []*Condition{
+		{Op: StartsWith, Path: "/Min", Value: "1"},
+		{Op: LenGt, Path: "/Min", Value: 0},
+		{Op: Between, Path: "/Min", Value: 1},
+	} {
+		if _, err := Evaluate(root, c); err == nil {
+			t.Errorf("Evaluate(%s %s): expected error", c.Op, c.Path)
+		}
+	}
+}
+
+func roundTripPredicate(t *testing.T, c *Condition) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(c.ToPredicate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
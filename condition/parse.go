@@ -0,0 +1,729 @@
+package condition
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseError reports a syntax error in a condition expression.
+type ParseError struct {
+	Offset int // byte offset of the error in the input
+	Line   int // 1-based line of the error
+	Column int // 1-based column (in runes) of the error
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("condition: %d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+// Parse parses a condition expression such as
+//
+//	/status == 'paid' && (/balance > 0 || !exists(/refund))
+//
+// The grammar, from lowest to highest precedence:
+//
+//	expr    = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | primary
+//	primary = "(" expr ")" | "true" | "false"
+//	        | "exists" "(" path ")"
+//	        | ("any" | "all" | "none") "(" expr ")"
+//	        | "len" "(" path ")" cmp operand
+//	        | path op operand
+//	op      = cmp | "in" | "matches" | "type" | "startsWith" | "endsWith"
+//	        | "contains" | "between"
+//	cmp     = "==" | "!=" | "<" | ">" | "<=" | ">="
+//	operand = literal | path
+//	literal = string | number | "true" | "false" | "null" | "[" [literal {"," literal}] "]"
+//
+// Paths are JSON Pointers written as is (/items/*/qty), or between backquotes
+// if a segment contains spaces or operator characters. Strings are single- or
+// double-quoted with Go escapes. A path on the right-hand side is compared by
+// value (see [Condition.Ref]). "true" and "false" as conditions are an empty
+// and and an empty or.
+//
+// [Condition.String] prints conditions in this syntax.
+func Parse(s string) (*Condition, error) {
+	p := &parser{src: s}
+	p.next()
+	c, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return c, nil
+}
+
+// UnmarshalJSON decodes a condition from its JSON object form or from a
+// string holding an expression accepted by [Parse], so that conditions in
+// hand-edited patch JSON can be written as text.
+func (c *Condition) UnmarshalJSON(data []byte) error {
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '"' {
+		var expr string
+		if err := json.Unmarshal(data, &expr); err != nil {
+			return err
+		}
+		parsed, err := Parse(expr)
+		if err != nil {
+			return err
+		}
+		*c = *parsed
+		return nil
+	}
+	type plain Condition
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+// String returns c in the expression syntax of [Parse]. Values other than
+// strings, numbers, booleans, nil and slices of those are printed as their
+// JSON encoding.
+func (c *Condition) String() string {
+	var b strings.Builder
+	writeCondition(&b, c, precOr)
+	return b.String()
+}
+
+// Operator precedences, from loosest to tightest.
+const (
+	precOr = iota
+	precAnd
+	precUnary
+)
+
+func writeCondition(b *strings.Builder, c *Condition, prec int) {
+	if c == nil {
+		b.WriteString("true")
+		return
+	}
+	switch c.Op {
+	case And, Or:
+		if len(c.Sub) == 0 {
+			if c.Op == And {
+				b.WriteString("true")
+			} else {
+				b.WriteString("false")
+			}
+			return
+		}
+		if len(c.Sub) == 1 {
+			writeCondition(b, c.Sub[0], prec)
+			return
+		}
+		sep, own := " && ", precAnd
+		if c.Op == Or {
+			sep, own = " || ", precOr
+		}
+		if prec > own {
+			b.WriteByte('(')
+		}
+		for i, sub := range c.Sub {
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			// Nested operators of the same kind keep their grouping.
+			writeCondition(b, sub, own+1)
+		}
+		if prec > own {
+			b.WriteByte(')')
+		}
+	case Not:
+		b.WriteByte('!')
+		if len(c.Sub) == 0 {
+			b.WriteString("true")
+			return
+		}
+		sub := c.Sub[0]
+		if sub != nil && (isFunction(sub) || (sub.Op == Not && len(sub.Sub) > 0)) {
+			writeCondition(b, sub, precUnary)
+			return
+		}
+		b.WriteByte('(')
+		writeCondition(b, sub, precOr)
+		b.WriteByte(')')
+	case Any, All, None:
+		b.WriteString(c.Op)
+		b.WriteByte('(')
+		if len(c.Sub) > 0 {
+			writeCondition(b, c.Sub[0], precOr)
+		}
+		b.WriteByte(')')
+	case Exists:
+		b.WriteString("exists(")
+		writePath(b, c.Path)
+		b.WriteByte(')')
+	default:
+		if cmp, ok := lenOp(c.Op); ok {
+			b.WriteString("len(")
+			writePath(b, c.Path)
+			b.WriteString(") ")
+			b.WriteString(cmp)
+		} else {
+			writePath(b, c.Path)
+			b.WriteByte(' ')
+			b.WriteString(c.Op)
+		}
+		b.WriteByte(' ')
+		if c.Ref != "" {
+			writePath(b, c.Ref)
+		} else {
+			writeValue(b, c.Value)
+		}
+	}
+}
+
+// isFunction reports whether c prints in function form, which binds tighter
+// than "!".
+func isFunction(c *Condition) bool {
+	switch c.Op {
+	case Exists, Any, All, None:
+		return true
+	case And, Or:
+		return len(c.Sub) == 0
+	}
+	return false
+}
+
+func writePath(b *strings.Builder, path string) {
+	if path == "" {
+		path = "/"
+	}
+	bare := path[0] == '/'
+	for _, r := range path {
+		if isPathDelim(r) {
+			bare = false
+			break
+		}
+	}
+	if bare {
+		b.WriteString(path)
+		return
+	}
+	b.WriteByte('`')
+	for _, r := range path {
+		if r == '`' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('`')
+}
+
+func writeValue(b *strings.Builder, v any) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+		return
+	case string:
+		writeString(b, v)
+		return
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+		return
+	case float32:
+		writeFloat(b, float64(v))
+		return
+	case float64:
+		writeFloat(b, v)
+		return
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(strconv.FormatInt(rv.Int(), 10))
+		return
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		b.WriteString(strconv.FormatUint(rv.Uint(), 10))
+		return
+	case reflect.Slice, reflect.Array:
+		b.WriteByte('[')
+		for i := 0; i < rv.Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeValue(b, rv.Index(i).Interface())
+		}
+		b.WriteByte(']')
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeString(b, fmt.Sprint(v))
+		return
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		writeString(b, string(data))
+		return
+	}
+	if _, ok := decoded.(map[string]any); ok {
+		// There is no object syntax.
+		writeString(b, string(data))
+		return
+	}
+	writeValue(b, decoded)
+}
+
+// writeFloat writes f so that it parses back as a float rather than an int.
+// Infinities and NaN have no literal syntax.
+func writeFloat(b *strings.Builder, f float64) {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !math.IsInf(f, 0) && !math.IsNaN(f) && !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	b.WriteString(s)
+}
+
+func writeString(b *strings.Builder, s string) {
+	b.WriteByte('\'')
+	for i, r := range s {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				// Not valid UTF-8: keep the byte.
+				fmt.Fprintf(b, `\x%02x`, s[i])
+				continue
+			}
+		}
+		switch r {
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == utf8.RuneError {
+				fmt.Fprintf(b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('\'')
+}
+
+// isPathDelim reports whether r ends a bare path.
+func isPathDelim(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r', '(', ')', '[', ']', ',', '!', '=', '<', '>', '&', '|', '\'', '"', '`':
+		return true
+	}
+	return false
+}
+
+// ── lexer ────────────────────────────────────────────────────────────────────
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokPath
+	tokString
+	tokNumber
+	tokIdent
+	tokPunct
+)
+
+type token struct {
+	kind tokKind
+	text string // punctuation, identifier or number text; decoded path or string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokPath:
+		return "path " + t.text
+	}
+	return strconv.Quote(t.text)
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+	err error
+}
+
+func (p *parser) errorAt(pos int, format string, args ...any) error {
+	line, col := 1, 1
+	for _, r := range p.src[:pos] {
+		if r == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return &ParseError{Offset: pos, Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+	return p.errorAt(p.tok.pos, format, args...)
+}
+
+// next scans the next token into p.tok. Lexical errors are kept in p.err and
+// reported by the parser as it reaches them.
+func (p *parser) next() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\n\r", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case c == '/':
+		end := p.pos
+		for end < len(p.src) {
+			r, size := utf8.DecodeRuneInString(p.src[end:])
+			if isPathDelim(r) {
+				break
+			}
+			end += size
+		}
+		p.tok = token{kind: tokPath, text: p.src[p.pos:end], pos: start}
+		p.pos = end
+	case c == '`':
+		var b strings.Builder
+		p.pos++
+		for {
+			if p.pos >= len(p.src) {
+				p.fail(start, "unterminated path")
+				return
+			}
+			r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+			p.pos += size
+			if r == '`' {
+				break
+			}
+			if r == '\\' && p.pos < len(p.src) {
+				r, size = utf8.DecodeRuneInString(p.src[p.pos:])
+				p.pos += size
+			}
+			b.WriteRune(r)
+		}
+		// `` is the root, as it is in a JSON Pointer; it prints as /.
+		text := b.String()
+		if text == "" {
+			text = "/"
+		}
+		p.tok = token{kind: tokPath, text: text, pos: start}
+	case c == '\'' || c == '"':
+		end, s, err := scanString(p.src, p.pos)
+		if err != nil {
+			p.fail(start, "%v", err)
+			return
+		}
+		p.tok = token{kind: tokString, text: s, pos: start}
+		p.pos = end
+	case c == '-' || (c >= '0' && c <= '9'):
+		end := p.pos + 1
+		for end < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[end]) >= 0 {
+			if (p.src[end] == '+' || p.src[end] == '-') && p.src[end-1] != 'e' && p.src[end-1] != 'E' {
+				break
+			}
+			end++
+		}
+		p.tok = token{kind: tokNumber, text: p.src[p.pos:end], pos: start}
+		p.pos = end
+	case isIdentStart(c):
+		end := p.pos
+		for end < len(p.src) && (isIdentStart(p.src[end]) || (p.src[end] >= '0' && p.src[end] <= '9')) {
+			end++
+		}
+		p.tok = token{kind: tokIdent, text: p.src[p.pos:end], pos: start}
+		p.pos = end
+	default:
+		for _, punct := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","} {
+			if strings.HasPrefix(p.src[p.pos:], punct) {
+				p.tok = token{kind: tokPunct, text: punct, pos: start}
+				p.pos += len(punct)
+				return
+			}
+		}
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		p.fail(start, "unexpected character %q", r)
+	}
+}
+
+// fail records a lexical error and ends the token stream.
+func (p *parser) fail(pos int, format string, args ...any) {
+	if p.err == nil {
+		p.err = p.errorAt(pos, format, args...)
+	}
+	p.tok = token{kind: tokEOF, pos: pos}
+	p.pos = len(p.src)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// scanString scans the quoted string starting at src[start] and returns the
+// offset after it and its decoded value.
+func scanString(src string, start int) (int, string, error) {
+	quote := src[start]
+	var b strings.Builder
+	for i := start + 1; i < len(src); {
+		c := src[i]
+		switch {
+		case c == quote:
+			return i + 1, b.String(), nil
+		case c == '\\':
+			// UnquoteChar handles the escape; it needs to be told which quote
+			// may appear escaped.
+			r, multibyte, tail, err := strconv.UnquoteChar(src[i:], quote)
+			if err != nil {
+				return 0, "", fmt.Errorf("invalid escape in string literal")
+			}
+			// \x and octal escapes stand for single bytes.
+			if multibyte {
+				b.WriteRune(r)
+			} else {
+				b.WriteByte(byte(r))
+			}
+			i = len(src) - len(tail)
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return 0, "", fmt.Errorf("unterminated string")
+}
+
+// ── parser ───────────────────────────────────────────────────────────────────
+
+func (p *parser) is(text string) bool {
+	return (p.tok.kind == tokPunct || p.tok.kind == tokIdent) && p.tok.text == text
+}
+
+func (p *parser) expect(text string) error {
+	if !p.is(text) {
+		return p.errorf("expected %q, found %s", text, p.tok)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) expr() (*Condition, error) {
+	return p.binary("||", Or, p.and)
+}
+
+func (p *parser) and() (*Condition, error) {
+	return p.binary("&&", And, p.unary)
+}
+
+func (p *parser) binary(sep, op string, operand func() (*Condition, error)) (*Condition, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	if !p.is(sep) {
+		return first, nil
+	}
+	res := &Condition{Op: op, Sub: []*Condition{first}}
+	for p.is(sep) {
+		p.next()
+		c, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		res.Sub = append(res.Sub, c)
+	}
+	return res, nil
+}
+
+func (p *parser) unary() (*Condition, error) {
+	if p.is("!") {
+		p.next()
+		c, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Condition{Op: Not, Sub: []*Condition{c}}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (*Condition, error) {
+	switch p.tok.kind {
+	case tokPath:
+		path := p.tok.text
+		p.next()
+		return p.comparison(path)
+	case tokPunct:
+		if p.is("(") {
+			p.next()
+			c, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return c, p.expect(")")
+		}
+	case tokIdent:
+		switch name := p.tok.text; name {
+		case "true":
+			p.next()
+			return &Condition{Op: And}, nil
+		case "false":
+			p.next()
+			return &Condition{Op: Or}, nil
+		case Exists:
+			p.next()
+			path, err := p.parenPath()
+			if err != nil {
+				return nil, err
+			}
+			return &Condition{Path: path, Op: Exists}, nil
+		case Any, All, None:
+			p.next()
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			c, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return &Condition{Op: name, Sub: []*Condition{c}}, p.expect(")")
+		case "len":
+			p.next()
+			path, err := p.parenPath()
+			if err != nil {
+				return nil, err
+			}
+			if !isCmp(p.tok) {
+				return nil, p.errorf("expected comparison after len(...), found %s", p.tok)
+			}
+			op := "len" + p.tok.text
+			p.next()
+			return p.operand(&Condition{Path: path, Op: op})
+		}
+	}
+	return nil, p.errorf("expected condition, found %s", p.tok)
+}
+
+func (p *parser) parenPath() (string, error) {
+	if err := p.expect("("); err != nil {
+		return "", err
+	}
+	if p.tok.kind != tokPath {
+		return "", p.errorf("expected path, found %s", p.tok)
+	}
+	path := p.tok.text
+	p.next()
+	return path, p.expect(")")
+}
+
+func isCmp(t token) bool {
+	if t.kind != tokPunct {
+		return false
+	}
+	switch t.text {
+	case Eq, Ne, Lt, Gt, Le, Ge:
+		return true
+	}
+	return false
+}
+
+func (p *parser) comparison(path string) (*Condition, error) {
+	c := &Condition{Path: path}
+	switch {
+	case isCmp(p.tok):
+		c.Op = p.tok.text
+	case p.tok.kind == tokIdent:
+		switch p.tok.text {
+		case In, Matches, Type, StartsWith, EndsWith, Contains, Between:
+			c.Op = p.tok.text
+		default:
+			return nil, p.errorf("unknown operator %q", p.tok.text)
+		}
+	default:
+		return nil, p.errorf("expected operator after %s, found %s", path, p.tok)
+	}
+	p.next()
+	return p.operand(c)
+}
+
+// operand parses the right-hand side of c.
+func (p *parser) operand(c *Condition) (*Condition, error) {
+	if p.tok.kind == tokPath {
+		c.Ref = p.tok.text
+		p.next()
+		return c, nil
+	}
+	v, err := p.literal()
+	if err != nil {
+		return nil, err
+	}
+	c.Value = v
+	return c, nil
+}
+
+func (p *parser) literal() (any, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokString:
+		p.next()
+		return tok.text, nil
+	case tokNumber:
+		p.next()
+		if !strings.ContainsAny(tok.text, ".eE") {
+			if i, err := strconv.Atoi(tok.text); err == nil {
+				return i, nil
+			}
+		}
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorAt(tok.pos, "invalid number %s", tok.text)
+		}
+		return f, nil
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			p.next()
+			return tok.text == "true", nil
+		case "null":
+			p.next()
+			return nil, nil
+		}
+	case tokPunct:
+		if tok.text == "[" {
+			p.next()
+			list := []any{}
+			for !p.is("]") {
+				if len(list) > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				v, err := p.literal()
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			p.next()
+			return list, nil
+		}
+	}
+	return nil, p.errorf("expected value, found %s", tok)
+}
@@ -0,0 +1,151 @@
+package condition
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		`/status == 'paid' && (/balance > 0 || !exists(/refund))`,
+		`/a == 1 || /b != 2.5 && /c < -3`,
+		`(/a == 1 || /b == 2) && /c == 3`,
+		`/a == 1 && (/b == 2 && /c == 3)`,
+		`!(/a <= 1e+21) && !!exists(/b)`,
+		`/name matches '^a\'b\\\\c$' && /tags in ['x', "y", 3, true, null]`,
+		`/t type 'string' && /s startsWith 'A' && /s endsWith 'z' && /s contains ''`,
+		`/n between [1, 10] && len(/items) >= 2 && len(/s) == /n`,
+		`/shipDate >= /orderDate`,
+		`any(/roles/* == 'admin') && none(all(/orders/*/lines/*/qty > 0))`,
+		"`/cities/New York/pop` > 1000000",
+		`true && !false`,
+	} {
+		c, err := Parse(src)
+		if err != nil {
+			t.Errorf("Parse(%s): %v", src, err)
+			continue
+		}
+		printed := c.String()
+		again, err := Parse(printed)
+		if err != nil {
+			t.Errorf("Parse(%s) (printed from %s): %v", printed, src, err)
+			continue
+		}
+		if !reflect.DeepEqual(c, again) {
+			t.Errorf("round trip of %s via %s:\n got %+v\nwant %+v", src, printed, again, c)
+		}
+		if again.String() != printed {
+			t.Errorf("String not stable: %s, then %s", printed, again.String())
+		}
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	c, err := Parse(`/status == 'paid' && (/balance > 0 || !exists(/refund))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Condition{Op: And, Sub: []*Condition{
+		{Path: "/status", Op: Eq, Value: "paid"},
+		{Op: Or, Sub: []*Condition{
+			{Path: "/balance", Op: Gt, Value: 0},
+			{Op: Not, Sub: []*Condition{{Path: "/refund", Op: Exists}}},
+		}},
+	}}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v", c)
+	}
+
+	// An empty quoted path is the root.
+	if c, err := Parse("`` > 0"); err != nil || c.Path != "/" {
+		t.Errorf("Parse(`` > 0) = %+v, %v", c, err)
+	}
+
+	// Built conditions print in the same syntax.
+	built := &Condition{Op: Or, Sub: []*Condition{
+		{Path: "/tags", Op: In, Value: []string{"a", "b"}},
+		{Path: "/ratio", Op: Ge, Value: float64(1)},
+		{Path: "/count", Op: LenLt, Value: int64(3)},
+	}}
+	if got, want := built.String(), `/tags in ['a', 'b'] || /ratio >= 1.0 || len(/count) < 3`; got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		src        string
+		line, col  int
+		wantSubstr string
+	}{
+		{`/a == `, 1, 7, "expected value"},
+		{`/a = 1`, 1, 4, "unexpected character"},
+		{`/a == 1 && (/b == 2`, 1, 20, `expected ")"`},
+		{"/a == 1 &&\n  /b foo 2", 2, 6, `unknown operator "foo"`},
+		{`/a == 'open`, 1, 7, "unterminated string"},
+		{`len(/a) in [1]`, 1, 9, "expected comparison"},
+		{`/a == 1 /b`, 1, 9, "unexpected path /b"},
+	} {
+		_, err := Parse(tt.src)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("Parse(%q) = %v, want *ParseError", tt.src, err)
+			continue
+		}
+		if pe.Line != tt.line || pe.Column != tt.col || !strings.Contains(pe.Msg, tt.wantSubstr) {
+			t.Errorf("Parse(%q) = %v, want %d:%d %s", tt.src, err, tt.line, tt.col, tt.wantSubstr)
+		}
+	}
+}
+
+func TestConditionUnmarshalJSONText(t *testing.T) {
+	var v struct {
+		If *Condition `json:"if"`
+		Un *Condition `json:"un"`
+	}
+	data := `{"if": "/qty > 0 && /sku startsWith 'A'", "un": {"p": "/locked", "o": "==", "v": true}}`
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.If.Op != And || len(v.If.Sub) != 2 || v.If.Sub[1].Op != StartsWith {
+		t.Errorf("text form: %+v", v.If)
+	}
+	if v.Un.Op != Eq || v.Un.Path != "/locked" || v.Un.Value != true {
+		t.Errorf("object form: %+v", v.Un)
+	}
+	if err := json.Unmarshal([]byte(`{"if": "/qty >"}`), &v); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func FuzzParseRoundTrip(f *testing.F) {
+	for _, src := range []string{
+		`/status == 'paid' && (/balance > 0 || !exists(/refund))`,
+		`/n between [1, 10] && len(/items) >= 2 && len(/s) == /n`,
+		`any(/roles/* == 'admin') && none(all(/orders/*/lines/*/qty > 0))`,
+		"`/cities/New York/pop` > 1000000",
+		"`` > 0",
+		"`a` == `b`",
+		`/a in ['x', "y", 3, -1.5e3, true, null]`,
+		`true && !false`,
+	} {
+		f.Add(src)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		c, err := Parse(src)
+		if err != nil {
+			return
+		}
+		printed := c.String()
+		again, err := Parse(printed)
+		if err != nil {
+			t.Fatalf("Parse(%q) (printed from %q): %v", printed, src, err)
+		}
+		if !reflect.DeepEqual(c, again) {
+			t.Fatalf("round trip of %q via %q:\n got %+v\nwant %+v", src, printed, again, c)
+		}
+	})
+}
@@ -23,6 +23,7 @@ package crdt
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"reflect"
 	"strings"
@@ -67,25 +68,154 @@ type CRDT[T any] struct {
 type Delta[T any] struct {
 	patch     deep.Patch[T]
 	Timestamp hlc.HLC `json:"t"`
+
+	// Signature authenticates the delta, including the node ID of its
+	// timestamp. Set via Sign.
+	Signature *deep.Signature `json:"sig,omitempty"`
 }
 
 func (d Delta[T]) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Patch     deep.Patch[T] `json:"p"`
-		Timestamp hlc.HLC       `json:"t"`
-	}{d.patch, d.Timestamp})
+		Patch     deep.Patch[T]   `json:"p"`
+		Timestamp hlc.HLC         `json:"t"`
+		Signature *deep.Signature `json:"sig,omitempty"`
+	}{d.patch, d.Timestamp, d.Signature})
 }
 
 func (d *Delta[T]) UnmarshalJSON(data []byte) error {
 	var m struct {
-		Patch     deep.Patch[T] `json:"p"`
-		Timestamp hlc.HLC       `json:"t"`
+		Patch     deep.Patch[T]   `json:"p"`
+		Timestamp hlc.HLC         `json:"t"`
+		Signature *deep.Signature `json:"sig"`
 	}
 	if err := json.Unmarshal(data, &m); err != nil {
 		return err
 	}
 	d.patch = m.Patch
 	d.Timestamp = m.Timestamp
+	d.Signature = m.Signature
+	return nil
+}
+
+// Sign returns a copy of d signed by s. The signature covers the patch and
+// the timestamp. For [RequireSignature] to accept the delta, the key ID of s
+// must be the ID of the node that produced it, which binds the node ID to the
+// key.
+func (d Delta[T]) Sign(s deep.Signer) (Delta[T], error) {
+	data, err := d.signingPayload()
+	if err != nil {
+		return d, err
+	}
+	sig, err := s.Sign(data)
+	if err != nil {
+		return d, err
+	}
+	d.Signature = &deep.Signature{KeyID: s.KeyID(), Value: sig}
+	return d, nil
+}
+
+// Verify checks the signature of d with v, and that it was made with the key
+// of the node named in the timestamp. It returns [deep.ErrUnsigned] if d has
+// no signature.
+func (d Delta[T]) Verify(v deep.Verifier) error {
+	if d.Signature == nil {
+		return deep.ErrUnsigned
+	}
+	if d.Signature.KeyID != d.Timestamp.NodeID {
+		return fmt.Errorf("%w: key %q cannot sign for node %q", deep.ErrInvalidSignature, d.Signature.KeyID, d.Timestamp.NodeID)
+	}
+	data, err := d.signingPayload()
+	if err != nil {
+		return err
+	}
+	return v.Verify(d.Signature.KeyID, data, d.Signature.Value)
+}
+
+// signingPayload returns the bytes a signature of d covers: the binary
+// encoding of its patch followed by its timestamp.
+func (d Delta[T]) signingPayload() ([]byte, error) {
+	p := d.patch
+	p.Signature = nil
+	patch, err := p.ToBinary()
+	if err != nil {
+		return nil, err
+	}
+	var w icore.BinaryWriter
+	w.Bytes(patch)
+	w.Varint(d.Timestamp.WallTime)
+	w.Varint(int64(d.Timestamp.Logical))
+	w.String(d.Timestamp.NodeID)
+	return w.Data(), nil
+}
+
+// ToBinary returns a compact binary encoding of the delta: its timestamp and
+// signature followed by the patch encoded with [deep.Patch.ToBinary].
+func (d Delta[T]) ToBinary(opts ...deep.BinaryOption) ([]byte, error) {
+	patch, err := d.patch.ToBinary(opts...)
+	if err != nil {
+		return nil, err
+	}
+	var w icore.BinaryWriter
+	w.Varint(d.Timestamp.WallTime)
+	w.Varint(int64(d.Timestamp.Logical))
+	w.String(d.Timestamp.NodeID)
+	if d.Signature == nil {
+		w.Byte(0)
+	} else {
+		w.Byte(1)
+		w.String(d.Signature.KeyID)
+		w.Bytes(d.Signature.Value)
+	}
+	return append(w.Data(), patch...), nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler using [Delta.ToBinary]
+// with default options.
+func (d Delta[T]) MarshalBinary() ([]byte, error) {
+	return d.ToBinary()
+}
+
+// UnmarshalBinary decodes a delta encoded by [Delta.ToBinary].
+func (d *Delta[T]) UnmarshalBinary(data []byte) error {
+	r := icore.NewBinaryReader(data)
+	var ts hlc.HLC
+	wall, err := r.Varint()
+	if err != nil {
+		return err
+	}
+	logical, err := r.Varint()
+	if err != nil {
+		return err
+	}
+	if ts.NodeID, err = r.String(); err != nil {
+		return err
+	}
+	ts.WallTime, ts.Logical = wall, int32(logical)
+
+	var sig *deep.Signature
+	signed, err := r.Byte()
+	if err != nil {
+		return err
+	}
+	if signed != 0 {
+		sig = &deep.Signature{}
+		if sig.KeyID, err = r.String(); err != nil {
+			return err
+		}
+		value, err := r.Bytes()
+		if err != nil {
+			return err
+		}
+		sig.Value = append([]byte(nil), value...)
+	}
+
+	var p deep.Patch[T]
+	if err := p.UnmarshalBinary(data[len(data)-r.Len():]); err != nil {
+		return err
+	}
+	d.patch = p
+	d.Timestamp = ts
+	d.Signature = sig
 	return nil
 }
 
@@ -149,13 +279,38 @@ func (c *CRDT[T]) updateMetadataLocked(patch deep.Patch[T], ts hlc.HLC) {
 	}
 }
 
+// DeltaOption configures [CRDT.ApplyDelta].
+type DeltaOption func(*deltaConfig)
+
+type deltaConfig struct {
+	verifier deep.Verifier
+}
+
+// RequireSignature makes [CRDT.ApplyDelta] reject deltas that are unsigned,
+// whose signature does not verify with v, or that were not signed by the node
+// named in their timestamp.
+func RequireSignature(v deep.Verifier) DeltaOption {
+	return func(c *deltaConfig) { c.verifier = v }
+}
+
 // ApplyDelta applies a delta from a remote peer using Last-Write-Wins resolution.
 // Returns true if any operations were accepted.
-func (c *CRDT[T]) ApplyDelta(delta Delta[T]) bool {
+func (c *CRDT[T]) ApplyDelta(delta Delta[T], opts ...DeltaOption) bool {
 	if delta.patch.IsEmpty() {
 		return false
 	}
 
+	var cfg deltaConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.verifier != nil {
+		if err := delta.Verify(cfg.verifier); err != nil {
+			slog.Default().Warn("crdt: rejected delta", "node", delta.Timestamp.NodeID, "err", err)
+			return false
+		}
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
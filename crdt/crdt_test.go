@@ -1,10 +1,13 @@
 package crdt
 
 import (
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/brunoga/deep/v5"
 )
 
 type TestUser struct {
@@ -340,3 +343,68 @@ func TestCRDT_JSON(t *testing.T) {
 		t.Error("Merge with unmarshalled node failed")
 	}
 }
+
+func TestCRDT_SignedDelta(t *testing.T) {
+	keys := map[string][]byte{"node-a": []byte("key-a"), "node-b": []byte("key-b")}
+	v := deep.NewHMACVerifier(keys)
+
+	nodeA := NewCRDT(TestUser{ID: 1, Name: "Alice"}, "node-a")
+	nodeB := NewCRDT(TestUser{ID: 1, Name: "Alice"}, "node-b")
+
+	delta := nodeA.Edit(func(u *TestUser) { u.Name = "Signed" })
+	if nodeB.ApplyDelta(delta, RequireSignature(v)) {
+		t.Fatal("unsigned delta should be rejected")
+	}
+	if err := delta.Verify(v); !errors.Is(err, deep.ErrUnsigned) {
+		t.Errorf("Verify = %v, want ErrUnsigned", err)
+	}
+
+	// A node cannot sign deltas on behalf of another node.
+	spoofed, err := delta.Sign(deep.NewHMACSigner("node-b", keys["node-b"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := spoofed.Verify(v); !errors.Is(err, deep.ErrInvalidSignature) {
+		t.Errorf("Verify = %v, want ErrInvalidSignature", err)
+	}
+
+	signed, err := delta.Sign(deep.NewHMACSigner("node-a", keys["node-a"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The signature survives both wire formats.
+	data, err := signed.ToBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromBinary Delta[TestUser]
+	if err := fromBinary.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	data, err = signed.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Delta[TestUser]
+	if err := fromJSON.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := fromJSON.Verify(v); err != nil {
+		t.Errorf("JSON round trip: %v", err)
+	}
+
+	// Tampering with the timestamp breaks the signature.
+	forged := fromBinary
+	forged.Timestamp.WallTime++
+	if nodeB.ApplyDelta(forged, RequireSignature(v)) {
+		t.Error("forged delta should be rejected")
+	}
+
+	if !nodeB.ApplyDelta(fromBinary, RequireSignature(v)) {
+		t.Fatal("signed delta should be accepted")
+	}
+	if nodeB.View().Name != "Signed" {
+		t.Errorf("got %q", nodeB.View().Name)
+	}
+}
@@ -1,36 +1,191 @@
 package deep
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"strings"
+
+	icore "github.com/brunoga/deep/v5/internal/core"
 	"github.com/brunoga/deep/v5/internal/engine"
 )
 
+type diffConfig struct {
+	ignore      []string
+	detectMoves bool
+	maxDepth    int
+	maxOps      int
+}
+
+func newDiffConfig(opts ...DiffOption) diffConfig {
+	cfg := diffConfig{maxDepth: -1}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// DiffOption configures the behaviour of [Diff].
+type DiffOption func(*diffConfig)
+
+// IgnorePath excludes the value at path, and everything below it, from the
+// patch returned by [Diff]. The path uses JSON Pointer notation; struct fields
+// may be named by their Go name or their json tag. Excluded values are not
+// compared, so they do not count towards [MaxOps].
+func IgnorePath(path string) DiffOption {
+	return func(c *diffConfig) { c.ignore = append(c.ignore, path) }
+}
+
+// DetectMoves enables move detection in [Diff]. A value that disappears from
+// one path and reappears unchanged at another is reported as a single [OpMove]
+// instead of a remove/add pair. For types with generated or hand-written Diff
+// methods, elements of non-keyed slices are left as remove/add pairs.
+func DetectMoves() DiffOption {
+	return func(c *diffConfig) { c.detectMoves = true }
+}
+
+// MaxDepth limits how deep [Diff] and [DiffContext] descend into a value. A
+// value n path segments below the root is not compared element by element:
+// if it differs, it is replaced as a whole by a single operation. MaxDepth(0)
+// replaces the whole value. Values that contain a path excluded with
+// [IgnorePath] are still compared element by element, so that the excluded
+// value is left out.
+func MaxDepth(n int) DiffOption {
+	return func(c *diffConfig) { c.maxDepth = n }
+}
+
+// MaxOps limits the number of operations [Diff] and [DiffContext] may
+// produce. When the limit would be exceeded the diff stops and returns a
+// *[DiffLimitError] wrapping [ErrMaxOps]. Slice comparisons stop as soon as
+// their result cannot fit in the remaining budget.
+func MaxOps(n int) DiffOption {
+	return func(c *diffConfig) { c.maxOps = n }
+}
+
+// DiffLimitError is returned by [Diff] and [DiffContext] when the diff stops
+// before completing. Its Err field is [ErrMaxOps] or the context's error.
+type DiffLimitError = engine.DiffLimitError
+
+// ErrMaxOps is wrapped by the *[DiffLimitError] returned when a diff exceeds
+// [MaxOps].
+var ErrMaxOps = engine.ErrMaxOps
+
 // Diff compares two values and returns a Patch describing the changes from a to b.
 // Generated types (produced by deep-gen) dispatch to a reflection-free implementation.
 // For other types, Diff falls back to the reflection engine which may return an error
 // for unsupported kinds (chan, func, etc.).
-func Diff[T any](a, b T) (Patch[T], error) {
-	// 1. Try generated optimized path (pointer receiver, pointer arg)
+//
+// Options are honoured the same way on both paths: ignored paths never appear
+// in the result and, with [DetectMoves], relocated values become move operations.
+func Diff[T any](a, b T, opts ...DiffOption) (Patch[T], error) {
+	return DiffContext(context.Background(), a, b, opts...)
+}
+
+// DiffContext is like [Diff] but stops with a *[DiffLimitError] wrapping the
+// context's error once ctx is done. Together with [MaxDepth] and [MaxOps] it
+// bounds the work spent on large or untrusted values, on both the generated
+// and the reflection paths.
+func DiffContext[T any](ctx context.Context, a, b T, opts ...DiffOption) (Patch[T], error) {
+	cfg := newDiffConfig(opts...)
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+
+	var ignored []string
+	for _, p := range cfg.ignore {
+		ignored = append(ignored, icore.CanonicalPath(typ, p))
+	}
+
+	limits := engine.NewDiffLimits(ctx, cfg.maxDepth, cfg.maxOps)
+	if err := limits.Check(); err != nil {
+		return Patch[T]{}, err
+	}
+
+	var res Patch[T]
+	var native bool
+	if cfg.maxDepth == 0 && len(ignored) == 0 {
+		// Nothing below the root may be compared: replace the whole value.
+		if !Equal(a, b) {
+			res.Operations = []Operation{{Kind: OpReplace, Path: "/", Old: a, New: b}}
+		}
+	} else {
+		var err error
+		if res, native, err = diffDispatch(a, b, typ, ignored, cfg.detectMoves, limits); err != nil {
+			return Patch[T]{}, err
+		}
+	}
+
+	if len(ignored) > 0 {
+		ops := res.Operations[:0]
+		for _, op := range res.Operations {
+			if !isIgnoredPath(icore.CanonicalPath(typ, op.Path), ignored) {
+				ops = append(ops, op)
+			}
+		}
+		res.Operations = ops
+	}
+
+	// The reflection engine detects moves natively; generated and hand-written
+	// Diff methods do not, so pair up their remove/add operations here.
+	if cfg.detectMoves && !native {
+		res.Operations = pairMoves(typ, res.Operations)
+	}
+
+	// Generated Diff methods name fields by their json tags and the reflection
+	// engine by their Go names; report move sources in the canonical form
+	// either way.
+	for i, op := range res.Operations {
+		if from, ok := op.Old.(string); ok && (op.Kind == OpMove || op.Kind == OpCopy) {
+			res.Operations[i].Old = icore.CanonicalPath(typ, from)
+		}
+	}
+
+	return res, nil
+}
+
+// diffDispatch computes the raw patch from a to b within limits. native
+// reports whether the reflection engine was used, in which case ignored paths
+// and move detection have already been applied.
+func diffDispatch[T any](a, b T, typ reflect.Type, ignored []string, detectMoves bool, limits *engine.DiffLimits) (Patch[T], bool, error) {
+	// 1. Try generated optimized path (pointer receiver, pointer arg). It
+	// skips ignored values through the limits.
+	if differ, ok := any(&a).(interface {
+		DiffLimited(*T, *engine.DiffLimits) (Patch[T], error)
+	}); ok {
+		p, err := differ.DiffLimited(&b, limits.WithIgnored(typ, ignored))
+		return p, false, err
+	}
 	if differ, ok := any(&a).(interface {
 		Diff(*T) Patch[T]
 	}); ok {
-		return differ.Diff(&b), nil
+		p := differ.Diff(&b)
+		return p, false, limits.Count(len(p.Operations))
 	}
 
 	// 2. Try hand-written Diff with value arg (e.g. crdt.Text)
 	if differ, ok := any(a).(interface {
 		Diff(T) Patch[T]
 	}); ok {
-		return differ.Diff(b), nil
+		p := differ.Diff(b)
+		return p, false, limits.Count(len(p.Operations))
 	}
 
 	// 3. Fallback to reflection engine
-	p, err := engine.Diff(a, b)
+	var engineOpts []engine.DiffOption
+	if limits != nil {
+		engineOpts = append(engineOpts, engine.DiffWithLimits(limits))
+	}
+	for _, p := range ignored {
+		engineOpts = append(engineOpts, engine.IgnorePath(p))
+	}
+	if detectMoves {
+		engineOpts = append(engineOpts, engine.DiffDetectMoves(true))
+	}
+
+	p, err := engine.Diff(a, b, engineOpts...)
 	if err != nil {
-		return Patch[T]{}, fmt.Errorf("deep.Diff: %w", err)
+		return Patch[T]{}, true, fmt.Errorf("deep.Diff: %w", err)
 	}
 	if p == nil {
-		return Patch[T]{}, nil
+		return Patch[T]{}, true, nil
 	}
 
 	res := Patch[T]{}
@@ -44,5 +199,74 @@ func Diff[T any](a, b T) (Patch[T], error) {
 		return nil
 	})
 
-	return res, nil
+	return res, true, nil
+}
+
+// isIgnoredPath reports whether path equals, or lies below, any of ignored.
+func isIgnoredPath(path string, ignored []string) bool {
+	for _, ig := range ignored {
+		if isSubPath(path, ig) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubPath reports whether path equals parent or lies below it.
+func isSubPath(path, parent string) bool {
+	return parent == "/" || path == parent || strings.HasPrefix(path, parent+"/")
+}
+
+// pairMoves replaces each add whose value equals that of a not-yet-paired
+// remove with a single move from the removed path. Elements of non-keyed
+// slices are never paired: their indices in a diff refer to different
+// versions of the slice, so a move between them would land in the wrong
+// place. Other operations keep their relative order.
+func pairMoves(typ reflect.Type, ops []Operation) []Operation {
+	movable := func(op Operation) bool {
+		return shiftAnchor(typ, op.Path) == op.Path
+	}
+
+	// Removes are indexed by the hash of their value, in order.
+	removes := make(map[uint64][]int)
+	for i, op := range ops {
+		if op.Kind == OpRemove && movable(op) {
+			h := engine.Hash(op.Old)
+			removes[h] = append(removes[h], i)
+		}
+	}
+	if len(removes) == 0 {
+		return ops
+	}
+
+	removed := make(map[int]bool)
+	res := make([]Operation, len(ops))
+	copy(res, ops)
+	for i, add := range res {
+		if add.Kind != OpAdd || !movable(add) {
+			continue
+		}
+		h := engine.Hash(add.New)
+		for k, j := range removes[h] {
+			rem := ops[j]
+			if rem.Path == add.Path || !engine.Equal(rem.Old, add.New) {
+				continue
+			}
+			removes[h] = append(removes[h][:k:k], removes[h][k+1:]...)
+			removed[j] = true
+			res[i] = Operation{Kind: OpMove, Path: add.Path, Old: rem.Path}
+			break
+		}
+	}
+
+	if len(removed) == 0 {
+		return ops
+	}
+	out := make([]Operation, 0, len(res)-len(removed))
+	for i, op := range res {
+		if !removed[i] {
+			out = append(out, op)
+		}
+	}
+	return out
 }
@@ -1,10 +1,15 @@
 package deep_test
 
 import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
 	"github.com/brunoga/deep/v5"
 	"github.com/brunoga/deep/v5/internal/testmodels"
-
-	"testing"
 )
 
 func TestBuilder(t *testing.T) {
@@ -106,3 +111,280 @@ func TestBuilderAdvanced(t *testing.T) {
 		t.Error("Guard failed")
 	}
 }
+
+func TestDiffIgnorePath(t *testing.T) {
+	u1 := testmodels.User{ID: 1, Name: "Alice", Info: testmodels.Detail{Age: 30, Address: "A"}}
+	u2 := testmodels.User{ID: 2, Name: "Bob", Info: testmodels.Detail{Age: 31, Address: "B"}}
+
+	// Generated path: json tag and Go field names are both accepted.
+	p, err := deep.Diff(u1, u2, deep.IgnorePath("/full_name"), deep.IgnorePath("/Info"))
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(p.Operations) != 1 || p.Operations[0].Path != "/id" {
+		t.Errorf("expected only /id, got %v", p.Operations)
+	}
+
+	// Reflection path.
+	type Inner struct{ X, Y int }
+	type Doc struct {
+		Title string `json:"title"`
+		In    Inner  `json:"in"`
+	}
+	d1 := Doc{Title: "a", In: Inner{X: 1, Y: 1}}
+	d2 := Doc{Title: "b", In: Inner{X: 2, Y: 2}}
+	pd, err := deep.Diff(d1, d2, deep.IgnorePath("/title"), deep.IgnorePath("/in/X"))
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(pd.Operations) != 1 || pd.Operations[0].Path != "/In/Y" {
+		t.Errorf("expected only /In/Y, got %v", pd.Operations)
+	}
+
+	// Ignored values are not compared, so they neither count towards MaxOps
+	// nor end up in the values replaced beyond MaxDepth.
+	u1.Roles = make([]string, 1000)
+	u2.Roles = make([]string, 1000)
+	for i := range u2.Roles {
+		u2.Roles[i] = "x"
+	}
+	u1.Score = map[string]int{"a": 1}
+	u2.Score = map[string]int{"b": 2}
+	paths := func(ops []deep.Operation) string {
+		var res []string
+		for _, op := range ops {
+			res = append(res, op.Path)
+		}
+		sort.Strings(res)
+		return strings.Join(res, " ")
+	}
+
+	p, err = deep.Diff(u1, u2, deep.IgnorePath("/roles"), deep.MaxOps(6))
+	if err != nil {
+		t.Fatalf("Diff with MaxOps failed: %v", err)
+	}
+	if got, want := paths(p.Operations), "/full_name /id /info/Age /info/addr /score/a /score/b"; got != want {
+		t.Errorf("MaxOps: got %s, want %s", got, want)
+	}
+
+	p, err = deep.Diff(u1, u2, deep.IgnorePath("/Info/Address"), deep.IgnorePath("/score/a"), deep.MaxDepth(1))
+	if err != nil {
+		t.Fatalf("Diff with MaxDepth failed: %v", err)
+	}
+	if got, want := paths(p.Operations), "/full_name /id /info/Age /roles /score/b"; got != want {
+		t.Errorf("MaxDepth: got %s, want %s", got, want)
+	}
+
+	pd, err = deep.Diff(d1, d2, deep.IgnorePath("/in/X"), deep.MaxDepth(1))
+	if err != nil {
+		t.Fatalf("Diff with MaxDepth failed: %v", err)
+	}
+	if got, want := paths(pd.Operations), "/In/Y /Title"; got != want {
+		t.Errorf("reflection MaxDepth: got %s, want %s", got, want)
+	}
+}
+
+func TestDiffDetectMoves(t *testing.T) {
+	u1 := testmodels.User{Score: map[string]int{"old": 7, "keep": 1}}
+	u2 := testmodels.User{Score: map[string]int{"new": 7, "keep": 1}}
+
+	// Both paths report the move source in canonical (Go field name) form.
+	check := func(name string, ops []deep.Operation) {
+		t.Helper()
+		if len(ops) != 1 {
+			t.Fatalf("%s: expected 1 op, got %v", name, ops)
+		}
+		if op := ops[0]; op.Kind != deep.OpMove || op.Old != "/Score/old" {
+			t.Errorf("%s: expected move from /Score/old, got %+v", name, op)
+		}
+	}
+
+	p, err := deep.Diff(u1, u2, deep.DetectMoves())
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	check("generated", p.Operations)
+
+	u3 := u1
+	u3.Score = map[string]int{"old": 7, "keep": 1}
+	if err := deep.Apply(&u3, p); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !deep.Equal(u3, u2) {
+		t.Errorf("move apply mismatch: got %v, want %v", u3.Score, u2.Score)
+	}
+
+	type Scores struct {
+		Score map[string]int `json:"score"`
+	}
+	ps, err := deep.Diff(Scores{Score: u1.Score}, Scores{Score: u2.Score}, deep.DetectMoves())
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	check("reflection", ps.Operations)
+
+	// Without the option a remove/add pair is reported.
+	p, _ = deep.Diff(u1, u2)
+	if len(p.Operations) != 2 {
+		t.Errorf("expected remove/add pair without DetectMoves, got %v", p.Operations)
+	}
+
+	// Elements of non-keyed slices are not paired, since their indices refer
+	// to different versions of the slice.
+	pl, err := deep.Diff(movingList{}, movingList{}, deep.DetectMoves())
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(pl.Operations) != 2 || pl.Operations[0].Kind != deep.OpRemove || pl.Operations[1].Kind != deep.OpAdd {
+		t.Errorf("expected the slice remove/add pair to be kept, got %v", pl.Operations)
+	}
+}
+
+// movingList has a hand-written Diff that moves an element of a non-keyed
+// slice.
+type movingList struct {
+	Items []string
+}
+
+func (m *movingList) Diff(other *movingList) deep.Patch[movingList] {
+	return deep.Patch[movingList]{Operations: []deep.Operation{
+		{Kind: deep.OpRemove, Path: "/Items/0", Old: "a"},
+		{Kind: deep.OpAdd, Path: "/Items/2", New: "a"},
+	}}
+}
+
+type limitNode struct {
+	Name  string
+	Inner limitInner
+	Items []int
+}
+
+type limitInner struct {
+	A, B int
+	Deep map[string]int
+}
+
+func TestDiffContextLimits(t *testing.T) {
+	a := limitNode{Name: "a", Inner: limitInner{A: 1, B: 2, Deep: map[string]int{"x": 1}}, Items: []int{1, 2, 3}}
+	b := limitNode{Name: "b", Inner: limitInner{A: 3, B: 4, Deep: map[string]int{"x": 2}}, Items: []int{4, 5, 6, 7}}
+
+	// Reflection path: values below the maximum depth are replaced whole.
+	p, err := deep.DiffContext(context.Background(), a, b, deep.MaxDepth(1))
+	if err != nil {
+		t.Fatalf("DiffContext failed: %v", err)
+	}
+	paths := map[string]bool{}
+	for _, op := range p.Operations {
+		paths[op.Path] = true
+	}
+	if len(paths) != 3 || !paths["/Name"] || !paths["/Inner"] || !paths["/Items"] {
+		t.Errorf("unexpected operations with MaxDepth(1): %v", p)
+	}
+	c := deep.Clone(a)
+	if err := deep.Apply(&c, p); err != nil || !deep.Equal(c, b) {
+		t.Errorf("coarse patch did not produce target: %+v (%v)", c, err)
+	}
+
+	p, err = deep.Diff(a, b, deep.MaxDepth(0))
+	if err != nil || len(p.Operations) != 1 || p.Operations[0].Path != "/" {
+		t.Errorf("unexpected patch with MaxDepth(0): %v (%v)", p, err)
+	}
+
+	_, err = deep.Diff(a, b, deep.MaxOps(3))
+	var lerr *deep.DiffLimitError
+	if !errors.As(err, &lerr) || !errors.Is(err, deep.ErrMaxOps) {
+		t.Errorf("expected ErrMaxOps, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := deep.DiffContext(ctx, a, b); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestDiffContextLimitsGenerated(t *testing.T) {
+	u1 := testmodels.User{ID: 1, Name: "Alice", Info: testmodels.Detail{Age: 30, Address: "Home"}, Score: map[string]int{"a": 1}}
+	u2 := testmodels.User{ID: 2, Name: "Bob", Info: testmodels.Detail{Age: 31, Address: "Work"}, Score: map[string]int{"a": 2, "b": 3}}
+
+	p, err := deep.Diff(u1, u2, deep.MaxDepth(1))
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	for _, op := range p.Operations {
+		if strings.Count(op.Path, "/") > 1 {
+			t.Errorf("operation below maximum depth: %v", op)
+		}
+	}
+	u3 := deep.Clone(u1)
+	if err := deep.Apply(&u3, p); err != nil || !deep.Equal(u3, u2) {
+		t.Errorf("coarse patch did not produce target: %+v (%v)", u3, err)
+	}
+
+	full, _ := deep.Diff(u1, u2)
+	if _, err := deep.Diff(u1, u2, deep.MaxOps(len(full.Operations))); err != nil {
+		t.Errorf("unexpected error at exact limit: %v", err)
+	}
+	_, err = deep.Diff(u1, u2, deep.MaxOps(len(full.Operations)-1))
+	if !errors.Is(err, deep.ErrMaxOps) {
+		t.Errorf("expected ErrMaxOps, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := deep.DiffContext(ctx, u1, u2); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestDiffContextLimitsGeneratedLoop(t *testing.T) {
+	// The budget is checked for every element, so a large slice diff stops
+	// inside the slice instead of after comparing all of it.
+	u1 := testmodels.User{Roles: make([]string, 100000)}
+	u2 := testmodels.User{Roles: make([]string, len(u1.Roles))}
+	for i := range u2.Roles {
+		u2.Roles[i] = "x"
+	}
+	_, err := deep.Diff(u1, u2, deep.MaxOps(10))
+	var lerr *deep.DiffLimitError
+	if !errors.As(err, &lerr) || !errors.Is(err, deep.ErrMaxOps) || lerr.Path != "/roles" {
+		t.Errorf("expected ErrMaxOps at /roles, got %v", err)
+	}
+}
+
+func TestDiffSliceRoundTrip(t *testing.T) {
+	type Doc struct {
+		Items []int
+		Tags  []string
+	}
+	rng := rand.New(rand.NewSource(1))
+	randInts := func() []int {
+		s := make([]int, rng.Intn(7))
+		for i := range s {
+			s[i] = rng.Intn(4)
+		}
+		return s
+	}
+	randStrings := func() []string {
+		s := make([]string, rng.Intn(7))
+		for i := range s {
+			s[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return s
+	}
+
+	for i := 0; i < 2000; i++ {
+		a := Doc{Items: randInts(), Tags: randStrings()}
+		b := Doc{Items: randInts(), Tags: randStrings()}
+		for _, opts := range [][]deep.DiffOption{nil, {deep.DetectMoves()}} {
+			p, err := deep.Diff(a, b, opts...)
+			if err != nil {
+				t.Fatalf("Diff(%v, %v): %v", a, b, err)
+			}
+			got := deep.Clone(a)
+			if err := deep.Apply(&got, p); err != nil || !deep.Equal(got, b) {
+				t.Fatalf("Apply(%v, Diff) = %v, %v, want %v\npatch: %v", a, got, err, b, p.Operations)
+			}
+		}
+	}
+}
@@ -6,12 +6,16 @@ import (
 	"reflect"
 	"sort"
 
-	"github.com/brunoga/deep/v5/condition"
 	"github.com/brunoga/deep/v5/internal/engine"
 )
 
 type applyConfig struct {
-	logger *slog.Logger
+	logger     *slog.Logger
+	atomic     bool
+	condPolicy ConditionErrorPolicy
+	before     []Hook
+	after      []Hook
+	verifier   Verifier
 }
 
 func newApplyConfig(opts ...ApplyOption) applyConfig {
@@ -34,9 +38,14 @@ func WithLogger(l *slog.Logger) ApplyOption {
 // Apply applies a Patch to a target pointer.
 // v5 prioritizes the generated Patch method but falls back to reflection if needed.
 //
-// Note: when a Patch has been serialized to JSON and decoded, numeric values in
-// Operation.Old and Operation.New will be float64 regardless of the original type.
-// This affects strict-mode Old-value checks.
+// By default Apply keeps going after a failed operation and reports every
+// failure in an [ApplyError]; use [Atomic] to roll back on the first failure.
+// A failed [OpTest] always rolls the patch back, as RFC 6902 requires.
+//
+// Note: a Patch decoded from JSON as a whole restores the Go types of
+// Operation.Old and Operation.New (see [Patch.UnmarshalJSON]); operations
+// decoded individually hold float64 numbers, which affects strict-mode
+// Old-value checks.
 func Apply[T any](target *T, p Patch[T], opts ...ApplyOption) error {
 	v := reflect.ValueOf(target)
 	if v.Kind() != reflect.Pointer || v.IsNil() {
@@ -44,6 +53,14 @@ func Apply[T any](target *T, p Patch[T], opts ...ApplyOption) error {
 	}
 
 	cfg := newApplyConfig(opts...)
+	if cfg.atomic || cfg.condPolicy != SkipOnConditionError || len(cfg.before) > 0 || len(cfg.after) > 0 || hasTest(p) {
+		_, err := applySteps(target, p, cfg)
+		return err
+	}
+
+	if err := verifyPatch(p, cfg); err != nil {
+		return err
+	}
 
 	// Dispatch to generated Patch method if available.
 	if patcher, ok := any(target).(interface {
@@ -54,14 +71,8 @@ func Apply[T any](target *T, p Patch[T], opts ...ApplyOption) error {
 
 	// Reflection fallback.
 
-	if p.Guard != nil {
-		ok, err := condition.Evaluate(v.Elem(), p.Guard)
-		if err != nil {
-			return fmt.Errorf("global condition evaluation failed: %w", err)
-		}
-		if !ok {
-			return fmt.Errorf("global condition not met")
-		}
+	if err := checkGuard(target, p.Guard, cfg.logger); err != nil {
+		return err
 	}
 
 	var errors []error
@@ -133,6 +144,21 @@ func Equal[T any](a, b T) bool {
 	return engine.Equal(a, b)
 }
 
+// Hash returns a structural hash of v, suitable for ETags, deduplication and
+// cache keys. Values that [Equal] considers equal have the same hash: fields
+// tagged deep:"-" are ignored and map iteration order does not matter.
+// v5 prioritizes the generated Hash method but falls back to reflection if
+// needed. The hash is not cryptographic.
+func Hash[T any](v T) uint64 {
+	if hashable, ok := any(&v).(interface {
+		Hash() uint64
+	}); ok {
+		return hashable.Hash()
+	}
+
+	return engine.Hash(v)
+}
+
 // Clone returns a deep copy of v.
 func Clone[T any](v T) T {
 	if copyable, ok := any(&v).(interface {
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/condition"
 	"github.com/brunoga/deep/v5/crdt"
 	"github.com/brunoga/deep/v5/crdt/hlc"
 	"github.com/brunoga/deep/v5/internal/testmodels"
@@ -68,6 +69,73 @@ func TestApplyOperation(t *testing.T) {
 	}
 }
 
+func TestSliceDiffOrder(t *testing.T) {
+	type Doc struct{ S []string }
+
+	// OpAdd overwrites below the length of a slice and appends at it, so Diff
+	// reports removals from the highest index down, then appends.
+	from := Doc{S: []string{"a", "b", "c", "d"}}
+	to := Doc{S: []string{"b", "d", "e"}}
+	p, err := deep.Diff(from, to)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	var paths []string
+	for _, op := range p.Operations {
+		paths = append(paths, op.Kind.String()+" "+op.Path)
+	}
+	if got, want := strings.Join(paths, ", "), "remove /S/2, remove /S/0, add /S/2"; got != want {
+		t.Errorf("Diff: got %s, want %s", got, want)
+	}
+	if err := deep.Apply(&from, p); err != nil || !deep.Equal(from, to) {
+		t.Errorf("applying the diff: got %v, %v", from.S, err)
+	}
+
+	// An insertion before a remaining element is reported by position.
+	from = Doc{S: []string{"a", "c"}}
+	to = Doc{S: []string{"a", "b", "c"}}
+	p, err = deep.Diff(from, to)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	paths = nil
+	for _, op := range p.Operations {
+		paths = append(paths, op.Kind.String()+" "+op.Path)
+	}
+	if got, want := strings.Join(paths, ", "), "replace /S/1, add /S/2"; got != want {
+		t.Errorf("Diff: got %s, want %s", got, want)
+	}
+	if err := deep.Apply(&from, p); err != nil || !deep.Equal(from, to) {
+		t.Errorf("applying the diff: got %v, %v", from.S, err)
+	}
+}
+
+func TestGeneratedConditionFallback(t *testing.T) {
+	// Condition paths the generated evaluator has no case for, such as paths
+	// into nested structs and map entries, are evaluated by reflection.
+	u := testmodels.User{ID: 1, Info: testmodels.Detail{Age: 30}, Score: map[string]int{"a": 1}}
+	p := deep.Patch[testmodels.User]{
+		Guard: &condition.Condition{Path: "/info/Age", Op: condition.Eq, Value: 30},
+		Operations: []deep.Operation{
+			{Kind: deep.OpReplace, Path: "/full_name", New: "Bob",
+				If: &condition.Condition{Path: "/score/a", Op: condition.Eq, Value: 1}},
+			{Kind: deep.OpReplace, Path: "/id", New: 2,
+				Unless: &condition.Condition{Path: "/Info/addr", Op: condition.Eq, Value: ""}},
+		},
+	}
+	if err := deep.Apply(&u, p); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if u.Name != "Bob" || u.ID != 1 {
+		t.Errorf("unexpected result: %+v", u)
+	}
+
+	p.Guard.Value = 31
+	if err := deep.Apply(&u, p); err == nil {
+		t.Error("expected the nested guard to fail")
+	}
+}
+
 func TestApplyError(t *testing.T) {
 	err1 := fmt.Errorf("error 1")
 	err2 := fmt.Errorf("error 2")
@@ -49,4 +49,17 @@ func main() {
 
 	deep.Apply(&meta, p2)
 	fmt.Printf("Result: %+v\n", meta)
+
+	// 3. Transactional reload: with deep.Atomic(), a failing operation rolls
+	// back everything applied before it, so the config is never half-updated.
+	reload := deep.Patch[SystemMeta]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/proxy/port", New: 9443},
+		{Kind: deep.OpReplace, Path: "/cid", New: "PROD-US-2"},
+	}}
+
+	fmt.Println("\n--- TRANSACTIONAL RELOAD ---")
+	if err := deep.Apply(&meta, reload, deep.Atomic()); err != nil {
+		fmt.Printf("ROLLED BACK: %v\n", err)
+	}
+	fmt.Printf("Result: %+v\n", meta)
 }
@@ -8,6 +8,7 @@ import (
 	_deepengine "github.com/brunoga/deep/v5/internal/engine"
 	"log/slog"
 	"regexp"
+	"strings"
 )
 
 // Patch applies p to t using the generated fast path.
@@ -28,11 +29,14 @@ func (t *ProxyConfig) Patch(p deep.Patch[ProxyConfig], logger *slog.Logger) erro
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -62,6 +66,9 @@ func (t *ProxyConfig) applyOperation(op deep.Operation, logger *slog.Logger) (bo
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(ProxyConfig)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -75,6 +82,15 @@ func (t *ProxyConfig) applyOperation(op deep.Operation, logger *slog.Logger) (bo
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/host", "/Host":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Host != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Host)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Host)
 			return true, nil
@@ -89,6 +105,21 @@ func (t *ProxyConfig) applyOperation(op deep.Operation, logger *slog.Logger) (bo
 			return true, nil
 		}
 	case "/port", "/Port":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.Port != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Port)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.Port) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Port)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Port)
 			return true, nil
@@ -122,15 +153,37 @@ func (t *ProxyConfig) applyOperation(op deep.Operation, logger *slog.Logger) (bo
 
 // Diff compares t with other and returns a Patch.
 func (t *ProxyConfig) Diff(other *ProxyConfig) deep.Patch[ProxyConfig] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *ProxyConfig) DiffLimited(other *ProxyConfig, l *_deepengine.DiffLimits) (deep.Patch[ProxyConfig], error) {
 	p := deep.Patch[ProxyConfig]{}
-	if t.Host != other.Host {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/host", Old: t.Host, New: other.Host})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.Port != other.Port {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/port", Old: t.Port, New: other.Port})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("host") {
+		if t.Host != other.Host {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/host", Old: t.Host, New: other.Host})
+		}
+	}
+	if !l.Ignored("port") {
+		if t.Port != other.Port {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/port", Old: t.Port, New: other.Port})
+		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *ProxyConfig) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *ProxyConfig) evaluateCondition(c condition.Condition) (bool, error) {
@@ -160,6 +213,14 @@ func (t *ProxyConfig) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -173,6 +234,12 @@ func (t *ProxyConfig) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Host))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Host, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Host), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Host")
@@ -190,6 +257,12 @@ func (t *ProxyConfig) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Host >= _sv, nil
 		case "<=":
 			return t.Host <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Host, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Host, _sv), nil
+		case "contains":
+			return strings.Contains(t.Host, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -217,6 +290,9 @@ func (t *ProxyConfig) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Port))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Port, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -265,6 +341,9 @@ func (t *ProxyConfig) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -279,6 +358,14 @@ func (t *ProxyConfig) Equal(other *ProxyConfig) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *ProxyConfig) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.String(t.Host)
+	h.Int64(int64(t.Port))
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *ProxyConfig) Clone() *ProxyConfig {
 	res := &ProxyConfig{
@@ -306,11 +393,14 @@ func (t *SystemMeta) Patch(p deep.Patch[SystemMeta], logger *slog.Logger) error
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -340,6 +430,9 @@ func (t *SystemMeta) applyOperation(op deep.Operation, logger *slog.Logger) (boo
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(SystemMeta)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -353,8 +446,20 @@ func (t *SystemMeta) applyOperation(op deep.Operation, logger *slog.Logger) (boo
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/cid", "/ClusterID":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.ClusterID != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.ClusterID)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		return true, fmt.Errorf("field %s is read-only", op.Path)
 	case "/proxy", "/Settings":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Settings)
 			return true, nil
@@ -375,15 +480,37 @@ func (t *SystemMeta) applyOperation(op deep.Operation, logger *slog.Logger) (boo
 
 // Diff compares t with other and returns a Patch.
 func (t *SystemMeta) Diff(other *SystemMeta) deep.Patch[SystemMeta] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *SystemMeta) DiffLimited(other *SystemMeta, l *_deepengine.DiffLimits) (deep.Patch[SystemMeta], error) {
 	p := deep.Patch[SystemMeta]{}
-	if t.ClusterID != other.ClusterID {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/cid", Old: t.ClusterID, New: other.ClusterID})
+	if err := l.Check(); err != nil {
+		return p, err
+	}
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("cid") {
+		if t.ClusterID != other.ClusterID {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/cid", Old: t.ClusterID, New: other.ClusterID})
+		}
 	}
-	if t.Settings != other.Settings {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/proxy", Old: t.Settings, New: other.Settings})
+	if !l.Ignored("proxy") {
+		if t.Settings != other.Settings {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/proxy", Old: t.Settings, New: other.Settings})
+		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *SystemMeta) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *SystemMeta) evaluateCondition(c condition.Condition) (bool, error) {
@@ -413,6 +540,14 @@ func (t *SystemMeta) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -426,6 +561,12 @@ func (t *SystemMeta) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.ClusterID))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.ClusterID, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.ClusterID), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field ClusterID")
@@ -443,6 +584,12 @@ func (t *SystemMeta) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.ClusterID >= _sv, nil
 		case "<=":
 			return t.ClusterID <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.ClusterID, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.ClusterID, _sv), nil
+		case "contains":
+			return strings.Contains(t.ClusterID, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -461,6 +608,9 @@ func (t *SystemMeta) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -475,6 +625,14 @@ func (t *SystemMeta) Equal(other *SystemMeta) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *SystemMeta) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.String(t.ClusterID)
+	h.Uint64((&t.Settings).Hash())
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *SystemMeta) Clone() *SystemMeta {
 	res := &SystemMeta{
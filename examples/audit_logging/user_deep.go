@@ -29,11 +29,14 @@ func (t *User) Patch(p deep.Patch[User], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -63,6 +66,9 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(User)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -76,6 +82,15 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/name", "/Name":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Name != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Name)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Name)
 			return true, nil
@@ -90,6 +105,15 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 			return true, nil
 		}
 	case "/email", "/Email":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Email != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Email)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Email)
 			return true, nil
@@ -104,6 +128,9 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 			return true, nil
 		}
 	case "/tags", "/Tags":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Tags)
 			return true, nil
@@ -121,6 +148,9 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 		if strings.HasPrefix(op.Path, "/tags/") {
 			parts := strings.Split(op.Path[len("/tags/"):], "/")
 			key := parts[0]
+			if op.Kind == deep.OpTest {
+				return false, nil
+			}
 			if op.Kind == deep.OpRemove {
 				delete(t.Tags, key)
 				return true, nil
@@ -139,37 +169,85 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 
 // Diff compares t with other and returns a Patch.
 func (t *User) Diff(other *User) deep.Patch[User] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *User) DiffLimited(other *User, l *_deepengine.DiffLimits) (deep.Patch[User], error) {
 	p := deep.Patch[User]{}
-	if t.Name != other.Name {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/name", Old: t.Name, New: other.Name})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.Email != other.Email {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/email", Old: t.Email, New: other.Email})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("name") {
+		if t.Name != other.Name {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/name", Old: t.Name, New: other.Name})
+		}
 	}
-	if other.Tags != nil {
-		for k, v := range other.Tags {
-			if t.Tags == nil {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/tags/%v", k), New: v})
-				continue
-			}
-			if oldV, ok := t.Tags[k]; !ok || v != oldV {
-				kind := deep.OpReplace
-				if !ok {
-					kind = deep.OpAdd
-				}
-				p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/tags/%v", k), Old: oldV, New: v})
-			}
+	if !l.Ignored("email") {
+		if t.Email != other.Email {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/email", Old: t.Email, New: other.Email})
 		}
 	}
-	if t.Tags != nil {
-		for k, v := range t.Tags {
-			if other.Tags == nil || !contains(other.Tags, k) {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/tags/%v", k), Old: v})
+	if !l.Ignored("tags") {
+		if l.Descend("tags") {
+			skip := l.IgnoresBelow()
+			if other.Tags != nil {
+				for k, v := range other.Tags {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if t.Tags == nil {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/tags/%v", k), New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+						continue
+					}
+					if oldV, ok := t.Tags[k]; !ok || v != oldV {
+						kind := deep.OpReplace
+						if !ok {
+							kind = deep.OpAdd
+						}
+						p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/tags/%v", k), Old: oldV, New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
 			}
+			if t.Tags != nil {
+				for k, v := range t.Tags {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if other.Tags == nil || !contains(other.Tags, k) {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/tags/%v", k), Old: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
+			}
+			l.Ascend()
+		} else if !deep.Equal(t.Tags, other.Tags) {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/tags", Old: t.Tags, New: other.Tags})
 		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *User) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
@@ -199,6 +277,14 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -212,6 +298,12 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Name))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Name, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Name), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Name")
@@ -229,6 +321,12 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Name >= _sv, nil
 		case "<=":
 			return t.Name <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Name, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Name, _sv), nil
+		case "contains":
+			return strings.Contains(t.Name, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -256,6 +354,12 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Email))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Email, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Email), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Email")
@@ -273,6 +377,12 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Email >= _sv, nil
 		case "<=":
 			return t.Email <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Email, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Email, _sv), nil
+		case "contains":
+			return strings.Contains(t.Email, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -291,6 +401,9 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -317,6 +430,25 @@ func (t *User) Equal(other *User) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *User) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.String(t.Name)
+	h.String(t.Email)
+	h.Uint64(uint64(len(t.Tags)))
+	{
+		var sum uint64
+		for k, v := range t.Tags {
+			e := _deepengine.NewHasher()
+			e.String(k)
+			e.Bool(v)
+			sum += e.Sum()
+		}
+		h.Uint64(sum)
+	}
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *User) Clone() *User {
 	res := &User{
@@ -8,6 +8,7 @@ import (
 	_deepengine "github.com/brunoga/deep/v5/internal/engine"
 	"log/slog"
 	"regexp"
+	"strings"
 )
 
 // Patch applies p to t using the generated fast path.
@@ -28,11 +29,14 @@ func (t *Stock) Patch(p deep.Patch[Stock], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -62,6 +66,9 @@ func (t *Stock) applyOperation(op deep.Operation, logger *slog.Logger) (bool, er
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(Stock)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -75,6 +82,15 @@ func (t *Stock) applyOperation(op deep.Operation, logger *slog.Logger) (bool, er
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/sku", "/SKU":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.SKU != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.SKU)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.SKU)
 			return true, nil
@@ -89,6 +105,21 @@ func (t *Stock) applyOperation(op deep.Operation, logger *slog.Logger) (bool, er
 			return true, nil
 		}
 	case "/q", "/Quantity":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.Quantity != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Quantity)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.Quantity) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Quantity)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Quantity)
 			return true, nil
@@ -122,15 +153,37 @@ func (t *Stock) applyOperation(op deep.Operation, logger *slog.Logger) (bool, er
 
 // Diff compares t with other and returns a Patch.
 func (t *Stock) Diff(other *Stock) deep.Patch[Stock] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *Stock) DiffLimited(other *Stock, l *_deepengine.DiffLimits) (deep.Patch[Stock], error) {
 	p := deep.Patch[Stock]{}
-	if t.SKU != other.SKU {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/sku", Old: t.SKU, New: other.SKU})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.Quantity != other.Quantity {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/q", Old: t.Quantity, New: other.Quantity})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("sku") {
+		if t.SKU != other.SKU {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/sku", Old: t.SKU, New: other.SKU})
+		}
+	}
+	if !l.Ignored("q") {
+		if t.Quantity != other.Quantity {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/q", Old: t.Quantity, New: other.Quantity})
+		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *Stock) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *Stock) evaluateCondition(c condition.Condition) (bool, error) {
@@ -160,6 +213,14 @@ func (t *Stock) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -173,6 +234,12 @@ func (t *Stock) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.SKU))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.SKU, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.SKU), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field SKU")
@@ -190,6 +257,12 @@ func (t *Stock) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.SKU >= _sv, nil
 		case "<=":
 			return t.SKU <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.SKU, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.SKU, _sv), nil
+		case "contains":
+			return strings.Contains(t.SKU, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -217,6 +290,9 @@ func (t *Stock) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Quantity))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Quantity, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -265,6 +341,9 @@ func (t *Stock) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -279,6 +358,14 @@ func (t *Stock) Equal(other *Stock) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *Stock) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.String(t.SKU)
+	h.Int64(int64(t.Quantity))
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *Stock) Clone() *Stock {
 	res := &Stock{
@@ -29,11 +29,14 @@ func (t *Config) Patch(p deep.Patch[Config], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -63,6 +66,9 @@ func (t *Config) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(Config)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -76,6 +82,21 @@ func (t *Config) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/version", "/Version":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.Version != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Version)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.Version) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Version)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Version)
 			return true, nil
@@ -103,6 +124,15 @@ func (t *Config) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 			return true, nil
 		}
 	case "/env", "/Environment":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Environment != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Environment)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Environment)
 			return true, nil
@@ -117,6 +147,21 @@ func (t *Config) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 			return true, nil
 		}
 	case "/timeout", "/Timeout":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.Timeout != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Timeout)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.Timeout) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Timeout)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Timeout)
 			return true, nil
@@ -144,6 +189,9 @@ func (t *Config) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 			return true, nil
 		}
 	case "/features", "/Features":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Features)
 			return true, nil
@@ -161,6 +209,9 @@ func (t *Config) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 		if strings.HasPrefix(op.Path, "/features/") {
 			parts := strings.Split(op.Path[len("/features/"):], "/")
 			key := parts[0]
+			if op.Kind == deep.OpTest {
+				return false, nil
+			}
 			if op.Kind == deep.OpRemove {
 				delete(t.Features, key)
 				return true, nil
@@ -179,40 +230,90 @@ func (t *Config) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 
 // Diff compares t with other and returns a Patch.
 func (t *Config) Diff(other *Config) deep.Patch[Config] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *Config) DiffLimited(other *Config, l *_deepengine.DiffLimits) (deep.Patch[Config], error) {
 	p := deep.Patch[Config]{}
-	if t.Version != other.Version {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/version", Old: t.Version, New: other.Version})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.Environment != other.Environment {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/env", Old: t.Environment, New: other.Environment})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("version") {
+		if t.Version != other.Version {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/version", Old: t.Version, New: other.Version})
+		}
 	}
-	if t.Timeout != other.Timeout {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/timeout", Old: t.Timeout, New: other.Timeout})
+	if !l.Ignored("env") {
+		if t.Environment != other.Environment {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/env", Old: t.Environment, New: other.Environment})
+		}
 	}
-	if other.Features != nil {
-		for k, v := range other.Features {
-			if t.Features == nil {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/features/%v", k), New: v})
-				continue
-			}
-			if oldV, ok := t.Features[k]; !ok || v != oldV {
-				kind := deep.OpReplace
-				if !ok {
-					kind = deep.OpAdd
-				}
-				p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/features/%v", k), Old: oldV, New: v})
-			}
+	if !l.Ignored("timeout") {
+		if t.Timeout != other.Timeout {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/timeout", Old: t.Timeout, New: other.Timeout})
 		}
 	}
-	if t.Features != nil {
-		for k, v := range t.Features {
-			if other.Features == nil || !contains(other.Features, k) {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/features/%v", k), Old: v})
+	if !l.Ignored("features") {
+		if l.Descend("features") {
+			skip := l.IgnoresBelow()
+			if other.Features != nil {
+				for k, v := range other.Features {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if t.Features == nil {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/features/%v", k), New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+						continue
+					}
+					if oldV, ok := t.Features[k]; !ok || v != oldV {
+						kind := deep.OpReplace
+						if !ok {
+							kind = deep.OpAdd
+						}
+						p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/features/%v", k), Old: oldV, New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
 			}
+			if t.Features != nil {
+				for k, v := range t.Features {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if other.Features == nil || !contains(other.Features, k) {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/features/%v", k), Old: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
+			}
+			l.Ascend()
+		} else if !deep.Equal(t.Features, other.Features) {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/features", Old: t.Features, New: other.Features})
 		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *Config) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *Config) evaluateCondition(c condition.Condition) (bool, error) {
@@ -242,6 +343,14 @@ func (t *Config) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -255,6 +364,9 @@ func (t *Config) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Version))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Version, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -312,6 +424,12 @@ func (t *Config) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Environment))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Environment, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Environment), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Environment")
@@ -329,6 +447,12 @@ func (t *Config) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Environment >= _sv, nil
 		case "<=":
 			return t.Environment <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Environment, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Environment, _sv), nil
+		case "contains":
+			return strings.Contains(t.Environment, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -356,6 +480,9 @@ func (t *Config) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Timeout))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Timeout, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -404,6 +531,9 @@ func (t *Config) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -433,6 +563,26 @@ func (t *Config) Equal(other *Config) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *Config) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.Int64(int64(t.Version))
+	h.String(t.Environment)
+	h.Int64(int64(t.Timeout))
+	h.Uint64(uint64(len(t.Features)))
+	{
+		var sum uint64
+		for k, v := range t.Features {
+			e := _deepengine.NewHasher()
+			e.String(k)
+			e.Bool(v)
+			sum += e.Sum()
+		}
+		h.Uint64(sum)
+	}
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *Config) Clone() *Config {
 	res := &Config{
@@ -8,6 +8,7 @@ import (
 	_deepengine "github.com/brunoga/deep/v5/internal/engine"
 	"log/slog"
 	"regexp"
+	"strings"
 )
 
 // Patch applies p to t using the generated fast path.
@@ -28,11 +29,14 @@ func (t *Resource) Patch(p deep.Patch[Resource], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -62,6 +66,9 @@ func (t *Resource) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(Resource)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -75,6 +82,15 @@ func (t *Resource) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/id", "/ID":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.ID != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.ID)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.ID)
 			return true, nil
@@ -89,6 +105,15 @@ func (t *Resource) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 			return true, nil
 		}
 	case "/data", "/Data":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Data != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Data)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Data)
 			return true, nil
@@ -103,6 +128,21 @@ func (t *Resource) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 			return true, nil
 		}
 	case "/value", "/Value":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.Value != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Value)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.Value) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Value)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Value)
 			return true, nil
@@ -136,18 +176,42 @@ func (t *Resource) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 
 // Diff compares t with other and returns a Patch.
 func (t *Resource) Diff(other *Resource) deep.Patch[Resource] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *Resource) DiffLimited(other *Resource, l *_deepengine.DiffLimits) (deep.Patch[Resource], error) {
 	p := deep.Patch[Resource]{}
-	if t.ID != other.ID {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/id", Old: t.ID, New: other.ID})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.Data != other.Data {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/data", Old: t.Data, New: other.Data})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("id") {
+		if t.ID != other.ID {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/id", Old: t.ID, New: other.ID})
+		}
 	}
-	if t.Value != other.Value {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/value", Old: t.Value, New: other.Value})
+	if !l.Ignored("data") {
+		if t.Data != other.Data {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/data", Old: t.Data, New: other.Data})
+		}
+	}
+	if !l.Ignored("value") {
+		if t.Value != other.Value {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/value", Old: t.Value, New: other.Value})
+		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *Resource) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *Resource) evaluateCondition(c condition.Condition) (bool, error) {
@@ -177,6 +241,14 @@ func (t *Resource) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -190,6 +262,12 @@ func (t *Resource) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.ID))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.ID, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.ID), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field ID")
@@ -207,6 +285,12 @@ func (t *Resource) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.ID >= _sv, nil
 		case "<=":
 			return t.ID <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.ID, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.ID, _sv), nil
+		case "contains":
+			return strings.Contains(t.ID, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -234,6 +318,12 @@ func (t *Resource) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Data))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Data, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Data), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Data")
@@ -251,6 +341,12 @@ func (t *Resource) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Data >= _sv, nil
 		case "<=":
 			return t.Data <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Data, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Data, _sv), nil
+		case "contains":
+			return strings.Contains(t.Data, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -278,6 +374,9 @@ func (t *Resource) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Value))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Value, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -326,6 +425,9 @@ func (t *Resource) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -343,6 +445,15 @@ func (t *Resource) Equal(other *Resource) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *Resource) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.String(t.ID)
+	h.String(t.Data)
+	h.Int64(int64(t.Value))
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *Resource) Clone() *Resource {
 	res := &Resource{
@@ -8,6 +8,7 @@ import (
 	_deepengine "github.com/brunoga/deep/v5/internal/engine"
 	"log/slog"
 	"regexp"
+	"strings"
 )
 
 // Patch applies p to t using the generated fast path.
@@ -28,11 +29,14 @@ func (t *UIState) Patch(p deep.Patch[UIState], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -62,6 +66,9 @@ func (t *UIState) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(UIState)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -75,6 +82,15 @@ func (t *UIState) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/theme", "/Theme":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Theme != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Theme)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Theme)
 			return true, nil
@@ -89,6 +105,15 @@ func (t *UIState) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 			return true, nil
 		}
 	case "/sidebar_open", "/Open":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(bool); ok {
+				if t.Open != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Open)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Open)
 			return true, nil
@@ -109,15 +134,37 @@ func (t *UIState) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 
 // Diff compares t with other and returns a Patch.
 func (t *UIState) Diff(other *UIState) deep.Patch[UIState] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *UIState) DiffLimited(other *UIState, l *_deepengine.DiffLimits) (deep.Patch[UIState], error) {
 	p := deep.Patch[UIState]{}
-	if t.Theme != other.Theme {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/theme", Old: t.Theme, New: other.Theme})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.Open != other.Open {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/sidebar_open", Old: t.Open, New: other.Open})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("theme") {
+		if t.Theme != other.Theme {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/theme", Old: t.Theme, New: other.Theme})
+		}
+	}
+	if !l.Ignored("sidebar_open") {
+		if t.Open != other.Open {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/sidebar_open", Old: t.Open, New: other.Open})
+		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *UIState) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *UIState) evaluateCondition(c condition.Condition) (bool, error) {
@@ -147,6 +194,14 @@ func (t *UIState) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -160,6 +215,12 @@ func (t *UIState) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Theme))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Theme, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Theme), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Theme")
@@ -177,6 +238,12 @@ func (t *UIState) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Theme >= _sv, nil
 		case "<=":
 			return t.Theme <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Theme, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Theme, _sv), nil
+		case "contains":
+			return strings.Contains(t.Theme, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -215,6 +282,9 @@ func (t *UIState) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Open != _bv, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -229,6 +299,14 @@ func (t *UIState) Equal(other *UIState) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *UIState) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.String(t.Theme)
+	h.Bool(t.Open)
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *UIState) Clone() *UIState {
 	res := &UIState{
@@ -8,6 +8,7 @@ import (
 	_deepengine "github.com/brunoga/deep/v5/internal/engine"
 	"log/slog"
 	"regexp"
+	"strings"
 )
 
 // Patch applies p to t using the generated fast path.
@@ -28,11 +29,14 @@ func (t *Employee) Patch(p deep.Patch[Employee], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -62,6 +66,9 @@ func (t *Employee) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(Employee)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -75,6 +82,21 @@ func (t *Employee) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/id", "/ID":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.ID != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.ID)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.ID) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.ID)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.ID)
 			return true, nil
@@ -102,6 +124,15 @@ func (t *Employee) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 			return true, nil
 		}
 	case "/name", "/Name":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Name != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Name)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Name)
 			return true, nil
@@ -116,6 +147,15 @@ func (t *Employee) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 			return true, nil
 		}
 	case "/role", "/Role":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Role != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Role)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Role)
 			return true, nil
@@ -130,6 +170,21 @@ func (t *Employee) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 			return true, nil
 		}
 	case "/rating", "/Rating":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.Rating != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Rating)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.Rating) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Rating)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Rating)
 			return true, nil
@@ -163,21 +218,47 @@ func (t *Employee) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 
 // Diff compares t with other and returns a Patch.
 func (t *Employee) Diff(other *Employee) deep.Patch[Employee] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *Employee) DiffLimited(other *Employee, l *_deepengine.DiffLimits) (deep.Patch[Employee], error) {
 	p := deep.Patch[Employee]{}
-	if t.ID != other.ID {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/id", Old: t.ID, New: other.ID})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.Name != other.Name {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/name", Old: t.Name, New: other.Name})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("id") {
+		if t.ID != other.ID {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/id", Old: t.ID, New: other.ID})
+		}
 	}
-	if t.Role != other.Role {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/role", Old: t.Role, New: other.Role})
+	if !l.Ignored("name") {
+		if t.Name != other.Name {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/name", Old: t.Name, New: other.Name})
+		}
 	}
-	if t.Rating != other.Rating {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/rating", Old: t.Rating, New: other.Rating})
+	if !l.Ignored("role") {
+		if t.Role != other.Role {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/role", Old: t.Role, New: other.Role})
+		}
+	}
+	if !l.Ignored("rating") {
+		if t.Rating != other.Rating {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/rating", Old: t.Rating, New: other.Rating})
+		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *Employee) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *Employee) evaluateCondition(c condition.Condition) (bool, error) {
@@ -207,6 +288,14 @@ func (t *Employee) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -220,6 +309,9 @@ func (t *Employee) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.ID))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.ID, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -277,6 +369,12 @@ func (t *Employee) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Name))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Name, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Name), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Name")
@@ -294,6 +392,12 @@ func (t *Employee) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Name >= _sv, nil
 		case "<=":
 			return t.Name <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Name, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Name, _sv), nil
+		case "contains":
+			return strings.Contains(t.Name, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -321,6 +425,12 @@ func (t *Employee) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Role))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Role, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Role), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Role")
@@ -338,6 +448,12 @@ func (t *Employee) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Role >= _sv, nil
 		case "<=":
 			return t.Role <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Role, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Role, _sv), nil
+		case "contains":
+			return strings.Contains(t.Role, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -365,6 +481,9 @@ func (t *Employee) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Rating))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Rating, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -413,6 +532,9 @@ func (t *Employee) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -433,6 +555,16 @@ func (t *Employee) Equal(other *Employee) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *Employee) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.Int64(int64(t.ID))
+	h.String(t.Name)
+	h.String(t.Role)
+	h.Int64(int64(t.Rating))
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *Employee) Clone() *Employee {
 	res := &Employee{
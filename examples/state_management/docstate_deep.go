@@ -29,11 +29,14 @@ func (t *DocState) Patch(p deep.Patch[DocState], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -63,6 +66,9 @@ func (t *DocState) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(DocState)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -76,6 +82,15 @@ func (t *DocState) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/title", "/Title":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Title != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Title)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Title)
 			return true, nil
@@ -90,6 +105,15 @@ func (t *DocState) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 			return true, nil
 		}
 	case "/content", "/Content":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Content != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Content)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Content)
 			return true, nil
@@ -104,6 +128,9 @@ func (t *DocState) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 			return true, nil
 		}
 	case "/metadata", "/Metadata":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Metadata)
 			return true, nil
@@ -121,6 +148,9 @@ func (t *DocState) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 		if strings.HasPrefix(op.Path, "/metadata/") {
 			parts := strings.Split(op.Path[len("/metadata/"):], "/")
 			key := parts[0]
+			if op.Kind == deep.OpTest {
+				return false, nil
+			}
 			if op.Kind == deep.OpRemove {
 				delete(t.Metadata, key)
 				return true, nil
@@ -139,37 +169,85 @@ func (t *DocState) applyOperation(op deep.Operation, logger *slog.Logger) (bool,
 
 // Diff compares t with other and returns a Patch.
 func (t *DocState) Diff(other *DocState) deep.Patch[DocState] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *DocState) DiffLimited(other *DocState, l *_deepengine.DiffLimits) (deep.Patch[DocState], error) {
 	p := deep.Patch[DocState]{}
-	if t.Title != other.Title {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/title", Old: t.Title, New: other.Title})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.Content != other.Content {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/content", Old: t.Content, New: other.Content})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("title") {
+		if t.Title != other.Title {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/title", Old: t.Title, New: other.Title})
+		}
 	}
-	if other.Metadata != nil {
-		for k, v := range other.Metadata {
-			if t.Metadata == nil {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/metadata/%v", k), New: v})
-				continue
-			}
-			if oldV, ok := t.Metadata[k]; !ok || v != oldV {
-				kind := deep.OpReplace
-				if !ok {
-					kind = deep.OpAdd
-				}
-				p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/metadata/%v", k), Old: oldV, New: v})
-			}
+	if !l.Ignored("content") {
+		if t.Content != other.Content {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/content", Old: t.Content, New: other.Content})
 		}
 	}
-	if t.Metadata != nil {
-		for k, v := range t.Metadata {
-			if other.Metadata == nil || !contains(other.Metadata, k) {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/metadata/%v", k), Old: v})
+	if !l.Ignored("metadata") {
+		if l.Descend("metadata") {
+			skip := l.IgnoresBelow()
+			if other.Metadata != nil {
+				for k, v := range other.Metadata {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if t.Metadata == nil {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/metadata/%v", k), New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+						continue
+					}
+					if oldV, ok := t.Metadata[k]; !ok || v != oldV {
+						kind := deep.OpReplace
+						if !ok {
+							kind = deep.OpAdd
+						}
+						p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/metadata/%v", k), Old: oldV, New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
+			}
+			if t.Metadata != nil {
+				for k, v := range t.Metadata {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if other.Metadata == nil || !contains(other.Metadata, k) {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/metadata/%v", k), Old: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
 			}
+			l.Ascend()
+		} else if !deep.Equal(t.Metadata, other.Metadata) {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/metadata", Old: t.Metadata, New: other.Metadata})
 		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *DocState) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *DocState) evaluateCondition(c condition.Condition) (bool, error) {
@@ -199,6 +277,14 @@ func (t *DocState) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -212,6 +298,12 @@ func (t *DocState) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Title))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Title, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Title), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Title")
@@ -229,6 +321,12 @@ func (t *DocState) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Title >= _sv, nil
 		case "<=":
 			return t.Title <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Title, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Title, _sv), nil
+		case "contains":
+			return strings.Contains(t.Title, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -256,6 +354,12 @@ func (t *DocState) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Content))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Content, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Content), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Content")
@@ -273,6 +377,12 @@ func (t *DocState) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Content >= _sv, nil
 		case "<=":
 			return t.Content <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Content, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Content, _sv), nil
+		case "contains":
+			return strings.Contains(t.Content, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -291,6 +401,9 @@ func (t *DocState) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -317,6 +430,25 @@ func (t *DocState) Equal(other *DocState) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *DocState) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.String(t.Title)
+	h.String(t.Content)
+	h.Uint64(uint64(len(t.Metadata)))
+	{
+		var sum uint64
+		for k, v := range t.Metadata {
+			e := _deepengine.NewHasher()
+			e.String(k)
+			e.String(v)
+			sum += e.Sum()
+		}
+		h.Uint64(sum)
+	}
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *DocState) Clone() *DocState {
 	res := &DocState{
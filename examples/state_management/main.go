@@ -13,48 +13,63 @@ type DocState struct {
 	Metadata map[string]string `json:"metadata"`
 }
 
+var (
+	titlePath    = deep.Field(func(d *DocState) *string { return &d.Title })
+	metadataPath = deep.Field(func(d *DocState) *map[string]string { return &d.Metadata })
+)
+
 func main() {
-	current := DocState{
+	store := deep.NewStore(DocState{
 		Title:    "Draft 1",
 		Content:  "Hello World",
 		Metadata: map[string]string{"author": "Alice"},
-	}
+	})
+
+	// Listeners are registered per path and only run when their part of the
+	// state changes.
+	deep.Subscribe(store, titlePath, func(old, new string) {
+		fmt.Printf("  [title bar] %q -> %q\n", old, new)
+	})
+	deep.Subscribe(store, deep.MapKey(metadataPath, "tags"), func(old, new string) {
+		fmt.Printf("  [tag list]  %q -> %q\n", old, new)
+	})
 
 	// Each edit records a reverse patch for undo.
 	var undoStack []deep.Patch[DocState]
 
 	edit := func(fn func(*DocState)) {
-		next := deep.Clone(current)
-		fn(&next)
-		patch, err := deep.Diff(current, next)
+		patch, err := store.Edit(fn)
 		if err != nil {
 			log.Fatal(err)
 		}
 		undoStack = append(undoStack, patch.Reverse())
-		current = next
+	}
+	undo := func() {
+		if err := store.Apply(undoStack[len(undoStack)-1]); err != nil {
+			log.Fatal(err)
+		}
+		undoStack = undoStack[:len(undoStack)-1]
 	}
 
+	fmt.Println("--- EDIT 1 (title and content) ---")
 	edit(func(d *DocState) {
 		d.Title = "Final Version"
 		d.Content = "Goodbye World"
 	})
 
+	fmt.Println("--- EDIT 2 (tags) ---")
 	edit(func(d *DocState) {
 		d.Metadata["tags"] = "go,library"
 	})
 
-	fmt.Println("--- CURRENT STATE ---")
-	fmt.Printf("%+v\n", current)
+	fmt.Println("\n--- CURRENT STATE ---")
+	fmt.Printf("%+v\n", store.View())
 
-	// Undo edit 2.
-	deep.Apply(&current, undoStack[len(undoStack)-1])
-	undoStack = undoStack[:len(undoStack)-1]
-	fmt.Println("\n--- AFTER UNDO (edit 2) ---")
-	fmt.Printf("%+v\n", current)
+	fmt.Println("\n--- UNDO (edit 2) ---")
+	undo()
+	fmt.Printf("%+v\n", store.View())
 
-	// Undo edit 1.
-	deep.Apply(&current, undoStack[len(undoStack)-1])
-	undoStack = undoStack[:len(undoStack)-1]
-	fmt.Println("\n--- AFTER UNDO (edit 1) ---")
-	fmt.Printf("%+v\n", current)
+	fmt.Println("\n--- UNDO (edit 1) ---")
+	undo()
+	fmt.Printf("%+v\n", store.View())
 }
@@ -27,11 +27,14 @@ func (t *Fleet) Patch(p deep.Patch[Fleet], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -61,6 +64,9 @@ func (t *Fleet) applyOperation(op deep.Operation, logger *slog.Logger) (bool, er
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(Fleet)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -74,6 +80,9 @@ func (t *Fleet) applyOperation(op deep.Operation, logger *slog.Logger) (bool, er
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/devices", "/Devices":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Devices)
 			return true, nil
@@ -94,31 +103,75 @@ func (t *Fleet) applyOperation(op deep.Operation, logger *slog.Logger) (bool, er
 
 // Diff compares t with other and returns a Patch.
 func (t *Fleet) Diff(other *Fleet) deep.Patch[Fleet] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *Fleet) DiffLimited(other *Fleet, l *_deepengine.DiffLimits) (deep.Patch[Fleet], error) {
 	p := deep.Patch[Fleet]{}
-	if other.Devices != nil {
-		for k, v := range other.Devices {
-			if t.Devices == nil {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/devices/%v", k), New: v})
-				continue
-			}
-			if oldV, ok := t.Devices[k]; !ok || v != oldV {
-				kind := deep.OpReplace
-				if !ok {
-					kind = deep.OpAdd
+	if err := l.Check(); err != nil {
+		return p, err
+	}
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("devices") {
+		if l.Descend("devices") {
+			skip := l.IgnoresBelow()
+			if other.Devices != nil {
+				for k, v := range other.Devices {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if t.Devices == nil {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/devices/%v", k), New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+						continue
+					}
+					if oldV, ok := t.Devices[k]; !ok || v != oldV {
+						kind := deep.OpReplace
+						if !ok {
+							kind = deep.OpAdd
+						}
+						p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/devices/%v", k), Old: oldV, New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
 				}
-				p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/devices/%v", k), Old: oldV, New: v})
 			}
-		}
-	}
-	if t.Devices != nil {
-		for k, v := range t.Devices {
-			if other.Devices == nil || !contains(other.Devices, k) {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/devices/%v", k), Old: v})
+			if t.Devices != nil {
+				for k, v := range t.Devices {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if other.Devices == nil || !contains(other.Devices, k) {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/devices/%v", k), Old: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
 			}
+			l.Ascend()
+		} else if !deep.Equal(t.Devices, other.Devices) {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/devices", Old: t.Devices, New: other.Devices})
 		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *Fleet) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *Fleet) evaluateCondition(c condition.Condition) (bool, error) {
@@ -148,10 +201,21 @@ func (t *Fleet) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -172,6 +236,23 @@ func (t *Fleet) Equal(other *Fleet) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *Fleet) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.Uint64(uint64(len(t.Devices)))
+	{
+		var sum uint64
+		for k, v := range t.Devices {
+			e := _deepengine.NewHasher()
+			e.Value(k)
+			e.String(v)
+			sum += e.Sum()
+		}
+		h.Uint64(sum)
+	}
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *Fleet) Clone() *Fleet {
 	res := &Fleet{}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/brunoga/deep/v5"
 )
@@ -12,13 +13,6 @@ type SystemConfig struct {
 	Endpoints  map[string]string `json:"endpoints"`
 }
 
-type Resolver struct{}
-
-func (r *Resolver) Resolve(path string, local, remote any) any {
-	fmt.Printf("- conflict at %s: %v vs %v (picking remote)\n", path, local, remote)
-	return remote
-}
-
 func main() {
 	base := SystemConfig{
 		AppName:    "CoreAPI",
@@ -26,26 +20,38 @@ func main() {
 		Endpoints:  map[string]string{"auth": "https://auth.local"},
 	}
 
-	// User A changes Endpoints/auth.
-	patchA := deep.Patch[SystemConfig]{}
-	patchA.Operations = append(patchA.Operations, deep.Operation{
-		Kind: deep.OpReplace, Path: "/endpoints/auth", New: "https://auth.internal",
-	})
+	// User A changes Endpoints/auth and raises MaxThreads.
+	local := deep.Clone(base)
+	local.Endpoints["auth"] = "https://auth.internal"
+	local.MaxThreads = 20
 
-	// User B also changes Endpoints/auth — conflict.
-	patchB := deep.Patch[SystemConfig]{}
-	patchB.Operations = append(patchB.Operations, deep.Operation{
-		Kind: deep.OpReplace, Path: "/endpoints/auth", New: "https://auth.remote",
-	})
+	// User B also changes Endpoints/auth — conflict — and adds a new endpoint.
+	remote := deep.Clone(base)
+	remote.Endpoints["auth"] = "https://auth.remote"
+	remote.Endpoints["billing"] = "https://billing.remote"
 
 	fmt.Println("--- BASE STATE ---")
 	fmt.Printf("%+v\n", base)
 
-	fmt.Println("\n--- MERGING PATCHES (Custom Resolution) ---")
-	merged := deep.Merge(patchA, patchB, &Resolver{})
+	fmt.Println("\n--- THREE-WAY MERGE ---")
+	merged, conflicts, err := deep.Merge3(base, local, remote)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(merged)
+
+	// Resolve each conflict explicitly; here the remote side wins.
+	for _, c := range conflicts {
+		fmt.Printf("- conflict at %s: base=%v local=%v remote=%v (picking remote)\n", c.Path, c.Base, c.Local, c.Remote)
+		merged.Operations = append(merged.Operations, deep.Operation{
+			Kind: deep.OpReplace, Path: c.Path, Old: c.Base, New: c.Remote,
+		})
+	}
 
-	final := base
-	deep.Apply(&final, merged)
+	final := deep.Clone(base)
+	if err := deep.Apply(&final, merged); err != nil {
+		log.Fatal(err)
+	}
 
 	fmt.Println("\n--- FINAL STATE ---")
 	fmt.Printf("%+v\n", final)
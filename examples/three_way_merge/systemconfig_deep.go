@@ -29,11 +29,14 @@ func (t *SystemConfig) Patch(p deep.Patch[SystemConfig], logger *slog.Logger) er
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -63,6 +66,9 @@ func (t *SystemConfig) applyOperation(op deep.Operation, logger *slog.Logger) (b
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(SystemConfig)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -76,6 +82,15 @@ func (t *SystemConfig) applyOperation(op deep.Operation, logger *slog.Logger) (b
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/app", "/AppName":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.AppName != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.AppName)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.AppName)
 			return true, nil
@@ -90,6 +105,21 @@ func (t *SystemConfig) applyOperation(op deep.Operation, logger *slog.Logger) (b
 			return true, nil
 		}
 	case "/threads", "/MaxThreads":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.MaxThreads != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.MaxThreads)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.MaxThreads) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.MaxThreads)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.MaxThreads)
 			return true, nil
@@ -117,6 +147,9 @@ func (t *SystemConfig) applyOperation(op deep.Operation, logger *slog.Logger) (b
 			return true, nil
 		}
 	case "/endpoints", "/Endpoints":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Endpoints)
 			return true, nil
@@ -134,6 +167,9 @@ func (t *SystemConfig) applyOperation(op deep.Operation, logger *slog.Logger) (b
 		if strings.HasPrefix(op.Path, "/endpoints/") {
 			parts := strings.Split(op.Path[len("/endpoints/"):], "/")
 			key := parts[0]
+			if op.Kind == deep.OpTest {
+				return false, nil
+			}
 			if op.Kind == deep.OpRemove {
 				delete(t.Endpoints, key)
 				return true, nil
@@ -152,37 +188,85 @@ func (t *SystemConfig) applyOperation(op deep.Operation, logger *slog.Logger) (b
 
 // Diff compares t with other and returns a Patch.
 func (t *SystemConfig) Diff(other *SystemConfig) deep.Patch[SystemConfig] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *SystemConfig) DiffLimited(other *SystemConfig, l *_deepengine.DiffLimits) (deep.Patch[SystemConfig], error) {
 	p := deep.Patch[SystemConfig]{}
-	if t.AppName != other.AppName {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/app", Old: t.AppName, New: other.AppName})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.MaxThreads != other.MaxThreads {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/threads", Old: t.MaxThreads, New: other.MaxThreads})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("app") {
+		if t.AppName != other.AppName {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/app", Old: t.AppName, New: other.AppName})
+		}
 	}
-	if other.Endpoints != nil {
-		for k, v := range other.Endpoints {
-			if t.Endpoints == nil {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/endpoints/%v", k), New: v})
-				continue
-			}
-			if oldV, ok := t.Endpoints[k]; !ok || v != oldV {
-				kind := deep.OpReplace
-				if !ok {
-					kind = deep.OpAdd
-				}
-				p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/endpoints/%v", k), Old: oldV, New: v})
-			}
+	if !l.Ignored("threads") {
+		if t.MaxThreads != other.MaxThreads {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/threads", Old: t.MaxThreads, New: other.MaxThreads})
 		}
 	}
-	if t.Endpoints != nil {
-		for k, v := range t.Endpoints {
-			if other.Endpoints == nil || !contains(other.Endpoints, k) {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/endpoints/%v", k), Old: v})
+	if !l.Ignored("endpoints") {
+		if l.Descend("endpoints") {
+			skip := l.IgnoresBelow()
+			if other.Endpoints != nil {
+				for k, v := range other.Endpoints {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if t.Endpoints == nil {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/endpoints/%v", k), New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+						continue
+					}
+					if oldV, ok := t.Endpoints[k]; !ok || v != oldV {
+						kind := deep.OpReplace
+						if !ok {
+							kind = deep.OpAdd
+						}
+						p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/endpoints/%v", k), Old: oldV, New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
+			}
+			if t.Endpoints != nil {
+				for k, v := range t.Endpoints {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if other.Endpoints == nil || !contains(other.Endpoints, k) {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/endpoints/%v", k), Old: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
 			}
+			l.Ascend()
+		} else if !deep.Equal(t.Endpoints, other.Endpoints) {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/endpoints", Old: t.Endpoints, New: other.Endpoints})
 		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *SystemConfig) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *SystemConfig) evaluateCondition(c condition.Condition) (bool, error) {
@@ -212,6 +296,14 @@ func (t *SystemConfig) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -225,6 +317,12 @@ func (t *SystemConfig) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.AppName))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.AppName, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.AppName), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field AppName")
@@ -242,6 +340,12 @@ func (t *SystemConfig) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.AppName >= _sv, nil
 		case "<=":
 			return t.AppName <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.AppName, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.AppName, _sv), nil
+		case "contains":
+			return strings.Contains(t.AppName, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -269,6 +373,9 @@ func (t *SystemConfig) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.MaxThreads))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.MaxThreads, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -317,6 +424,9 @@ func (t *SystemConfig) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -343,6 +453,25 @@ func (t *SystemConfig) Equal(other *SystemConfig) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *SystemConfig) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.String(t.AppName)
+	h.Int64(int64(t.MaxThreads))
+	h.Uint64(uint64(len(t.Endpoints)))
+	{
+		var sum uint64
+		for k, v := range t.Endpoints {
+			e := _deepengine.NewHasher()
+			e.String(k)
+			e.String(v)
+			sum += e.Sum()
+		}
+		h.Uint64(sum)
+	}
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *SystemConfig) Clone() *SystemConfig {
 	res := &SystemConfig{
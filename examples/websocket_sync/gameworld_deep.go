@@ -29,11 +29,14 @@ func (t *GameWorld) Patch(p deep.Patch[GameWorld], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -63,6 +66,9 @@ func (t *GameWorld) applyOperation(op deep.Operation, logger *slog.Logger) (bool
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(GameWorld)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -76,6 +82,9 @@ func (t *GameWorld) applyOperation(op deep.Operation, logger *slog.Logger) (bool
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/players", "/Players":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Players)
 			return true, nil
@@ -90,6 +99,21 @@ func (t *GameWorld) applyOperation(op deep.Operation, logger *slog.Logger) (bool
 			return true, nil
 		}
 	case "/time", "/Time":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.Time != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Time)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.Time) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Time)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Time)
 			return true, nil
@@ -120,6 +144,9 @@ func (t *GameWorld) applyOperation(op deep.Operation, logger *slog.Logger) (bool
 		if strings.HasPrefix(op.Path, "/players/") {
 			parts := strings.Split(op.Path[len("/players/"):], "/")
 			key := parts[0]
+			if op.Kind == deep.OpTest {
+				return false, nil
+			}
 			if op.Kind == deep.OpRemove {
 				delete(t.Players, key)
 				return true, nil
@@ -138,34 +165,80 @@ func (t *GameWorld) applyOperation(op deep.Operation, logger *slog.Logger) (bool
 
 // Diff compares t with other and returns a Patch.
 func (t *GameWorld) Diff(other *GameWorld) deep.Patch[GameWorld] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *GameWorld) DiffLimited(other *GameWorld, l *_deepengine.DiffLimits) (deep.Patch[GameWorld], error) {
 	p := deep.Patch[GameWorld]{}
-	if other.Players != nil {
-		for k, v := range other.Players {
-			if t.Players == nil {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/players/%v", k), New: v})
-				continue
+	if err := l.Check(); err != nil {
+		return p, err
+	}
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("players") {
+		if l.Descend("players") {
+			skip := l.IgnoresBelow()
+			if other.Players != nil {
+				for k, v := range other.Players {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if t.Players == nil {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/players/%v", k), New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+						continue
+					}
+					if oldV, ok := t.Players[k]; !ok || v != oldV {
+						kind := deep.OpReplace
+						if !ok {
+							kind = deep.OpAdd
+						}
+						p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/players/%v", k), Old: oldV, New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
 			}
-			if oldV, ok := t.Players[k]; !ok || v != oldV {
-				kind := deep.OpReplace
-				if !ok {
-					kind = deep.OpAdd
+			if t.Players != nil {
+				for k, v := range t.Players {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if other.Players == nil || !contains(other.Players, k) {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/players/%v", k), Old: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
 				}
-				p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/players/%v", k), Old: oldV, New: v})
 			}
+			l.Ascend()
+		} else if !deep.Equal(t.Players, other.Players) {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/players", Old: t.Players, New: other.Players})
 		}
 	}
-	if t.Players != nil {
-		for k, v := range t.Players {
-			if other.Players == nil || !contains(other.Players, k) {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/players/%v", k), Old: v})
-			}
+	if !l.Ignored("time") {
+		if t.Time != other.Time {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/time", Old: t.Time, New: other.Time})
 		}
 	}
-	if t.Time != other.Time {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/time", Old: t.Time, New: other.Time})
-	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *GameWorld) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *GameWorld) evaluateCondition(c condition.Condition) (bool, error) {
@@ -195,6 +268,14 @@ func (t *GameWorld) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -208,6 +289,9 @@ func (t *GameWorld) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Time))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Time, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -256,6 +340,9 @@ func (t *GameWorld) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -279,6 +366,24 @@ func (t *GameWorld) Equal(other *GameWorld) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *GameWorld) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.Uint64(uint64(len(t.Players)))
+	{
+		var sum uint64
+		for k, v := range t.Players {
+			e := _deepengine.NewHasher()
+			e.String(k)
+			e.Value(v)
+			sum += e.Sum()
+		}
+		h.Uint64(sum)
+	}
+	h.Int64(int64(t.Time))
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *GameWorld) Clone() *GameWorld {
 	res := &GameWorld{
@@ -311,11 +416,14 @@ func (t *Player) Patch(p deep.Patch[Player], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -345,6 +453,9 @@ func (t *Player) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(Player)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -358,6 +469,21 @@ func (t *Player) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/x", "/X":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.X != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.X)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.X) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.X)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.X)
 			return true, nil
@@ -385,6 +511,21 @@ func (t *Player) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 			return true, nil
 		}
 	case "/y", "/Y":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.Y != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Y)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.Y) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Y)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Y)
 			return true, nil
@@ -412,6 +553,15 @@ func (t *Player) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 			return true, nil
 		}
 	case "/name", "/Name":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Name != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Name)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Name)
 			return true, nil
@@ -432,18 +582,42 @@ func (t *Player) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 
 // Diff compares t with other and returns a Patch.
 func (t *Player) Diff(other *Player) deep.Patch[Player] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *Player) DiffLimited(other *Player, l *_deepengine.DiffLimits) (deep.Patch[Player], error) {
 	p := deep.Patch[Player]{}
-	if t.X != other.X {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/x", Old: t.X, New: other.X})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.Y != other.Y {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/y", Old: t.Y, New: other.Y})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("x") {
+		if t.X != other.X {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/x", Old: t.X, New: other.X})
+		}
 	}
-	if t.Name != other.Name {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/name", Old: t.Name, New: other.Name})
+	if !l.Ignored("y") {
+		if t.Y != other.Y {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/y", Old: t.Y, New: other.Y})
+		}
+	}
+	if !l.Ignored("name") {
+		if t.Name != other.Name {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/name", Old: t.Name, New: other.Name})
+		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *Player) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *Player) evaluateCondition(c condition.Condition) (bool, error) {
@@ -473,6 +647,14 @@ func (t *Player) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -486,6 +668,9 @@ func (t *Player) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.X))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.X, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -543,6 +728,9 @@ func (t *Player) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Y))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Y, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -600,6 +788,12 @@ func (t *Player) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Name))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Name, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Name), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Name")
@@ -617,6 +811,12 @@ func (t *Player) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Name >= _sv, nil
 		case "<=":
 			return t.Name <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Name, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Name, _sv), nil
+		case "contains":
+			return strings.Contains(t.Name, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -635,6 +835,9 @@ func (t *Player) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -652,6 +855,15 @@ func (t *Player) Equal(other *Player) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *Player) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.Int64(int64(t.X))
+	h.Int64(int64(t.Y))
+	h.String(t.Name)
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *Player) Clone() *Player {
 	res := &Player{
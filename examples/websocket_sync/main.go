@@ -48,14 +48,23 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	wireData, _ := json.Marshal(patch)
+	jsonData, _ := json.Marshal(patch)
+
+	// The binary encoding is what actually goes over the wire.
+	wireData, err := patch.ToBinary(deep.FieldIndexPaths())
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	fmt.Println("\n--- SERVER BROADCAST ---")
-	fmt.Printf("Patch (%d bytes): %s\n", len(wireData), string(wireData))
+	fmt.Printf("JSON patch (%d bytes): %s\n", len(jsonData), string(jsonData))
+	fmt.Printf("Binary patch (%d bytes, %.0f%% of JSON)\n", len(wireData), 100*float64(len(wireData))/float64(len(jsonData)))
 
 	// Client receives and applies.
 	var receivedPatch deep.Patch[GameWorld]
-	json.Unmarshal(wireData, &receivedPatch)
+	if err := receivedPatch.UnmarshalBinary(wireData); err != nil {
+		log.Fatal(err)
+	}
 	deep.Apply(&clientState, receivedPatch)
 
 	fmt.Println("\n--- CLIENT STATE AFTER SYNC ---")
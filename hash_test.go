@@ -0,0 +1,47 @@
+package deep_test
+
+import (
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+// detailMirror has the same fields as testmodels.Detail but no generated
+// methods, so it is hashed by reflection.
+type detailMirror struct {
+	Age     int
+	Address string
+}
+
+func TestHash(t *testing.T) {
+	u1 := testmodels.User{
+		ID:    1,
+		Name:  "Alice",
+		Info:  testmodels.Detail{Age: 30, Address: "Home"},
+		Roles: []string{"admin"},
+		Score: map[string]int{"a": 1, "b": 2, "c": 3},
+	}
+	u2 := deep.Clone(u1)
+	if deep.Hash(u1) != deep.Hash(u2) {
+		t.Error("clones hash differently")
+	}
+
+	u2.Score["b"] = 20
+	if deep.Hash(u1) == deep.Hash(u2) {
+		t.Error("different values hash alike")
+	}
+
+	// Nil and empty collections are equal for generated types.
+	u1.Roles, u2.Roles = nil, []string{}
+	u2.Score = map[string]int{"a": 1, "b": 2, "c": 3}
+	if !deep.Equal(u1, u2) || deep.Hash(u1) != deep.Hash(u2) {
+		t.Error("nil and empty slices should be equal and hash alike")
+	}
+
+	// The generated method and reflection agree.
+	d := testmodels.Detail{Age: 30, Address: "Home"}
+	if deep.Hash(d) != deep.Hash(detailMirror{Age: 30, Address: "Home"}) {
+		t.Error("generated and reflection hashes differ")
+	}
+}
@@ -0,0 +1,190 @@
+package deep
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNothingToUndo is returned by [History.Undo] when there is no step to
+// undo, and ErrNothingToRedo by [History.Redo] when there is none to redo.
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+type historyConfig struct {
+	maxDepth int
+}
+
+// HistoryOption configures a [History].
+type HistoryOption func(*historyConfig)
+
+// MaxUndo limits a [History] to the n most recent undo steps; older steps are
+// discarded. The default is 100; n <= 0 means no limit.
+func MaxUndo(n int) HistoryOption {
+	return func(c *historyConfig) { c.maxDepth = n }
+}
+
+// historyStep is one undoable step: the patch that performs it and the patch
+// that reverts it.
+type historyStep[T any] struct {
+	forward, inverse Patch[T]
+}
+
+// History applies patches to a value and records, for each, an inverse patch
+// computed with [Diff] from the value before and after the patch, so that
+// edits can be undone and redone.
+//
+// Recording a new step clears the redo steps. Patches applied between
+// [History.BeginGroup] and [History.EndGroup] form a single step.
+type History[T any] struct {
+	mu     sync.Mutex
+	target *T
+	cfg    historyConfig
+	undo   []historyStep[T]
+	redo   []historyStep[T]
+
+	// groupDepth counts open groups; groupStart is the value when the
+	// outermost one began.
+	groupDepth int
+	groupStart T
+}
+
+// NewHistory returns a history for the value target points to. All changes
+// to it should go through the history, since undo steps are computed against
+// the value as the history last saw it.
+func NewHistory[T any](target *T, opts ...HistoryOption) *History[T] {
+	cfg := historyConfig{maxDepth: 100}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &History[T]{target: target, cfg: cfg}
+}
+
+// Apply applies p to the target with [Apply] and records the change as an
+// undo step. If p fails part-way, the operations that were applied are
+// recorded. A patch that leaves the target unchanged records nothing.
+func (h *History[T]) Apply(p Patch[T], opts ...ApplyOption) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.groupDepth > 0 {
+		// The redo steps are cleared when EndGroup records the group.
+		return Apply(h.target, p, opts...)
+	}
+
+	before := Clone(*h.target)
+	err := Apply(h.target, p, opts...)
+	if rerr := h.record(before); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// BeginGroup starts a group: every patch applied until the matching
+// [History.EndGroup] is undone and redone as one step. Groups may nest; only
+// the outermost one records a step.
+func (h *History[T]) BeginGroup() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.groupDepth == 0 {
+		h.groupStart = Clone(*h.target)
+	}
+	h.groupDepth++
+}
+
+// EndGroup ends the group started by the last [History.BeginGroup].
+func (h *History[T]) EndGroup() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.groupDepth == 0 {
+		return errors.New("EndGroup without BeginGroup")
+	}
+	h.groupDepth--
+	if h.groupDepth > 0 {
+		return nil
+	}
+	start := h.groupStart
+	var zero T
+	h.groupStart = zero
+	return h.record(start)
+}
+
+// record pushes the step from before to the current target, if there is one,
+// and clears the redo steps.
+func (h *History[T]) record(before T) error {
+	forward, err := Diff(before, *h.target)
+	if err != nil {
+		return err
+	}
+	if forward.IsEmpty() {
+		return nil
+	}
+	inverse, err := Diff(*h.target, before)
+	if err != nil {
+		return err
+	}
+
+	h.redo = nil
+	h.undo = append(h.undo, historyStep[T]{forward: forward, inverse: inverse})
+	if h.cfg.maxDepth > 0 && len(h.undo) > h.cfg.maxDepth {
+		h.undo = append(h.undo[:0:0], h.undo[len(h.undo)-h.cfg.maxDepth:]...)
+	}
+	return nil
+}
+
+// Undo reverts the most recent step. It fails with [ErrNothingToUndo] if
+// there is none, and cannot be called while a group is open. The inverse is
+// applied atomically; if it fails, the target and the history are unchanged.
+func (h *History[T]) Undo() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.groupDepth > 0 {
+		return errors.New("cannot undo inside a group")
+	}
+	if len(h.undo) == 0 {
+		return ErrNothingToUndo
+	}
+	step := h.undo[len(h.undo)-1]
+	if err := Apply(h.target, step.inverse, Atomic()); err != nil {
+		return err
+	}
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, step)
+	return nil
+}
+
+// Redo reapplies the most recently undone step. It fails with
+// [ErrNothingToRedo] if there is none, and cannot be called while a group is
+// open.
+func (h *History[T]) Redo() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.groupDepth > 0 {
+		return errors.New("cannot redo inside a group")
+	}
+	if len(h.redo) == 0 {
+		return ErrNothingToRedo
+	}
+	step := h.redo[len(h.redo)-1]
+	if err := Apply(h.target, step.forward, Atomic()); err != nil {
+		return err
+	}
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, step)
+	return nil
+}
+
+// CanUndo reports whether there is a step to undo.
+func (h *History[T]) CanUndo() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.undo) > 0
+}
+
+// CanRedo reports whether there is a step to redo.
+func (h *History[T]) CanRedo() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.redo) > 0
+}
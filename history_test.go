@@ -0,0 +1,147 @@
+package deep_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+func TestHistory(t *testing.T) {
+	u := testmodels.User{ID: 1, Name: "a", Roles: []string{"x"}}
+	h := deep.NewHistory(&u)
+
+	namePath := deep.Field(func(u *testmodels.User) *string { return &u.Name })
+	rolesPath := deep.Field(func(u *testmodels.User) *[]string { return &u.Roles })
+	set := func(name string) deep.Patch[testmodels.User] {
+		return deep.Edit(&u).With(deep.Set(namePath, name)).Build()
+	}
+
+	if err := h.Apply(set("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Apply(set("c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Undo(); err != nil || u.Name != "b" {
+		t.Fatalf("Undo: %v, name %q", err, u.Name)
+	}
+	if err := h.Redo(); err != nil || u.Name != "c" {
+		t.Fatalf("Redo: %v, name %q", err, u.Name)
+	}
+
+	// A group is one step.
+	h.BeginGroup()
+	h.Apply(set("d"))
+	h.Apply(deep.Edit(&u).With(deep.Add(deep.At(rolesPath, 1), "y")).Build())
+	if err := h.EndGroup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	want := testmodels.User{ID: 1, Name: "c", Roles: []string{"x"}}
+	if !reflect.DeepEqual(u, want) {
+		t.Fatalf("after group undo: %+v", u)
+	}
+
+	// A group that changes nothing keeps the redo steps.
+	h.BeginGroup()
+	h.Apply(set("c"))
+	if err := h.EndGroup(); err != nil {
+		t.Fatal(err)
+	}
+	if !h.CanRedo() {
+		t.Error("redo steps should survive a group that changed nothing")
+	}
+
+	// A new edit clears the redo steps.
+	if err := h.Apply(set("e")); err != nil {
+		t.Fatal(err)
+	}
+	if h.CanRedo() {
+		t.Error("redo steps should be cleared by a new edit")
+	}
+	if err := h.Redo(); !errors.Is(err, deep.ErrNothingToRedo) {
+		t.Errorf("Redo = %v, want ErrNothingToRedo", err)
+	}
+
+	for h.CanUndo() {
+		if err := h.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if u.Name != "a" {
+		t.Errorf("after undoing everything: %+v", u)
+	}
+	if err := h.Undo(); !errors.Is(err, deep.ErrNothingToUndo) {
+		t.Errorf("Undo = %v, want ErrNothingToUndo", err)
+	}
+}
+
+func TestHistoryMaxUndo(t *testing.T) {
+	u := testmodels.User{}
+	h := deep.NewHistory(&u, deep.MaxUndo(2))
+	idPath := deep.Field(func(u *testmodels.User) *int { return &u.ID })
+	for i := 1; i <= 5; i++ {
+		if err := h.Apply(deep.Edit(&u).With(deep.Set(idPath, i)).Build()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := 0
+	for h.CanUndo() {
+		h.Undo()
+		n++
+	}
+	if n != 2 || u.ID != 3 {
+		t.Errorf("undid %d steps, ID = %d", n, u.ID)
+	}
+}
+
+func TestHistorySliceUndo(t *testing.T) {
+	type Doc struct {
+		Items []int
+		Tags  []string
+	}
+	d := Doc{Items: []int{1}, Tags: []string{"a", "b", "c"}}
+	h := deep.NewHistory(&d)
+
+	steps := []Doc{
+		{Items: []int{4, 0, 1}, Tags: []string{"a", "b", "c"}},
+		{Items: []int{0}, Tags: []string{"c", "a"}},
+		{Items: nil, Tags: []string{"b", "c", "d", "a"}},
+	}
+	history := []Doc{deep.Clone(d)}
+	for _, s := range steps {
+		p, err := deep.Diff(d, s)
+		if err != nil {
+			t.Fatalf("Diff failed: %v", err)
+		}
+		if err := h.Apply(p); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if !deep.Equal(d, s) {
+			t.Fatalf("after Apply: got %+v, want %+v", d, s)
+		}
+		history = append(history, deep.Clone(s))
+	}
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		if err := h.Undo(); err != nil {
+			t.Fatalf("Undo of step %d: %v", i, err)
+		}
+		if !deep.Equal(d, history[i]) {
+			t.Fatalf("after Undo of step %d: got %+v, want %+v", i, d, history[i])
+		}
+	}
+	for i := range steps {
+		if err := h.Redo(); err != nil {
+			t.Fatalf("Redo of step %d: %v", i, err)
+		}
+		if !deep.Equal(d, history[i+1]) {
+			t.Fatalf("after Redo of step %d: got %+v, want %+v", i, d, history[i+1])
+		}
+	}
+}
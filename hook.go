@@ -0,0 +1,136 @@
+package deep
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Hook is called around operations applied by [Apply], [ApplyWithReport] and
+// [Preview].
+//
+// Before an operation runs, old is the value currently at op.Path and new is
+// the value the operation will write (op.New for adds and replaces, nil
+// otherwise). Returning [ErrSkipOp] vetoes the operation, returning the error
+// of [RewriteOp] runs a different operation in its place, and any other error
+// fails it.
+//
+// After an operation has run, old is the value it overwrote and new is the
+// value now at op.Path. Returning an error fails the operation, which is then
+// undone.
+//
+// For an [OpLog] operation both values are nil.
+type Hook func(op Operation, old, new any) error
+
+// ErrSkipOp is returned by a hook to skip the operation it is called for. It
+// is not reported as a failure.
+var ErrSkipOp = errors.New("operation skipped by hook")
+
+// RewriteOp returns an error that makes a before hook replace the operation
+// it is called for with op. The remaining before hooks see op. Conditions of
+// the replacement are ignored; those of the original operation have already
+// been evaluated.
+func RewriteOp(op Operation) error {
+	return &rewriteError{op: op}
+}
+
+type rewriteError struct {
+	op Operation
+}
+
+func (e *rewriteError) Error() string {
+	return fmt.Sprintf("operation rewritten to %s %s", e.op.Kind, e.op.Path)
+}
+
+// HookPhase tells a hook registered with [WithHook] whether it is called
+// before or after an operation.
+type HookPhase int
+
+const (
+	// BeforePhase is the call before an operation runs, which may veto or
+	// rewrite it.
+	BeforePhase HookPhase = iota
+	// AfterPhase is the call after an operation has run.
+	AfterPhase
+)
+
+func (p HookPhase) String() string {
+	if p == AfterPhase {
+		return "after"
+	}
+	return "before"
+}
+
+// WithHook registers h to be called both before and after every operation,
+// with the phase of each call; the values passed in each phase are those
+// described for [Hook]. Use [BeforeOp] or [AfterOp] for a hook that only
+// needs one phase. Hooks run in the order they are registered.
+func WithHook(h func(phase HookPhase, op Operation, old, new any) error) ApplyOption {
+	return func(c *applyConfig) {
+		c.before = append(c.before, func(op Operation, old, new any) error { return h(BeforePhase, op, old, new) })
+		c.after = append(c.after, func(op Operation, old, new any) error { return h(AfterPhase, op, old, new) })
+	}
+}
+
+// BeforeOp registers h to be called only before every operation.
+func BeforeOp(h Hook) ApplyOption {
+	return func(c *applyConfig) { c.before = append(c.before, h) }
+}
+
+// AfterOp registers h to be called only after every operation that was
+// applied.
+func AfterOp(h Hook) ApplyOption {
+	return func(c *applyConfig) { c.after = append(c.after, h) }
+}
+
+// runBeforeHooks calls the before hooks for op, whose path currently holds
+// old, and returns the operation to apply in its place together with the value
+// at that operation's path. It reports false if a hook skipped the operation.
+func runBeforeHooks[T any](hooks []Hook, target *T, op Operation, old any) (Operation, any, bool, error) {
+	for _, h := range hooks {
+		err := h(op, old, hookNewValue(op))
+		var rw *rewriteError
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrSkipOp):
+			return op, old, false, nil
+		case errors.As(err, &rw):
+			rw.op.Strict = op.Strict
+			rw.op.If, rw.op.Unless = nil, nil
+			if rw.op.Kind == OpLog {
+				old = nil
+			} else if rw.op.Path != op.Path || op.Kind == OpLog {
+				old = valueAtPath(target, rw.op.Path)
+			}
+			op = rw.op
+		default:
+			return op, old, false, fmt.Errorf("hook rejected %s %s: %w", op.Kind, op.Path, err)
+		}
+	}
+	return op, old, true, nil
+}
+
+// runAfterHooks calls the after hooks for an applied op.
+func runAfterHooks[T any](hooks []Hook, target *T, op Operation, old any) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	var cur any
+	if op.Kind != OpLog {
+		cur = valueAtPath(target, op.Path)
+	}
+	for _, h := range hooks {
+		if err := h(op, old, cur); err != nil {
+			return fmt.Errorf("hook rejected %s %s: %w", op.Kind, op.Path, err)
+		}
+	}
+	return nil
+}
+
+// hookNewValue returns the value op will write at its path.
+func hookNewValue(op Operation) any {
+	switch op.Kind {
+	case OpAdd, OpReplace:
+		return op.New
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package deep_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+type hookDoc struct {
+	Title string
+	Tags  []string
+}
+
+func TestWithHook(t *testing.T) {
+	var calls []string
+	hook := func(phase deep.HookPhase, op deep.Operation, old, new any) error {
+		calls = append(calls, fmt.Sprintf("%s %s %v->%v", phase, op.Path, old, new))
+		return nil
+	}
+
+	// Generated fast path.
+	u := testmodels.User{ID: 1, Name: "Alice"}
+	p := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/full_name", New: "Bob"},
+	}}
+	if err := deep.Apply(&u, p, deep.WithHook(hook)); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := []string{"before /full_name Alice->Bob", "after /full_name Alice->Bob"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) || u.Name != "Bob" {
+		t.Errorf("calls = %v, name = %q", calls, u.Name)
+	}
+
+	// Reflection fallback.
+	calls = nil
+	d := hookDoc{Title: "a"}
+	dp := deep.Patch[hookDoc]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/Title", New: "b"},
+		{Kind: deep.OpAdd, Path: "/Tags/0", New: "x"},
+	}}
+	if err := deep.Apply(&d, dp, deep.WithHook(hook)); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want = []string{"before /Title a->b", "after /Title a->b", "before /Tags/0 <nil>->x", "after /Tags/0 <nil>->x"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestBeforeOpVetoAndRewrite(t *testing.T) {
+	u := testmodels.User{ID: 1, Name: "Alice", Info: testmodels.Detail{Age: 30}}
+	p := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/id", New: 2},
+		{Kind: deep.OpReplace, Path: "/full_name", New: "bob"},
+		{Kind: deep.OpReplace, Path: "/info/Age", New: -1},
+	}}
+	before := deep.BeforeOp(func(op deep.Operation, old, new any) error {
+		switch op.Path {
+		case "/id":
+			return deep.ErrSkipOp
+		case "/full_name":
+			op.New = "Bob"
+			return deep.RewriteOp(op)
+		case "/info/Age":
+			if new.(int) < 0 {
+				return errors.New("negative age")
+			}
+		}
+		return nil
+	})
+
+	rep, err := deep.ApplyWithReport(&u, p, before)
+	if err == nil {
+		t.Fatal("expected error from rejected operation")
+	}
+	want := []deep.OpStatus{deep.StatusSkipped, deep.StatusApplied, deep.StatusFailed}
+	for i, r := range rep.Results {
+		if r.Status != want[i] {
+			t.Errorf("result %d: status %v, want %v", i, r.Status, want[i])
+		}
+	}
+	if !errors.Is(rep.Results[0].Err, deep.ErrSkipOp) {
+		t.Errorf("expected ErrSkipOp, got %v", rep.Results[0].Err)
+	}
+	if u.ID != 1 || u.Name != "Bob" || u.Info.Age != 30 {
+		t.Errorf("unexpected target: %+v", u)
+	}
+}
+
+func TestAfterOpUndo(t *testing.T) {
+	u := testmodels.User{ID: 1, Name: "Alice"}
+	p := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/full_name", New: ""},
+		{Kind: deep.OpReplace, Path: "/id", New: 2},
+	}}
+	after := deep.AfterOp(func(op deep.Operation, old, new any) error {
+		if op.Path == "/full_name" && new == "" {
+			return errors.New("name must not be empty")
+		}
+		return nil
+	})
+
+	if err := deep.Apply(&u, p, after); err == nil {
+		t.Fatal("expected error from after hook")
+	}
+	if u.Name != "Alice" || u.ID != 2 {
+		t.Errorf("unexpected target: %+v", u)
+	}
+
+	u = testmodels.User{ID: 1, Name: "Alice"}
+	if err := deep.Apply(&u, p, after, deep.Atomic()); err == nil {
+		t.Fatal("expected error from after hook")
+	}
+	if u.Name != "Alice" || u.ID != 1 {
+		t.Errorf("unexpected target after atomic rollback: %+v", u)
+	}
+}
@@ -0,0 +1,638 @@
+package core
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/brunoga/deep/v5/internal/unsafe"
+)
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+// isBinaryCodec reports whether values of typ encode themselves, as
+// time.Time does.
+func isBinaryCodec(typ reflect.Type) bool {
+	return typ.Kind() != reflect.Pointer && typ.Kind() != reflect.Interface &&
+		typ.Implements(binaryMarshalerType) && reflect.PointerTo(typ).Implements(binaryUnmarshalerType)
+}
+
+// Tags of dynamically typed values in the binary encoding.
+const (
+	dynNil byte = iota
+	dynBool
+	dynInt
+	dynInt8
+	dynInt16
+	dynInt32
+	dynInt64
+	dynUint
+	dynUint8
+	dynUint16
+	dynUint32
+	dynUint64
+	dynFloat32
+	dynFloat64
+	dynString
+	dynBytes
+	dynSlice
+	dynMap
+	dynGob
+)
+
+// BinaryWriter builds the compact binary encoding used for patches. Values
+// whose type is known to both sides are written without type information;
+// others are tagged with their type. Map entries are written in a fixed
+// order, so the encoding of a value is deterministic unless it contains
+// values encoded with encoding/gob.
+type BinaryWriter struct {
+	buf []byte
+}
+
+// Data returns the bytes written so far.
+func (w *BinaryWriter) Data() []byte {
+	return w.buf
+}
+
+func (w *BinaryWriter) Byte(b byte) {
+	w.buf = append(w.buf, b)
+}
+
+func (w *BinaryWriter) Uvarint(x uint64) {
+	w.buf = binary.AppendUvarint(w.buf, x)
+}
+
+func (w *BinaryWriter) Varint(x int64) {
+	w.buf = binary.AppendVarint(w.buf, x)
+}
+
+// Bytes writes b prefixed with its length.
+func (w *BinaryWriter) Bytes(b []byte) {
+	w.Uvarint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+// String writes s prefixed with its length.
+func (w *BinaryWriter) String(s string) {
+	w.Uvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// Value writes v, whose type the reader is expected to know. Unexported
+// struct fields are included.
+func (w *BinaryWriter) Value(v reflect.Value) error {
+	typ := v.Type()
+	if isBinaryCodec(typ) {
+		if !v.CanInterface() {
+			unsafe.DisableRO(&v)
+		}
+		data, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		w.Bytes(data)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			w.Byte(1)
+		} else {
+			w.Byte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.Varint(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.Uvarint(v.Uint())
+	case reflect.Float32:
+		w.buf = binary.LittleEndian.AppendUint32(w.buf, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(v.Float()))
+	case reflect.Complex64:
+		c := v.Complex()
+		w.buf = binary.LittleEndian.AppendUint32(w.buf, math.Float32bits(float32(real(c))))
+		w.buf = binary.LittleEndian.AppendUint32(w.buf, math.Float32bits(float32(imag(c))))
+	case reflect.Complex128:
+		c := v.Complex()
+		w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(real(c)))
+		w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(imag(c)))
+	case reflect.String:
+		w.String(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			w.Uvarint(0)
+			return nil
+		}
+		w.Uvarint(uint64(v.Len()) + 1)
+		if typ.Elem().Kind() == reflect.Uint8 {
+			w.buf = append(w.buf, v.Bytes()...)
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := w.Value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := w.Value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			w.Uvarint(0)
+			return nil
+		}
+		w.Uvarint(uint64(v.Len()) + 1)
+		// Entries are sorted by their encoded key so that equal maps encode
+		// alike.
+		type entry struct {
+			key []byte
+			val reflect.Value
+		}
+		entries := make([]entry, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var kw BinaryWriter
+			if err := kw.Value(iter.Key()); err != nil {
+				return err
+			}
+			entries = append(entries, entry{kw.buf, iter.Value()})
+		}
+		sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+		for _, e := range entries {
+			w.buf = append(w.buf, e.key...)
+			if err := w.Value(e.val); err != nil {
+				return err
+			}
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			w.Byte(0)
+			return nil
+		}
+		w.Byte(1)
+		return w.Value(v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return w.Dynamic(nil)
+		}
+		if !v.CanInterface() {
+			unsafe.DisableRO(&v)
+		}
+		return w.Dynamic(v.Interface())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if err := w.Value(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %v", typ)
+	}
+	return nil
+}
+
+// Dynamic writes v preceded by a tag identifying its type. Types other than
+// the basic kinds, []any and map[string]any are written with encoding/gob and
+// must have been registered with gob.Register.
+func (w *BinaryWriter) Dynamic(v any) error {
+	switch x := v.(type) {
+	case nil:
+		w.Byte(dynNil)
+	case bool:
+		w.Byte(dynBool)
+		return w.Value(reflect.ValueOf(x))
+	case int:
+		w.Byte(dynInt)
+		w.Varint(int64(x))
+	case int8:
+		w.Byte(dynInt8)
+		w.Varint(int64(x))
+	case int16:
+		w.Byte(dynInt16)
+		w.Varint(int64(x))
+	case int32:
+		w.Byte(dynInt32)
+		w.Varint(int64(x))
+	case int64:
+		w.Byte(dynInt64)
+		w.Varint(x)
+	case uint:
+		w.Byte(dynUint)
+		w.Uvarint(uint64(x))
+	case uint8:
+		w.Byte(dynUint8)
+		w.Uvarint(uint64(x))
+	case uint16:
+		w.Byte(dynUint16)
+		w.Uvarint(uint64(x))
+	case uint32:
+		w.Byte(dynUint32)
+		w.Uvarint(uint64(x))
+	case uint64:
+		w.Byte(dynUint64)
+		w.Uvarint(x)
+	case float32:
+		w.Byte(dynFloat32)
+		return w.Value(reflect.ValueOf(x))
+	case float64:
+		w.Byte(dynFloat64)
+		return w.Value(reflect.ValueOf(x))
+	case string:
+		w.Byte(dynString)
+		w.String(x)
+	case []byte:
+		w.Byte(dynBytes)
+		return w.Value(reflect.ValueOf(x))
+	case []any:
+		w.Byte(dynSlice)
+		if x == nil {
+			w.Uvarint(0)
+			return nil
+		}
+		w.Uvarint(uint64(len(x)) + 1)
+		for _, e := range x {
+			if err := w.Dynamic(e); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		w.Byte(dynMap)
+		if x == nil {
+			w.Uvarint(0)
+			return nil
+		}
+		w.Uvarint(uint64(len(x)) + 1)
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			w.String(k)
+			if err := w.Dynamic(x[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+			return fmt.Errorf("cannot encode %T: %w", v, err)
+		}
+		w.Byte(dynGob)
+		w.Bytes(buf.Bytes())
+	}
+	return nil
+}
+
+// BinaryReader decodes data produced by a BinaryWriter.
+type BinaryReader struct {
+	data []byte
+	off  int
+}
+
+// NewBinaryReader returns a reader for data.
+func NewBinaryReader(data []byte) *BinaryReader {
+	return &BinaryReader{data: data}
+}
+
+// Len returns the number of unread bytes.
+func (r *BinaryReader) Len() int {
+	return len(r.data) - r.off
+}
+
+func (r *BinaryReader) Byte() (byte, error) {
+	if r.off >= len(r.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.off]
+	r.off++
+	return b, nil
+}
+
+func (r *BinaryReader) Uvarint() (uint64, error) {
+	x, n := binary.Uvarint(r.data[r.off:])
+	if n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.off += n
+	return x, nil
+}
+
+func (r *BinaryReader) Varint() (int64, error) {
+	x, n := binary.Varint(r.data[r.off:])
+	if n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.off += n
+	return x, nil
+}
+
+// Length reads a length written by BinaryWriter, checking that it does not
+// exceed the remaining input.
+func (r *BinaryReader) Length() (int, error) {
+	n, err := r.Uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(r.Len()) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int(n), nil
+}
+
+func (r *BinaryReader) next(n int) ([]byte, error) {
+	if n > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+// Bytes reads a length-prefixed byte slice. The result aliases the input.
+func (r *BinaryReader) Bytes() ([]byte, error) {
+	n, err := r.Length()
+	if err != nil {
+		return nil, err
+	}
+	return r.next(n)
+}
+
+func (r *BinaryReader) String() (string, error) {
+	b, err := r.Bytes()
+	return string(b), err
+}
+
+// count reads a nil-or-length prefix: ok is false for nil.
+func (r *BinaryReader) count() (n int, ok bool, err error) {
+	c, err := r.Uvarint()
+	if err != nil || c == 0 {
+		return 0, false, err
+	}
+	if c-1 > uint64(r.Len()) {
+		return 0, false, io.ErrUnexpectedEOF
+	}
+	return int(c - 1), true, nil
+}
+
+// Value reads a value of type typ written by BinaryWriter.Value.
+func (r *BinaryReader) Value(typ reflect.Type) (reflect.Value, error) {
+	v := reflect.New(typ).Elem()
+	return v, r.decodeInto(v)
+}
+
+func (r *BinaryReader) decodeInto(v reflect.Value) error {
+	typ := v.Type()
+	if isBinaryCodec(typ) {
+		data, err := r.Bytes()
+		if err != nil {
+			return err
+		}
+		target := v.Addr()
+		if !target.CanInterface() {
+			unsafe.DisableRO(&target)
+		}
+		return target.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := r.Byte()
+		v.SetBool(b != 0)
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := r.Varint()
+		v.SetInt(x)
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, err := r.Uvarint()
+		v.SetUint(x)
+		return err
+	case reflect.Float32:
+		b, err := r.next(4)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+	case reflect.Float64:
+		b, err := r.next(8)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	case reflect.Complex64:
+		b, err := r.next(8)
+		if err != nil {
+			return err
+		}
+		v.SetComplex(complex(
+			float64(math.Float32frombits(binary.LittleEndian.Uint32(b))),
+			float64(math.Float32frombits(binary.LittleEndian.Uint32(b[4:]))),
+		))
+	case reflect.Complex128:
+		b, err := r.next(16)
+		if err != nil {
+			return err
+		}
+		v.SetComplex(complex(
+			math.Float64frombits(binary.LittleEndian.Uint64(b)),
+			math.Float64frombits(binary.LittleEndian.Uint64(b[8:])),
+		))
+	case reflect.String:
+		s, err := r.String()
+		v.SetString(s)
+		return err
+	case reflect.Slice:
+		n, ok, err := r.count()
+		if err != nil || !ok {
+			return err
+		}
+		if typ.Elem().Kind() == reflect.Uint8 {
+			b, err := r.next(n)
+			if err != nil {
+				return err
+			}
+			s := reflect.MakeSlice(typ, n, n)
+			reflect.Copy(s, reflect.ValueOf(b))
+			v.Set(s)
+			return nil
+		}
+		s := reflect.MakeSlice(typ, n, n)
+		for i := 0; i < n; i++ {
+			if err := r.decodeInto(s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := r.decodeInto(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		n, ok, err := r.count()
+		if err != nil || !ok {
+			return err
+		}
+		m := reflect.MakeMapWithSize(typ, n)
+		for i := 0; i < n; i++ {
+			k, err := r.Value(typ.Key())
+			if err != nil {
+				return err
+			}
+			e, err := r.Value(typ.Elem())
+			if err != nil {
+				return err
+			}
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+	case reflect.Pointer:
+		present, err := r.Byte()
+		if err != nil || present == 0 {
+			return err
+		}
+		p := reflect.New(typ.Elem())
+		if err := r.decodeInto(p.Elem()); err != nil {
+			return err
+		}
+		v.Set(p)
+	case reflect.Interface:
+		x, err := r.Dynamic()
+		if err != nil {
+			return err
+		}
+		if x != nil {
+			v.Set(reflect.ValueOf(x))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if !f.CanSet() {
+				unsafe.DisableRO(&f)
+			}
+			if err := r.decodeInto(f); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot decode %v", typ)
+	}
+	return nil
+}
+
+// Dynamic reads a value written by BinaryWriter.Dynamic.
+func (r *BinaryReader) Dynamic() (any, error) {
+	tag, err := r.Byte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case dynNil:
+		return nil, nil
+	case dynBool:
+		return r.dynamicValue(reflect.TypeOf(false))
+	case dynInt, dynInt8, dynInt16, dynInt32, dynInt64:
+		x, err := r.Varint()
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case dynInt:
+			return int(x), nil
+		case dynInt8:
+			return int8(x), nil
+		case dynInt16:
+			return int16(x), nil
+		case dynInt32:
+			return int32(x), nil
+		}
+		return x, nil
+	case dynUint, dynUint8, dynUint16, dynUint32, dynUint64:
+		x, err := r.Uvarint()
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case dynUint:
+			return uint(x), nil
+		case dynUint8:
+			return uint8(x), nil
+		case dynUint16:
+			return uint16(x), nil
+		case dynUint32:
+			return uint32(x), nil
+		}
+		return x, nil
+	case dynFloat32:
+		return r.dynamicValue(reflect.TypeOf(float32(0)))
+	case dynFloat64:
+		return r.dynamicValue(reflect.TypeOf(float64(0)))
+	case dynString:
+		return r.String()
+	case dynBytes:
+		return r.dynamicValue(reflect.TypeOf([]byte(nil)))
+	case dynSlice:
+		n, ok, err := r.count()
+		if err != nil || !ok {
+			return []any(nil), err
+		}
+		s := make([]any, n)
+		for i := range s {
+			if s[i], err = r.Dynamic(); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	case dynMap:
+		n, ok, err := r.count()
+		if err != nil || !ok {
+			return map[string]any(nil), err
+		}
+		m := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			k, err := r.String()
+			if err != nil {
+				return nil, err
+			}
+			if m[k], err = r.Dynamic(); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case dynGob:
+		b, err := r.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		var v any
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unknown value tag %d", tag)
+	}
+}
+
+func (r *BinaryReader) dynamicValue(typ reflect.Type) (any, error) {
+	v, err := r.Value(typ)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
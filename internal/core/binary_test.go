@@ -0,0 +1,91 @@
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type binaryInner struct {
+	A [2]int8
+	b complex64
+}
+
+type binaryOuter struct {
+	U      uint16
+	F      float32
+	C      complex128
+	Bytes  []byte
+	Inner  *binaryInner
+	Nested map[int][]binaryInner
+	Any    any
+	hidden string
+}
+
+func TestBinaryValue(t *testing.T) {
+	tests := []any{
+		true,
+		int32(-300),
+		"hello",
+		[]string(nil),
+		[]string{},
+		map[string]int(nil),
+		(*int)(nil),
+		binaryOuter{
+			U:      7,
+			F:      1.5,
+			C:      complex(1, -2),
+			Bytes:  []byte{1, 2, 3},
+			Inner:  &binaryInner{A: [2]int8{-1, 1}, b: complex(3, 4)},
+			Nested: map[int][]binaryInner{1: {{b: 1}}},
+			Any:    map[string]any{"k": []any{uint8(1), 2.5, nil, []byte("x")}},
+			hidden: "secret",
+		},
+	}
+	for _, want := range tests {
+		var w BinaryWriter
+		if err := w.Value(reflect.ValueOf(want)); err != nil {
+			t.Fatalf("Value(%#v): %v", want, err)
+		}
+		r := NewBinaryReader(w.Data())
+		got, err := r.Value(reflect.TypeOf(want))
+		if err != nil {
+			t.Fatalf("decoding %#v: %v", want, err)
+		}
+		if !reflect.DeepEqual(got.Interface(), want) || r.Len() != 0 {
+			t.Errorf("got %#v, want %#v", got.Interface(), want)
+		}
+	}
+}
+
+func TestBinaryDynamicUnregistered(t *testing.T) {
+	var w BinaryWriter
+	if err := w.Dynamic(binaryInner{}); err == nil {
+		t.Error("expected error for unregistered type")
+	}
+}
+
+func TestBinaryMapsDeterministic(t *testing.T) {
+	typed := map[string]int{}
+	dyn := map[string]any{}
+	for i := 0; i < 50; i++ {
+		typed[strconv.Itoa(i)] = i
+		dyn[strconv.Itoa(i)] = i
+	}
+	var first []byte
+	for i := 0; i < 10; i++ {
+		var w BinaryWriter
+		if err := w.Value(reflect.ValueOf(typed)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Dynamic(dyn); err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = w.Data()
+		} else if !bytes.Equal(first, w.Data()) {
+			t.Fatal("map encoding is not deterministic")
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package core
+
+import "testing"
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{"b": 1, "a": [true, null, "x"]}`, `{"a":[true,null,"x"],"b":1}`},
+		{`[1.0, -0, 1e21, 1e-7, 0.000001, 123456789012, 1.5e300]`, `[1,0,1e+21,1e-7,0.000001,123456789012,1.5e+300]`},
+		// Integers stay exact beyond 2^53; fractions and exponents do not.
+		{`[9007199254740993, 9007199254740992, -9223372036854775808, 18446744073709551615, 9007199254740993.0]`,
+			`[9007199254740993,9007199254740992,-9223372036854775808,18446744073709551615,9007199254740992]`},
+		{`"<&>é \n\u0001"`, "\"<&>é \\n\\u0001\""},
+		// Sorted by UTF-16 code units: U+1F600 (surrogates D83D DE00) sorts
+		// before U+FB01.
+		{`{"ﬁ": 1, "😀": 2, "a": 3}`, "{\"a\":3,\"\U0001F600\":2,\"ﬁ\":1}"},
+	}
+	for _, tt := range tests {
+		got, err := CanonicalJSON([]byte(tt.in))
+		if err != nil {
+			t.Fatalf("CanonicalJSON(%s) failed: %v", tt.in, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("CanonicalJSON(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := CanonicalJSON([]byte(`{} {}`)); err == nil {
+		t.Error("expected error for trailing data")
+	}
+}
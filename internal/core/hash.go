@@ -0,0 +1,215 @@
+package core
+
+import (
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/brunoga/deep/v5/internal/unsafe"
+)
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// Hasher computes a structural hash with 64-bit FNV-1a. Values that are
+// equal according to Equal produce the same hash: floats are normalized so
+// that 0 and -0 hash alike, slices and maps hash their length rather than
+// whether they are nil, and map entries are combined independently of their
+// iteration order.
+//
+// Generated Hash methods feed their fields to a Hasher in declaration order.
+type Hasher struct {
+	sum uint64
+}
+
+// NewHasher returns an empty Hasher.
+func NewHasher() Hasher {
+	return Hasher{sum: fnvOffset}
+}
+
+// Sum returns the hash of everything written so far.
+func (h *Hasher) Sum() uint64 {
+	return h.sum
+}
+
+// Uint64 writes v to the hash.
+func (h *Hasher) Uint64(v uint64) {
+	for i := 0; i < 8; i++ {
+		h.sum ^= v & 0xff
+		h.sum *= fnvPrime
+		v >>= 8
+	}
+}
+
+// Int64 writes v to the hash.
+func (h *Hasher) Int64(v int64) {
+	h.Uint64(uint64(v))
+}
+
+// Float64 writes v to the hash.
+func (h *Hasher) Float64(v float64) {
+	if v == 0 {
+		v = 0 // -0 == 0
+	}
+	h.Uint64(math.Float64bits(v))
+}
+
+// Bool writes v to the hash.
+func (h *Hasher) Bool(v bool) {
+	if v {
+		h.Uint64(1)
+	} else {
+		h.Uint64(0)
+	}
+}
+
+// String writes s to the hash.
+func (h *Hasher) String(s string) {
+	h.Uint64(uint64(len(s)))
+	for i := 0; i < len(s); i++ {
+		h.sum ^= uint64(s[i])
+		h.sum *= fnvPrime
+	}
+}
+
+// Value hashes v by reflection.
+func (h *Hasher) Value(v any) {
+	HashValue(h, reflect.ValueOf(v))
+}
+
+var (
+	customHashFuncs = make(map[reflect.Type]reflect.Value)
+	muHash          sync.RWMutex
+)
+
+// RegisterCustomHash registers a custom hash function for a specific type.
+// The function must be of type func(T) uint64.
+func RegisterCustomHash(typ reflect.Type, fn reflect.Value) {
+	muHash.Lock()
+	defer muHash.Unlock()
+	customHashFuncs[typ] = fn
+}
+
+var hashMethodType = reflect.TypeOf((*interface{ Hash() uint64 })(nil)).Elem()
+
+// Hash returns the structural hash of v.
+func Hash[T any](v T) uint64 {
+	h := NewHasher()
+	HashValue(&h, reflect.ValueOf(&v).Elem())
+	return h.Sum()
+}
+
+// HashValue writes the structural hash of v to h.
+func HashValue(h *Hasher, v reflect.Value) {
+	hashRecursive(h, v, make(map[uintptr]bool))
+}
+
+func hashRecursive(h *Hasher, v reflect.Value, visited map[uintptr]bool) {
+	if !v.IsValid() {
+		h.Uint64(0)
+		return
+	}
+	typ := v.Type()
+
+	muHash.RLock()
+	fn, ok := customHashFuncs[typ]
+	muHash.RUnlock()
+	if ok {
+		h.Uint64(fn.Call([]reflect.Value{v})[0].Uint())
+		return
+	}
+	// A custom equality function may consider any two values equal, so
+	// values of its type contribute nothing beyond their presence.
+	muEqual.RLock()
+	_, ok = customEqualFuncs[typ]
+	muEqual.RUnlock()
+	if ok {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		h.Bool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		h.Int64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		h.Uint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		h.Float64(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		h.Float64(real(c))
+		h.Float64(imag(c))
+	case reflect.String:
+		h.String(v.String())
+
+	case reflect.Pointer:
+		if v.IsNil() {
+			h.Bool(false)
+			return
+		}
+		h.Bool(true)
+		if visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+		defer delete(visited, v.Pointer())
+		hashRecursive(h, v.Elem(), visited)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			h.Bool(false)
+			return
+		}
+		h.Bool(true)
+		h.String(v.Elem().Type().String())
+		hashRecursive(h, v.Elem(), visited)
+
+	case reflect.Struct:
+		if reflect.PointerTo(typ).Implements(hashMethodType) {
+			if !v.CanInterface() {
+				unsafe.DisableRO(&v)
+			}
+			if !v.CanAddr() {
+				c := reflect.New(typ).Elem()
+				c.Set(v)
+				v = c
+			}
+			h.Uint64(v.Addr().Interface().(interface{ Hash() uint64 }).Hash())
+			return
+		}
+		for _, f := range GetTypeInfo(typ).Fields {
+			if f.Tag.Ignore {
+				continue
+			}
+			fv := v.Field(f.Index)
+			if !fv.CanInterface() {
+				unsafe.DisableRO(&fv)
+			}
+			hashRecursive(h, fv, visited)
+		}
+
+	case reflect.Slice, reflect.Array:
+		h.Uint64(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			hashRecursive(h, v.Index(i), visited)
+		}
+
+	case reflect.Map:
+		h.Uint64(uint64(v.Len()))
+		var sum uint64
+		iter := v.MapRange()
+		for iter.Next() {
+			e := NewHasher()
+			hashRecursive(&e, iter.Key(), visited)
+			hashRecursive(&e, iter.Value(), visited)
+			sum += e.Sum()
+		}
+		h.Uint64(sum)
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		h.Uint64(uint64(v.Pointer()))
+	}
+}
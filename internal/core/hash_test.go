@@ -0,0 +1,74 @@
+package core
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+type hashNode struct {
+	Name     string
+	Tags     map[string]int
+	Next     *hashNode
+	Skip     int `deep:"-"`
+	hidden   float64
+	Children []any
+}
+
+func TestHashConsistentWithEqual(t *testing.T) {
+	tags := map[string]int{}
+	for i := 0; i < 50; i++ {
+		tags[string(rune('a'+i%26))+string(rune('a'+i/26))] = i
+	}
+	a := hashNode{Name: "n", Tags: tags, Skip: 1, hidden: 0, Children: []any{1, "x", nil}}
+	b := a
+	b.Tags = make(map[string]int)
+	for k, v := range tags {
+		b.Tags[k] = v
+	}
+	b.Skip = 2
+	b.hidden = math.Copysign(0, -1)
+
+	if !Equal(a, b) {
+		t.Fatal("values should be equal")
+	}
+	if Hash(a) != Hash(b) {
+		t.Error("equal values hash differently")
+	}
+
+	b.Children = []any{int64(1), "x", nil}
+	if Equal(a, b) || Hash(a) == Hash(b) {
+		t.Error("values with different dynamic types should differ")
+	}
+
+	// Cycles terminate.
+	a.Next = &a
+	Hash(a)
+}
+
+func TestHashCustom(t *testing.T) {
+	type caseless string
+	typ := reflect.TypeOf(caseless(""))
+	RegisterCustomEqual(typ, reflect.ValueOf(func(a, b caseless) bool {
+		return len(a) == len(b)
+	}))
+	defer func() {
+		muEqual.Lock()
+		delete(customEqualFuncs, typ)
+		muEqual.Unlock()
+	}()
+
+	if Hash(caseless("ab")) != Hash(caseless("cd")) {
+		t.Error("values equal under a custom equality must hash alike")
+	}
+
+	RegisterCustomHash(typ, reflect.ValueOf(func(a caseless) uint64 { return uint64(len(a)) }))
+	defer func() {
+		muHash.Lock()
+		delete(customHashFuncs, typ)
+		muHash.Unlock()
+	}()
+	if Hash(caseless("ab")) == Hash(caseless("abc")) {
+		t.Error("custom hash was not used")
+	}
+}
@@ -1,8 +1,10 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -201,6 +203,12 @@ func setAtPath(v reflect.Value, parts []PathPart, val reflect.Value) error {
 			return err
 		}
 		if len(rest) == 0 {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return fmt.Errorf("cannot add key %v to nil map", part.Key)
+				}
+				v.Set(reflect.MakeMap(v.Type()))
+			}
 			v.SetMapIndex(keyVal, ConvertValue(val, v.Type().Elem()))
 			return nil
 		}
@@ -491,6 +499,13 @@ func CompareValues(v1, v2 reflect.Value, op string, ignoreCase bool) (bool, erro
 		return false, fmt.Errorf("type mismatch: %v and %v", v1.Type(), v2.Type())
 	}
 
+	// Types such as time.Time order themselves with a Compare method.
+	if m := v1.MethodByName("Compare"); m.IsValid() && v2.Type() == v1.Type() {
+		if mt := m.Type(); mt.NumIn() == 1 && mt.In(0) == v1.Type() && mt.NumOut() == 1 && mt.Out(0).Kind() == reflect.Int {
+			return compareOrdered(m.Call([]reflect.Value{v2})[0].Int(), 0, op)
+		}
+	}
+
 	switch v1.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return compareOrdered(v1.Int(), v2.Int(), op)
@@ -638,3 +653,230 @@ func findSliceElemByKey(s reflect.Value, keyIdx int, keyStr string) (reflect.Val
 	}
 	return reflect.Value{}, false
 }
+
+// CanonicalPath rewrites path so that every struct field segment uses the Go
+// field name rather than its json tag, following typ as it descends. Segments
+// below a map, slice, or array are kept as-is. Segments that cannot be matched
+// against typ are copied through unchanged.
+func CanonicalPath(typ reflect.Type, path string) string {
+	parts := ParsePath(path)
+	if len(parts) == 0 {
+		return "/"
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		for typ != nil && typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		key := part.Key
+		b.WriteByte('/')
+		if typ == nil {
+			b.WriteString(EscapeKey(key))
+			continue
+		}
+		switch typ.Kind() {
+		case reflect.Struct:
+			var next reflect.Type
+			for _, fInfo := range GetTypeInfo(typ).Fields {
+				if fInfo.Name == key || (fInfo.JSONTag != "" && fInfo.JSONTag == key) {
+					key = fInfo.Name
+					next = typ.Field(fInfo.Index).Type
+					break
+				}
+			}
+			typ = next
+		case reflect.Map, reflect.Slice, reflect.Array:
+			typ = typ.Elem()
+		default:
+			typ = nil
+		}
+		b.WriteString(EscapeKey(key))
+	}
+	return b.String()
+}
+
+// ErrInterfacePath is wrapped by the errors of TypeAtPath and FieldsAlongPath
+// when path continues below an interface-typed value, whose dynamic type is
+// not known statically.
+var ErrInterfacePath = errors.New("cannot resolve path below interface")
+
+// TypeAtPath returns the static type of the value addressed by path within a
+// value of type typ. Pointers are followed transparently while descending, but
+// the returned type is the declared type at path. Interface-typed values cannot
+// be traversed statically and produce an error wrapping ErrInterfacePath.
+func TypeAtPath(typ reflect.Type, path string) (reflect.Type, error) {
+	return walkTypePath(typ, path, nil)
+}
+
+// FieldsAlongPath returns the struct fields traversed by path within a value
+// of type typ, outermost first, so that their tags can be inspected.
+func FieldsAlongPath(typ reflect.Type, path string) ([]FieldInfo, error) {
+	var fields []FieldInfo
+	_, err := walkTypePath(typ, path, func(f FieldInfo) { fields = append(fields, f) })
+	return fields, err
+}
+
+func walkTypePath(typ reflect.Type, path string, visit func(FieldInfo)) (reflect.Type, error) {
+	for _, part := range ParsePath(path) {
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		switch typ.Kind() {
+		case reflect.Struct:
+			var next reflect.Type
+			for _, fInfo := range GetTypeInfo(typ).Fields {
+				if fInfo.Name == part.Key || (fInfo.JSONTag != "" && fInfo.JSONTag == part.Key) {
+					next = typ.Field(fInfo.Index).Type
+					if visit != nil {
+						visit(fInfo)
+					}
+					break
+				}
+			}
+			if next == nil {
+				return nil, fmt.Errorf("field %s not found in %v", part.Key, typ)
+			}
+			typ = next
+		case reflect.Slice:
+			if isWildcard(part) {
+				typ = typ.Elem()
+				continue
+			}
+			keyIdx, keyed := sliceKeyField(typ)
+			if !keyed && !part.IsIndex {
+				return nil, fmt.Errorf("non-numeric index %q for non-keyed slice", part.Key)
+			}
+			if keyed {
+				elem := typ.Elem()
+				for elem.Kind() == reflect.Pointer {
+					elem = elem.Elem()
+				}
+				keyType := elem.Field(keyIdx).Type
+				switch keyType.Kind() {
+				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+					if _, err := makeMapKey(keyType, part); err != nil {
+						return nil, fmt.Errorf("invalid key for keyed slice %v: %w", typ, err)
+					}
+				}
+			}
+			typ = typ.Elem()
+		case reflect.Array:
+			if isWildcard(part) {
+				typ = typ.Elem()
+				continue
+			}
+			if !part.IsIndex {
+				return nil, fmt.Errorf("non-numeric index %q for array", part.Key)
+			}
+			if part.Index >= typ.Len() {
+				return nil, fmt.Errorf("index out of bounds: %d", part.Index)
+			}
+			typ = typ.Elem()
+		case reflect.Map:
+			if !isWildcard(part) {
+				if _, err := makeMapKey(typ.Key(), part); err != nil {
+					return nil, err
+				}
+			}
+			typ = typ.Elem()
+		case reflect.Interface:
+			return nil, fmt.Errorf("%w %v at %q", ErrInterfacePath, typ, part.Key)
+		default:
+			return nil, fmt.Errorf("cannot navigate into %v at %q", typ, part.Key)
+		}
+	}
+	return typ, nil
+}
+
+// IsKeyedSlice reports whether typ is a slice whose element type carries a
+// deep:"key" field.
+func IsKeyedSlice(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Slice {
+		return false
+	}
+	_, ok := sliceKeyField(typ)
+	return ok
+}
+
+// Wildcard is the path segment that matches every element of a slice or
+// array and every value of a map.
+const Wildcard = "*"
+
+// WildcardPrefix returns the part of path up to and including its first
+// wildcard segment, and whether path has one.
+func WildcardPrefix(path string) (string, bool) {
+	parts := ParsePath(path)
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteByte('/')
+		if part.IsIndex {
+			b.WriteString(strconv.Itoa(part.Index))
+		} else {
+			b.WriteString(EscapeKey(part.Key))
+		}
+		if isWildcard(part) {
+			return b.String(), true
+		}
+	}
+	return "", false
+}
+
+func isWildcard(part PathPart) bool {
+	return !part.IsIndex && part.Key == Wildcard
+}
+
+// Expand returns the concrete paths matched by p within v, replacing each
+// wildcard segment with the index, key-field value or map key of every
+// element of the collection it addresses. Map keys are sorted. A path without
+// wildcards expands to itself, and a wildcard below a nil collection matches
+// nothing.
+func (p DeepPath) Expand(v reflect.Value) ([]string, error) {
+	prefix, ok := WildcardPrefix(string(p))
+	if !ok {
+		return []string{string(p)}, nil
+	}
+	parent := prefix[:len(prefix)-len(Wildcard)-1]
+	rest := NormalizePath(string(p))[len(prefix):]
+
+	coll, _, err := p.Navigate(v, ParsePath(parent))
+	if err != nil {
+		return nil, err
+	}
+	if !coll.IsValid() {
+		return nil, nil
+	}
+
+	var keys []string
+	switch coll.Kind() {
+	case reflect.Slice, reflect.Array:
+		keyIdx, keyed := -1, false
+		if coll.Kind() == reflect.Slice {
+			keyIdx, keyed = sliceKeyField(coll.Type())
+		}
+		for i := 0; i < coll.Len(); i++ {
+			if keyed {
+				keys = append(keys, EscapeKey(keyFieldStr(coll.Index(i), keyIdx)))
+			} else {
+				keys = append(keys, strconv.Itoa(i))
+			}
+		}
+	case reflect.Map:
+		for _, k := range coll.MapKeys() {
+			keys = append(keys, EscapeKey(fmt.Sprint(k.Interface())))
+		}
+		sort.Strings(keys)
+	default:
+		return nil, fmt.Errorf("wildcard on %v at %q", coll.Type(), parent)
+	}
+
+	var res []string
+	for _, k := range keys {
+		matches, err := DeepPath(JoinPath(parent, k) + rest).Expand(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, matches...)
+	}
+	return res, nil
+}
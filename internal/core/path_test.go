@@ -168,3 +168,116 @@ func TestDeepPath_ResolveParentPath(t *testing.T) {
 		}
 	}
 }
+
+func TestCanonicalPath(t *testing.T) {
+	type inner struct {
+		Addr string `json:"addr"`
+	}
+	type outer struct {
+		Name  string           `json:"full_name"`
+		Info  *inner           `json:"info"`
+		Items map[string]inner `json:"items"`
+		List  []inner
+	}
+	typ := reflect.TypeOf(outer{})
+
+	for _, tc := range []struct{ in, want string }{
+		{"/", "/"},
+		{"/full_name", "/Name"},
+		{"/Name", "/Name"},
+		{"/info/addr", "/Info/Addr"},
+		{"/items/addr/addr", "/Items/addr/Addr"},
+		{"/List/0/addr", "/List/0/Addr"},
+		{"/missing/addr", "/missing/addr"},
+	} {
+		if got := CanonicalPath(typ, tc.in); got != tc.want {
+			t.Errorf("CanonicalPath(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestTypeAtPath(t *testing.T) {
+	type outer struct {
+		Name  string            `json:"name"`
+		Items []keyedItem       `json:"items"`
+		Tags  []string          `json:"tags"`
+		M     map[int]*mapInner `json:"m"`
+		Arr   [2]int
+	}
+	typ := reflect.TypeOf(&outer{})
+
+	for _, tc := range []struct {
+		path string
+		want reflect.Type
+	}{
+		{"/", typ},
+		{"/name", reflect.TypeOf("")},
+		{"/items/todo/Value", reflect.TypeOf(0)},
+		{"/tags/3", reflect.TypeOf("")},
+		{"/m/7", reflect.TypeOf(&mapInner{})},
+		{"/m/7/X", reflect.TypeOf(0)},
+		{"/Arr/1", reflect.TypeOf(0)},
+	} {
+		got, err := TypeAtPath(typ, tc.path)
+		if err != nil {
+			t.Errorf("TypeAtPath(%q): %v", tc.path, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("TypeAtPath(%q) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"/missing", "/tags/x", "/m/x", "/Arr/5", "/name/x"} {
+		if _, err := TypeAtPath(typ, bad); err == nil {
+			t.Errorf("TypeAtPath(%q): expected error", bad)
+		}
+	}
+
+	if !IsKeyedSlice(reflect.TypeOf([]keyedItem{})) || IsKeyedSlice(reflect.TypeOf([]string{})) {
+		t.Error("IsKeyedSlice mismatch")
+	}
+}
+
+func TestDeepPath_Expand(t *testing.T) {
+	type line struct {
+		SKU string `deep:"key"`
+		Qty int
+	}
+	type order struct {
+		Lines []line
+		Tags  map[string][]int
+		Notes []string
+	}
+	o := order{
+		Lines: []line{{SKU: "a/b", Qty: 1}, {SKU: "c", Qty: 2}},
+		Tags:  map[string][]int{"y": {1}, "x": {2, 3}},
+	}
+	v := reflect.ValueOf(o)
+
+	for _, tc := range []struct {
+		path string
+		want []string
+	}{
+		{"/Lines/*/Qty", []string{"/Lines/a~1b/Qty", "/Lines/c/Qty"}},
+		{"/Tags/*/*", []string{"/Tags/x/0", "/Tags/x/1", "/Tags/y/0"}},
+		{"/Notes/*", nil},
+		{"/Lines/c/Qty", []string{"/Lines/c/Qty"}},
+	} {
+		got, err := DeepPath(tc.path).Expand(v)
+		if err != nil {
+			t.Errorf("Expand(%s): %v", tc.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Expand(%s) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+
+	if _, err := DeepPath("/Lines/c/Qty/*").Expand(v); err == nil {
+		t.Error("expected error for wildcard on int")
+	}
+	if typ, err := TypeAtPath(reflect.TypeOf(o), "/Tags/*/*"); err != nil || typ.Kind() != reflect.Int {
+		t.Errorf("TypeAtPath = %v, %v", typ, err)
+	}
+}
@@ -1,14 +1,20 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"reflect"
+	"strconv"
 
 	"github.com/brunoga/deep/v5/condition"
 	icore "github.com/brunoga/deep/v5/internal/core"
 )
 
+// ErrTestFailed is wrapped by the error of an OpTest operation whose value
+// does not match.
+var ErrTestFailed = errors.New("test failed")
+
 // ApplyOpReflection applies a single operation to target using reflection.
 // It is called by generated Patch methods for operations the generated fast-path does not handle
 // (e.g. slice index or map key paths). Direct use is not intended.
@@ -19,6 +25,13 @@ func ApplyOpReflection[T any](target *T, op Operation, logger *slog.Logger) erro
 	return ApplyOpReflectionValue(reflect.ValueOf(target).Elem(), op, logger)
 }
 
+// EvaluateConditionReflection evaluates c against target using reflection.
+// It is called by generated code for condition paths the generated fast-path
+// does not handle (e.g. paths into nested structs). Direct use is not intended.
+func EvaluateConditionReflection[T any](target *T, c condition.Condition) (bool, error) {
+	return condition.Evaluate(reflect.ValueOf(target).Elem(), &c)
+}
+
 // ApplyOpReflectionValue applies op to the already-reflected value v.
 func ApplyOpReflectionValue(v reflect.Value, op Operation, logger *slog.Logger) error {
 	// Strict check.
@@ -55,7 +68,7 @@ func ApplyOpReflectionValue(v reflect.Value, op Operation, logger *slog.Logger)
 					if fInfo.Tag.Ignore {
 						return nil
 					}
-					if fInfo.Tag.ReadOnly && op.Kind != OpLog {
+					if fInfo.Tag.ReadOnly && op.Kind != OpLog && op.Kind != OpTest {
 						return fmt.Errorf("field %s is read-only", op.Path)
 					}
 					break
@@ -90,9 +103,99 @@ func ApplyOpReflectionValue(v reflect.Value, op Operation, logger *slog.Logger)
 		}
 	case OpLog:
 		logger.Info("deep log", "message", op.New, "path", op.Path)
+	case OpTest:
+		return testValue(v, op.Path, op.New)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to apply %s at %s: %w", op.Kind, op.Path, err)
 	}
 	return nil
 }
+
+// testValue checks that the value at path in v equals want. A missing path
+// fails the test, as in RFC 6902. Numbers are compared by value, so a float64
+// decoded from JSON matches an integer field holding the same number.
+func testValue(v reflect.Value, path string, want any) error {
+	cur, err := icore.DeepPath(path).Resolve(v)
+	if err != nil && want == nil && nilAt(v, path) {
+		return nil
+	}
+	if err != nil || !cur.IsValid() {
+		return fmt.Errorf("%w at %s: no value", ErrTestFailed, path)
+	}
+	if !testEqual(cur, want) {
+		return fmt.Errorf("%w at %s: expected %v, got %v", ErrTestFailed, path, want, cur.Interface())
+	}
+	return nil
+}
+
+func testEqual(cur reflect.Value, want any) bool {
+	if cur.Kind() == reflect.Interface && !cur.IsNil() {
+		cur = cur.Elem()
+	}
+	if want == nil {
+		switch cur.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+			return cur.IsNil()
+		}
+		return false
+	}
+	w := reflect.ValueOf(want)
+	if n, ok := number(cur); ok {
+		if m, ok := number(w); ok {
+			return n == m
+		}
+		return false
+	}
+	ok, err := icore.CompareValues(cur, w, "==", false)
+	return err == nil && ok
+}
+
+// nilAt reports whether path names a nil pointer or interface, which
+// resolving path cannot dereference.
+func nilAt(v reflect.Value, path string) bool {
+	parent, last, err := icore.DeepPath(path).ResolveParent(v)
+	if err != nil {
+		return false
+	}
+	key := last.Key
+	if key == "" && last.IsIndex {
+		key = strconv.Itoa(last.Index)
+	}
+	var child reflect.Value
+	switch parent.Kind() {
+	case reflect.Struct:
+		for _, f := range icore.GetTypeInfo(parent.Type()).Fields {
+			if f.Name == key || (f.JSONTag != "" && f.JSONTag == key) {
+				child = parent.Field(f.Index)
+				break
+			}
+		}
+	case reflect.Map:
+		if parent.Type().Key().Kind() == reflect.String {
+			child = parent.MapIndex(reflect.ValueOf(key).Convert(parent.Type().Key()))
+		}
+	case reflect.Slice, reflect.Array:
+		if last.IsIndex && last.Index >= 0 && last.Index < parent.Len() {
+			child = parent.Index(last.Index)
+		}
+	}
+	if !child.IsValid() {
+		return false
+	}
+	k := child.Kind()
+	return (k == reflect.Pointer || k == reflect.Interface) && child.IsNil()
+}
+
+// number returns the value of a numeric v as a float64.
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
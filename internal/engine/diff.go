@@ -17,6 +17,7 @@ import (
 type diffConfig struct {
 	ignoredPaths map[string]bool
 	detectMoves  bool
+	limits       *DiffLimits
 }
 
 type diffOptionFunc func(*diffConfig)
@@ -403,6 +404,61 @@ func (d *Differ) isValueAtTarget(path string, val any, ctx *diffContext) bool {
 }
 
 func (d *Differ) diffRecursive(a, b reflect.Value, atomic bool, ctx *diffContext) (diffPatch, error) {
+	l := d.config.limits
+	if l == nil {
+		return d.diffValue(a, b, atomic, ctx)
+	}
+	if err := l.ctx.Err(); err != nil {
+		return nil, d.limitError(err, ctx)
+	}
+	// Below the maximum depth, values are compared as a whole unless they
+	// contain an ignored path.
+	if !l.expand(len(ctx.pathStack)) && !d.ignoresBelow(ctx.buildPath()) {
+		atomic = true
+	}
+	p, err := d.diffValue(a, b, atomic, ctx)
+	if err != nil || p == nil {
+		return p, err
+	}
+	switch p.(type) {
+	case *valuePatch, *movePatch, *copyPatch, *customDiffPatch:
+		if err := d.countOps(1, ctx); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// ignoresBelow reports whether an ignored path lies strictly below path.
+func (d *Differ) ignoresBelow(path string) bool {
+	prefix := path
+	if prefix != "/" {
+		prefix += "/"
+	}
+	for ig := range d.config.ignoredPaths {
+		if len(ig) > len(prefix) && strings.HasPrefix(ig, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// countOps records n operations produced at the current path against the
+// limits of the diff, if any.
+func (d *Differ) countOps(n int, ctx *diffContext) error {
+	if l := d.config.limits; l != nil && n > 0 {
+		if err := l.count(n); err != nil {
+			return d.limitError(err, ctx)
+		}
+	}
+	return nil
+}
+
+func (d *Differ) limitError(err error, ctx *diffContext) error {
+	return &DiffLimitError{Path: ctx.buildPath(), Err: err}
+}
+
+func (d *Differ) diffValue(a, b reflect.Value, atomic bool, ctx *diffContext) (diffPatch, error) {
 	if len(d.config.ignoredPaths) > 0 {
 		if d.config.ignoredPaths[ctx.buildPath()] {
 			return nil, nil
@@ -686,6 +742,7 @@ func (d *Differ) diffMap(a, b reflect.Value, ctx *diffContext) (diffPatch, error
 	var removed map[any]reflect.Value
 	var modified map[any]diffPatch
 	var originalKeys map[any]any
+	var moves int
 
 	getCanonical := func(v reflect.Value) any {
 		if v.CanInterface() {
@@ -757,6 +814,7 @@ func (d *Differ) diffMap(a, b reflect.Value, ctx *diffContext) (diffPatch, error
 				} else {
 					modified[ck] = &copyPatch{from: fromPath}
 				}
+				moves++
 				delete(bByCanonical, ck)
 				continue
 			}
@@ -770,6 +828,9 @@ func (d *Differ) diffMap(a, b reflect.Value, ctx *diffContext) (diffPatch, error
 	if added == nil && removed == nil && modified == nil {
 		return nil, nil
 	}
+	if err := d.countOps(len(added)+len(removed)+moves, ctx); err != nil {
+		return nil, err
+	}
 
 	mp := newMapPatch(b.Type().Key())
 	for k, v := range added {
@@ -868,7 +929,7 @@ func (d *Differ) diffSlice(a, b reflect.Value, ctx *diffContext) (diffPatch, err
 
 				op := sliceOp{
 					Kind:    OpCopy,
-					Index:   i,
+					Index:   midAStart,
 					Patch:   p,
 					PrevKey: prevKey,
 				}
@@ -881,7 +942,7 @@ func (d *Differ) diffSlice(a, b reflect.Value, ctx *diffContext) (diffPatch, err
 
 			op := sliceOp{
 				Kind:    OpAdd,
-				Index:   i,
+				Index:   midAStart,
 				Val:     icore.DeepCopyValue(b.Index(i)),
 				PrevKey: prevKey,
 			}
@@ -890,12 +951,12 @@ func (d *Differ) diffSlice(a, b reflect.Value, ctx *diffContext) (diffPatch, err
 			}
 			ops = append(ops, op)
 		}
-		return &slicePatch{ops: ops}, nil
+		return d.newSlicePatch(ops, a, b, ctx)
 	}
 
 	if midBStart == midBEnd && midAStart < midAEnd {
 		var ops []sliceOp
-		for i := midAEnd - 1; i >= midAStart; i-- {
+		for i := midAStart; i < midAEnd; i++ {
 			currentPath := icore.JoinPath(ctx.buildPath(), strconv.Itoa(i))
 			if ctx.movedPaths[currentPath] {
 				continue
@@ -910,7 +971,7 @@ func (d *Differ) diffSlice(a, b reflect.Value, ctx *diffContext) (diffPatch, err
 			}
 			ops = append(ops, op)
 		}
-		return &slicePatch{ops: ops}, nil
+		return d.newSlicePatch(ops, a, b, ctx)
 	}
 
 	if midAStart >= midAEnd && midBStart >= midBEnd {
@@ -922,7 +983,23 @@ func (d *Differ) diffSlice(a, b reflect.Value, ctx *diffContext) (diffPatch, err
 		return nil, err
 	}
 
-	return &slicePatch{ops: ops}, nil
+	return d.newSlicePatch(ops, a, b, ctx)
+}
+
+// newSlicePatch counts the insertions, removals and moves in ops, whose
+// replacements have already been counted, and wraps them in a slicePatch
+// from a to b.
+func (d *Differ) newSlicePatch(ops []sliceOp, a, b reflect.Value, ctx *diffContext) (diffPatch, error) {
+	n := 0
+	for _, op := range ops {
+		if op.Kind != OpReplace {
+			n++
+		}
+	}
+	if err := d.countOps(n, ctx); err != nil {
+		return nil, err
+	}
+	return &slicePatch{ops: ops, from: a, to: b}, nil
 }
 
 func (d *Differ) computeSliceEdits(a, b reflect.Value, aStart, aEnd, bStart, bEnd, keyField int, hasKey bool, ctx *diffContext) ([]sliceOp, error) {
@@ -951,6 +1028,16 @@ func (d *Differ) computeSliceEdits(a, b reflect.Value, aStart, aEnd, bStart, bEn
 	trace := [][]int{}
 
 	for dStep := 0; dStep <= max; dStep++ {
+		// Every step adds at least one insertion or removal: stop as soon as
+		// the result cannot fit in the remaining operation budget.
+		if l := d.config.limits; l != nil {
+			if err := l.ctx.Err(); err != nil {
+				return nil, d.limitError(err, ctx)
+			}
+			if budget := l.budget(); budget >= 0 && dStep > budget {
+				return nil, d.limitError(ErrMaxOps, ctx)
+			}
+		}
 		vc := make([]int, 2*max+1)
 		copy(vc, v)
 		trace = append(trace, vc)
@@ -0,0 +1,19 @@
+package engine
+
+import (
+	icore "github.com/brunoga/deep/v5/internal/core"
+)
+
+// Hasher accumulates a structural hash. It is used by generated Hash methods.
+type Hasher = icore.Hasher
+
+// NewHasher returns an empty Hasher.
+func NewHasher() Hasher {
+	return icore.NewHasher()
+}
+
+// Hash returns a structural hash of v that is the same for values Equal
+// considers equal. It supports cyclic references and unexported fields.
+func Hash[T any](v T) uint64 {
+	return icore.Hash(v)
+}
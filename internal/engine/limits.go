@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	icore "github.com/brunoga/deep/v5/internal/core"
+)
+
+// ErrMaxOps is wrapped by the DiffLimitError returned when a bounded diff
+// would produce more operations than allowed.
+var ErrMaxOps = errors.New("diff exceeds the maximum number of operations")
+
+// DiffLimitError reports that a bounded diff stopped before completing.
+type DiffLimitError struct {
+	// Path is the value being compared when the diff stopped.
+	Path string
+	// Err is ErrMaxOps or the error of the diff's context.
+	Err error
+}
+
+func (e *DiffLimitError) Error() string {
+	return fmt.Sprintf("diff stopped at %s: %v", e.Path, e.Err)
+}
+
+func (e *DiffLimitError) Unwrap() error {
+	return e.Err
+}
+
+// DiffLimits tracks the limits of a single bounded diff: cancellation through
+// a context, a maximum depth below which differing values are replaced as a
+// whole, a maximum number of operations, and the paths left out of the diff.
+// It is used by the reflection engine and by generated Diff methods; direct
+// use is not intended.
+//
+// All methods accept a nil receiver, which imposes no limit.
+type DiffLimits struct {
+	ctx      context.Context
+	maxDepth int // < 0 means unlimited
+	maxOps   int // <= 0 means unlimited
+	ops      int
+	path     []string
+
+	// typ is the type of the root value, against which the current path is
+	// resolved to the canonical form of ignored.
+	typ     reflect.Type
+	ignored []string
+}
+
+// NewDiffLimits returns limits for one diff, or nil if there is nothing to
+// enforce.
+func NewDiffLimits(ctx context.Context, maxDepth, maxOps int) *DiffLimits {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ctx.Done() == nil && maxDepth < 0 && maxOps <= 0 {
+		return nil
+	}
+	return &DiffLimits{ctx: ctx, maxDepth: maxDepth, maxOps: maxOps}
+}
+
+// WithIgnored returns limits that also leave out the values at ignored, which
+// are canonical paths (see icore.CanonicalPath) within a value of type typ.
+// It allocates new limits if l is nil.
+func (l *DiffLimits) WithIgnored(typ reflect.Type, ignored []string) *DiffLimits {
+	if len(ignored) == 0 {
+		return l
+	}
+	if l == nil {
+		l = &DiffLimits{ctx: context.Background(), maxDepth: -1}
+	}
+	l.typ, l.ignored = typ, ignored
+	return l
+}
+
+// Ignored reports whether the value at segment name below the current value
+// is left out of the diff. Callers skip it entirely, so that it is neither
+// compared nor counted.
+func (l *DiffLimits) Ignored(name string) bool {
+	if l == nil || len(l.ignored) == 0 {
+		return false
+	}
+	path := icore.CanonicalPath(l.typ, l.childPath(name))
+	for _, ig := range l.ignored {
+		if ig == "/" || path == ig || strings.HasPrefix(path, ig+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoresBelow reports whether a value below the current value is left out
+// of the diff, so that its elements must be checked with Ignored.
+func (l *DiffLimits) IgnoresBelow() bool {
+	if l == nil || len(l.ignored) == 0 {
+		return false
+	}
+	return l.ignoresBelow(l.currentPath())
+}
+
+// ignoresBelow reports whether an ignored path lies strictly below path.
+func (l *DiffLimits) ignoresBelow(path string) bool {
+	prefix := icore.CanonicalPath(l.typ, path)
+	if prefix != "/" {
+		prefix += "/"
+	}
+	for _, ig := range l.ignored {
+		if len(ig) > len(prefix) && strings.HasPrefix(ig, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns a *DiffLimitError if the diff's context is done.
+func (l *DiffLimits) Check() error {
+	if l == nil {
+		return nil
+	}
+	if err := l.ctx.Err(); err != nil {
+		return &DiffLimitError{Path: l.currentPath(), Err: err}
+	}
+	return nil
+}
+
+// Descend reports whether the value at segment name below the current value
+// may be compared element by element and, if so, makes it the current value
+// until the matching call to Ascend. Otherwise the caller compares the value
+// as a whole. Values that contain an ignored path are always compared element
+// by element, so that the ignored value is left out.
+func (l *DiffLimits) Descend(name string) bool {
+	if l == nil {
+		return true
+	}
+	if !l.expand(len(l.path)+1) && (len(l.ignored) == 0 || !l.ignoresBelow(l.childPath(name))) {
+		return false
+	}
+	l.path = append(l.path, name)
+	return true
+}
+
+// Ascend returns to the value that was current before the last successful
+// Descend.
+func (l *DiffLimits) Ascend() {
+	if l == nil || len(l.path) == 0 {
+		return
+	}
+	l.path = l.path[:len(l.path)-1]
+}
+
+// Count records n operations produced at the current value and returns a
+// *DiffLimitError if the maximum is exceeded or the context is done.
+func (l *DiffLimits) Count(n int) error {
+	if l == nil {
+		return nil
+	}
+	if err := l.count(n); err != nil {
+		return &DiffLimitError{Path: l.currentPath(), Err: err}
+	}
+	return nil
+}
+
+// count records n operations and returns ErrMaxOps or the context error if
+// the diff must stop.
+func (l *DiffLimits) count(n int) error {
+	l.ops += n
+	if l.maxOps > 0 && l.ops > l.maxOps {
+		return ErrMaxOps
+	}
+	return l.ctx.Err()
+}
+
+// expand reports whether a value at the given depth may be compared element
+// by element.
+func (l *DiffLimits) expand(depth int) bool {
+	return l.maxDepth < 0 || depth < l.maxDepth
+}
+
+// budget returns the number of operations that may still be produced, or -1
+// if unlimited.
+func (l *DiffLimits) budget() int {
+	if l.maxOps <= 0 {
+		return -1
+	}
+	return l.maxOps - l.ops
+}
+
+// childPath returns the path of the value at segment name below the current
+// value.
+func (l *DiffLimits) childPath(name string) string {
+	if len(l.path) == 0 {
+		return "/" + icore.EscapeKey(name)
+	}
+	return l.currentPath() + "/" + icore.EscapeKey(name)
+}
+
+func (l *DiffLimits) currentPath() string {
+	if len(l.path) == 0 {
+		return "/"
+	}
+	var b strings.Builder
+	for _, s := range l.path {
+		b.WriteByte('/')
+		b.WriteString(icore.EscapeKey(s))
+	}
+	return b.String()
+}
+
+// DiffWithLimits returns an option that makes Diff honor l.
+func DiffWithLimits(l *DiffLimits) DiffOption {
+	return diffOptionFunc(func(c *diffConfig) {
+		c.limits = l
+	})
+}
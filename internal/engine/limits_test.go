@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDiffLimits_SliceBudget(t *testing.T) {
+	a := make([]int, 100)
+	b := make([]int, 100)
+	for i := range b {
+		b[i] = i + 1000
+	}
+
+	l := NewDiffLimits(context.Background(), -1, 10)
+	_, err := Diff(a, b, DiffWithLimits(l))
+	var le *DiffLimitError
+	if !errors.As(err, &le) || !errors.Is(err, ErrMaxOps) {
+		t.Fatalf("expected DiffLimitError wrapping ErrMaxOps, got %v", err)
+	}
+
+	l = NewDiffLimits(context.Background(), -1, 1000)
+	if _, err := Diff(a, b, DiffWithLimits(l)); err != nil {
+		t.Fatalf("unexpected error within budget: %v", err)
+	}
+}
+
+func TestDiffLimits_Nil(t *testing.T) {
+	if l := NewDiffLimits(context.Background(), -1, 0); l != nil {
+		t.Fatalf("expected nil limits, got %+v", l)
+	}
+	var l *DiffLimits
+	if !l.Descend("x") || l.Check() != nil || l.Count(1<<20) != nil {
+		t.Error("nil limits must not restrict the diff")
+	}
+	l.Ascend()
+}
+
+func TestDiffLimits_Ignored(t *testing.T) {
+	type inner struct {
+		A int `json:"a"`
+		B int
+	}
+	type outer struct {
+		In   inner `json:"in"`
+		Keep inner
+	}
+	typ := reflect.TypeOf(outer{})
+
+	l := NewDiffLimits(context.Background(), 1, 0).WithIgnored(typ, []string{"/In/A"})
+	if l.Ignored("in") || !l.Descend("in") {
+		t.Fatal("a value containing an ignored path must be descended into")
+	}
+	if !l.Ignored("a") || l.Ignored("B") || !l.IgnoresBelow() {
+		t.Error("unexpected ignored values below /in")
+	}
+	l.Ascend()
+	if l.Descend("Keep") {
+		t.Error("values beyond the maximum depth without ignored paths must not be descended into")
+	}
+
+	var nl *DiffLimits
+	if nl.WithIgnored(typ, nil) != nil {
+		t.Error("no ignored paths must keep nil limits")
+	}
+	if nl = nl.WithIgnored(typ, []string{"/Keep"}); nl == nil || !nl.Ignored("Keep") || !nl.Descend("in") {
+		t.Error("ignored paths must be honored without other limits")
+	}
+}
@@ -68,3 +68,13 @@ func RegisterCustomEqual[T any](fn func(T, T) bool) {
 	typ := reflect.TypeOf(t)
 	icore.RegisterCustomEqual(typ, reflect.ValueOf(fn))
 }
+
+// RegisterCustomHash registers a custom hash function for a specific type. It
+// should be registered alongside a custom equality function so that values it
+// considers equal hash alike; without one, Hash ignores values of a type with
+// custom equality.
+func RegisterCustomHash[T any](fn func(T) uint64) {
+	var t T
+	typ := reflect.TypeOf(t)
+	icore.RegisterCustomHash(typ, reflect.ValueOf(fn))
+}
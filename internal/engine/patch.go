@@ -17,6 +17,7 @@ const (
 	OpMove
 	OpCopy
 	OpLog
+	OpTest
 )
 
 func (k OpKind) String() string {
@@ -33,6 +34,8 @@ func (k OpKind) String() string {
 		return "copy"
 	case OpLog:
 		return "log"
+	case OpTest:
+		return "test"
 	default:
 		return "unknown"
 	}
@@ -1065,8 +1065,11 @@ type ConflictResolver interface {
 }
 
 // slicePatch handles complex edits (insertions, deletions, modifications) in a slice.
+// Op indices refer to the original slice. from and to, when known, are the
+// slice before and after the edit.
 type slicePatch struct {
-	ops []sliceOp
+	ops      []sliceOp
+	from, to reflect.Value
 }
 
 func (p *slicePatch) apply(root, v reflect.Value, path string) {
@@ -1391,11 +1394,24 @@ func (p *slicePatch) reverse() diffPatch {
 		}
 	}
 	return &slicePatch{
-		ops: revOps,
+		ops:  revOps,
+		from: p.to,
+		to:   p.from,
 	}
 }
 
 func (p *slicePatch) walk(path string, fn func(path string, op OpKind, old, new any) error) error {
+	if p.to.IsValid() {
+		if _, keyed := icore.GetKeyField(p.to.Type().Elem()); !keyed {
+			return p.walkUnkeyed(path, fn)
+		}
+	}
+	return p.walkIndexed(path, fn)
+}
+
+// walkIndexed reports each operation of p at the index or key it was
+// recorded with.
+func (p *slicePatch) walkIndexed(path string, fn func(path string, op OpKind, old, new any) error) error {
 	for _, op := range p.ops {
 		fullPath := fmt.Sprintf("%s/%d", path, op.Index)
 		if op.Key != nil {
@@ -1435,6 +1451,107 @@ func (p *slicePatch) walk(path string, fn func(path string, op OpKind, old, new
 	return nil
 }
 
+// walkUnkeyed reports the edit of a non-keyed slice as operations that apply
+// in sequence, given that an add at an index below the length of a slice
+// overwrites the element there: removals from the highest index down, then
+// appends. An edit that inserts before a remaining element, or moves elements
+// within the slice, cannot be expressed that way and is reported position by
+// position instead.
+func (p *slicePatch) walkUnkeyed(path string, fn func(path string, op OpKind, old, new any) error) error {
+	var removes, adds []sliceOp
+	external := false
+	for _, op := range p.ops {
+		switch op.Kind {
+		case OpRemove:
+			removes = append(removes, op)
+		case OpAdd:
+			adds = append(adds, op)
+		case OpCopy:
+			// A copy or move from outside the slice keeps its source path.
+			if from := copySource(op); from != "" && !strings.HasPrefix(from, path+"/") {
+				external = true
+			}
+		}
+	}
+	if external {
+		return p.walkIndexed(path, fn)
+	}
+
+	// Check that removals and appends alone produce the new slice.
+	n := 0
+	if p.from.IsValid() {
+		n = p.from.Len()
+	}
+	removed := make(map[int]bool, len(removes))
+	for _, op := range removes {
+		removed[op.Index] = true
+	}
+	res := reflect.MakeSlice(p.to.Type(), 0, p.to.Len())
+	for i := 0; i < n; i++ {
+		if !removed[i] {
+			res = reflect.Append(res, p.from.Index(i))
+		}
+	}
+	kept := res.Len()
+	for _, op := range adds {
+		res = reflect.Append(res, icore.ConvertValue(op.Val, p.to.Type().Elem()))
+	}
+	if len(removes)+len(adds) != len(p.ops) || !icore.ValueEqual(res, p.to, nil) {
+		return p.walkPositional(path, fn)
+	}
+
+	for i := len(removes) - 1; i >= 0; i-- {
+		op := removes[i]
+		if err := fn(fmt.Sprintf("%s/%d", path, op.Index), OpRemove, icore.ValueToInterface(op.Val), nil); err != nil {
+			return err
+		}
+	}
+	for i, op := range adds {
+		if err := fn(fmt.Sprintf("%s/%d", path, kept+i), OpAdd, nil, icore.ValueToInterface(op.Val)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkPositional reports the edit of a non-keyed slice as replacements of the
+// elements that differ at each index, followed by appends or by removals from
+// the end.
+func (p *slicePatch) walkPositional(path string, fn func(path string, op OpKind, old, new any) error) error {
+	n, m := p.from.Len(), p.to.Len()
+	for i := 0; i < n && i < m; i++ {
+		a, b := p.from.Index(i), p.to.Index(i)
+		if icore.ValueEqual(a, b, nil) {
+			continue
+		}
+		if err := fn(fmt.Sprintf("%s/%d", path, i), OpReplace, icore.ValueToInterface(icore.DeepCopyValue(a)), icore.ValueToInterface(icore.DeepCopyValue(b))); err != nil {
+			return err
+		}
+	}
+	for i := n; i < m; i++ {
+		if err := fn(fmt.Sprintf("%s/%d", path, i), OpAdd, nil, icore.ValueToInterface(icore.DeepCopyValue(p.to.Index(i)))); err != nil {
+			return err
+		}
+	}
+	for i := n - 1; i >= m; i-- {
+		if err := fn(fmt.Sprintf("%s/%d", path, i), OpRemove, icore.ValueToInterface(icore.DeepCopyValue(p.from.Index(i))), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copySource returns the source path of a slice copy or move.
+func copySource(op sliceOp) string {
+	switch cp := op.Patch.(type) {
+	case *copyPatch:
+		return cp.from
+	case *movePatch:
+		return cp.from
+	}
+	return ""
+}
+
 func (p *slicePatch) format(indent int) string {
 	var b strings.Builder
 	b.WriteString("Slice{\n")
@@ -30,11 +30,14 @@ func (t *User) Patch(p deep.Patch[User], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -64,6 +67,9 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(User)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -77,6 +83,21 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/id", "/ID":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.ID != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.ID)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.ID) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.ID)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.ID)
 			return true, nil
@@ -104,6 +125,15 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 			return true, nil
 		}
 	case "/full_name", "/Name":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Name != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Name)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Name)
 			return true, nil
@@ -118,6 +148,9 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 			return true, nil
 		}
 	case "/info", "/Info":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Info)
 			return true, nil
@@ -132,6 +165,9 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 			return true, nil
 		}
 	case "/roles", "/Roles":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Roles)
 			return true, nil
@@ -146,6 +182,9 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 			return true, nil
 		}
 	case "/score", "/Score":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Score)
 			return true, nil
@@ -160,6 +199,9 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 			return true, nil
 		}
 	case "/bio", "/Bio":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Bio)
 			return true, nil
@@ -172,6 +214,21 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 		op.Path = "/"
 		return true, t.Bio.Patch(deep.Patch[crdt.Text]{Operations: []deep.Operation{op}}, logger)
 	case "/age":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.age != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.age)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.age) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.age)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.age)
 			return true, nil
@@ -206,6 +263,9 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 		if strings.HasPrefix(op.Path, "/score/") {
 			parts := strings.Split(op.Path[len("/score/"):], "/")
 			key := parts[0]
+			if op.Kind == deep.OpTest {
+				return false, nil
+			}
 			if op.Kind == deep.OpRemove {
 				delete(t.Score, key)
 				return true, nil
@@ -224,67 +284,150 @@ func (t *User) applyOperation(op deep.Operation, logger *slog.Logger) (bool, err
 
 // Diff compares t with other and returns a Patch.
 func (t *User) Diff(other *User) deep.Patch[User] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *User) DiffLimited(other *User, l *_deepengine.DiffLimits) (deep.Patch[User], error) {
 	p := deep.Patch[User]{}
-	if t.ID != other.ID {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/id", Old: t.ID, New: other.ID})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.Name != other.Name {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/full_name", Old: t.Name, New: other.Name})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("id") {
+		if t.ID != other.ID {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/id", Old: t.ID, New: other.ID})
+		}
 	}
-	subInfo := (&t.Info).Diff(&other.Info)
-	for _, op := range subInfo.Operations {
-		if op.Path == "" || op.Path == "/" {
-			op.Path = "/info"
-		} else {
-			op.Path = "/info" + op.Path
+	if !l.Ignored("full_name") {
+		if t.Name != other.Name {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/full_name", Old: t.Name, New: other.Name})
 		}
-		p.Operations = append(p.Operations, op)
 	}
-	if len(t.Roles) != len(other.Roles) {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/roles", Old: t.Roles, New: other.Roles})
-	} else {
-		for i := range t.Roles {
-			if t.Roles[i] != other.Roles[i] {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/roles/%d", i), Old: t.Roles[i], New: other.Roles[i]})
+	if !l.Ignored("info") {
+		if l.Descend("info") {
+			subInfo, err := (&t.Info).DiffLimited(&other.Info, l)
+			l.Ascend()
+			if err != nil {
+				return p, err
+			}
+			counted += len(subInfo.Operations)
+			for _, op := range subInfo.Operations {
+				if op.Path == "" || op.Path == "/" {
+					op.Path = "/info"
+				} else {
+					op.Path = "/info" + op.Path
+				}
+				p.Operations = append(p.Operations, op)
 			}
+		} else if !deep.Equal(t.Info, other.Info) {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/info", Old: t.Info, New: other.Info})
 		}
 	}
-	if other.Score != nil {
-		for k, v := range other.Score {
-			if t.Score == nil {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/score/%v", k), New: v})
-				continue
-			}
-			if oldV, ok := t.Score[k]; !ok || v != oldV {
-				kind := deep.OpReplace
-				if !ok {
-					kind = deep.OpAdd
+	if !l.Ignored("roles") {
+		if l.Descend("roles") {
+			skip := l.IgnoresBelow()
+			if len(t.Roles) != len(other.Roles) {
+				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/roles", Old: t.Roles, New: other.Roles})
+			} else {
+				for i := range t.Roles {
+					if skip && l.Ignored(fmt.Sprint(i)) {
+						continue
+					}
+					if t.Roles[i] != other.Roles[i] {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/roles/%d", i), Old: t.Roles[i], New: other.Roles[i]})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
 				}
-				p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/score/%v", k), Old: oldV, New: v})
 			}
+			l.Ascend()
+		} else if !deep.Equal(t.Roles, other.Roles) {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/roles", Old: t.Roles, New: other.Roles})
 		}
 	}
-	if t.Score != nil {
-		for k, v := range t.Score {
-			if other.Score == nil || !contains(other.Score, k) {
-				p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/score/%v", k), Old: v})
+	if !l.Ignored("score") {
+		if l.Descend("score") {
+			skip := l.IgnoresBelow()
+			if other.Score != nil {
+				for k, v := range other.Score {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if t.Score == nil {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: fmt.Sprintf("/score/%v", k), New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+						continue
+					}
+					if oldV, ok := t.Score[k]; !ok || v != oldV {
+						kind := deep.OpReplace
+						if !ok {
+							kind = deep.OpAdd
+						}
+						p.Operations = append(p.Operations, deep.Operation{Kind: kind, Path: fmt.Sprintf("/score/%v", k), Old: oldV, New: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
 			}
+			if t.Score != nil {
+				for k, v := range t.Score {
+					if skip && l.Ignored(fmt.Sprint(k)) {
+						continue
+					}
+					if other.Score == nil || !contains(other.Score, k) {
+						p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpRemove, Path: fmt.Sprintf("/score/%v", k), Old: v})
+						counted++
+						if err := l.Count(1); err != nil {
+							return p, err
+						}
+					}
+				}
+			}
+			l.Ascend()
+		} else if !deep.Equal(t.Score, other.Score) {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/score", Old: t.Score, New: other.Score})
 		}
 	}
-	subBio := (&t.Bio).Diff(other.Bio)
-	for _, op := range subBio.Operations {
-		if op.Path == "" || op.Path == "/" {
-			op.Path = "/bio"
-		} else {
-			op.Path = "/bio" + op.Path
+	if !l.Ignored("bio") {
+		if l.Descend("bio") {
+			subBio := (&t.Bio).Diff(other.Bio)
+			l.Ascend()
+			for _, op := range subBio.Operations {
+				if op.Path == "" || op.Path == "/" {
+					op.Path = "/bio"
+				} else {
+					op.Path = "/bio" + op.Path
+				}
+				p.Operations = append(p.Operations, op)
+			}
+		} else if !deep.Equal(t.Bio, other.Bio) {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/bio", Old: t.Bio, New: other.Bio})
 		}
-		p.Operations = append(p.Operations, op)
 	}
-	if t.age != other.age {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/age", Old: t.age, New: other.age})
+	if !l.Ignored("age") {
+		if t.age != other.age {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/age", Old: t.age, New: other.age})
+		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *User) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
@@ -314,6 +457,14 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -327,6 +478,9 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.ID))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.ID, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -384,6 +538,12 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Name))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Name, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Name), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Name")
@@ -401,6 +561,12 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Name >= _sv, nil
 		case "<=":
 			return t.Name <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Name, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Name, _sv), nil
+		case "contains":
+			return strings.Contains(t.Name, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -428,6 +594,9 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.age))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.age, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -476,6 +645,9 @@ func (t *User) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -524,6 +696,32 @@ func (t *User) Equal(other *User) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *User) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.Int64(int64(t.ID))
+	h.String(t.Name)
+	h.Uint64((&t.Info).Hash())
+	h.Uint64(uint64(len(t.Roles)))
+	for _, v := range t.Roles {
+		h.String(v)
+	}
+	h.Uint64(uint64(len(t.Score)))
+	{
+		var sum uint64
+		for k, v := range t.Score {
+			e := _deepengine.NewHasher()
+			e.String(k)
+			e.Int64(int64(v))
+			sum += e.Sum()
+		}
+		h.Uint64(sum)
+	}
+	h.Value(t.Bio)
+	h.Int64(int64(t.age))
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *User) Clone() *User {
 	res := &User{
@@ -561,11 +759,14 @@ func (t *Detail) Patch(p deep.Patch[Detail], logger *slog.Logger) error {
 	for _, op := range p.Operations {
 		op.Strict = p.Strict
 		handled, err := t.applyOperation(op, logger)
+		if err == nil && !handled {
+			err = _deepengine.ApplyOpReflection(t, op, logger)
+		}
 		if err != nil {
 			errs = append(errs, err)
-		} else if !handled {
-			if err := _deepengine.ApplyOpReflection(t, op, logger); err != nil {
-				errs = append(errs, err)
+			if op.Kind == deep.OpTest {
+				// A failed test stops the patch, as in RFC 6902.
+				break
 			}
 		}
 	}
@@ -595,6 +796,9 @@ func (t *Detail) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 
 	switch op.Path {
 	case "/":
+		if op.Kind == deep.OpTest {
+			return false, nil
+		}
 		if op.Strict && (op.Kind == deep.OpReplace || op.Kind == deep.OpRemove) {
 			if !deep.Equal(*t, op.Old.(Detail)) {
 				return true, fmt.Errorf("strict check failed at root: expected %v, got %v", op.Old, *t)
@@ -608,6 +812,21 @@ func (t *Detail) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 		}
 		return true, fmt.Errorf("unsupported root operation: %s", op.Kind)
 	case "/Age":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(int); ok {
+				if t.Age != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Age)
+				}
+				return true, nil
+			}
+			if v, ok := op.New.(float64); ok {
+				if float64(t.Age) != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Age)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Age)
 			return true, nil
@@ -635,6 +854,15 @@ func (t *Detail) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 			return true, nil
 		}
 	case "/addr", "/Address":
+		if op.Kind == deep.OpTest {
+			if v, ok := op.New.(string); ok {
+				if t.Address != v {
+					return true, fmt.Errorf("%w at %s: expected %v, got %v", deep.ErrTestFailed, op.Path, op.New, t.Address)
+				}
+				return true, nil
+			}
+			return false, nil
+		}
 		if op.Kind == deep.OpLog {
 			logger.Info("deep log", "message", op.New, "path", op.Path, "field", t.Address)
 			return true, nil
@@ -655,15 +883,37 @@ func (t *Detail) applyOperation(op deep.Operation, logger *slog.Logger) (bool, e
 
 // Diff compares t with other and returns a Patch.
 func (t *Detail) Diff(other *Detail) deep.Patch[Detail] {
+	p, _ := t.DiffLimited(other, nil)
+	return p
+}
+
+// DiffLimited is like Diff but honors the limits of a bounded diff. It is
+// called by deep.DiffContext; a nil l imposes no limit.
+func (t *Detail) DiffLimited(other *Detail, l *_deepengine.DiffLimits) (deep.Patch[Detail], error) {
 	p := deep.Patch[Detail]{}
-	if t.Age != other.Age {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/Age", Old: t.Age, New: other.Age})
+	if err := l.Check(); err != nil {
+		return p, err
 	}
-	if t.Address != other.Address {
-		p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/addr", Old: t.Address, New: other.Address})
+	// counted is the number of operations already passed to l.Count.
+	counted := 0
+	if !l.Ignored("Age") {
+		if t.Age != other.Age {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/Age", Old: t.Age, New: other.Age})
+		}
+	}
+	if !l.Ignored("addr") {
+		if t.Address != other.Address {
+			p.Operations = append(p.Operations, deep.Operation{Kind: deep.OpReplace, Path: "/addr", Old: t.Address, New: other.Address})
+		}
 	}
 
-	return p
+	return p, l.Count(len(p.Operations) - counted)
+}
+
+// EvaluateCondition reports whether c holds for t using the generated fast
+// path. It is called by deep.Apply and deep.ApplyWithReport.
+func (t *Detail) EvaluateCondition(c condition.Condition) (bool, error) {
+	return t.evaluateCondition(c)
 }
 
 func (t *Detail) evaluateCondition(c condition.Condition) (bool, error) {
@@ -693,6 +943,14 @@ func (t *Detail) evaluateCondition(c condition.Condition) (bool, error) {
 			return !ok, nil
 		}
 		return true, nil
+	case "any", "all", "none":
+		// Quantifiers range over collection elements, which are resolved by
+		// reflection like wildcard paths.
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
+	if c.Ref != "" {
+		// Comparisons with another path.
+		return _deepengine.EvaluateConditionReflection(t, c)
 	}
 
 	switch c.Path {
@@ -706,6 +964,9 @@ func (t *Detail) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Age))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Age, c.Value)
+		}
 		var _cv float64
 		switch v := c.Value.(type) {
 		case int:
@@ -763,6 +1024,12 @@ func (t *Detail) evaluateCondition(c condition.Condition) (bool, error) {
 		if c.Op == "matches" {
 			return regexp.MatchString(c.Value.(string), fmt.Sprintf("%v", t.Address))
 		}
+		if c.Op == "between" {
+			return condition.BetweenValues(t.Address, c.Value)
+		}
+		if strings.HasPrefix(c.Op, "len") {
+			return condition.CompareLen(len(t.Address), c.Op, c.Value)
+		}
 		_sv, _ok := c.Value.(string)
 		if !_ok {
 			return false, fmt.Errorf("condition value type mismatch for field Address")
@@ -780,6 +1047,12 @@ func (t *Detail) evaluateCondition(c condition.Condition) (bool, error) {
 			return t.Address >= _sv, nil
 		case "<=":
 			return t.Address <= _sv, nil
+		case "startsWith":
+			return strings.HasPrefix(t.Address, _sv), nil
+		case "endsWith":
+			return strings.HasSuffix(t.Address, _sv), nil
+		case "contains":
+			return strings.Contains(t.Address, _sv), nil
 		case "in":
 			switch vals := c.Value.(type) {
 			case []string:
@@ -798,6 +1071,9 @@ func (t *Detail) evaluateCondition(c condition.Condition) (bool, error) {
 			return false, nil
 		}
 	}
+	if c.Path != "" && c.Path != "/" {
+		return _deepengine.EvaluateConditionReflection(t, c)
+	}
 	return false, fmt.Errorf("unsupported condition path or op: %s", c.Path)
 }
 
@@ -812,6 +1088,14 @@ func (t *Detail) Equal(other *Detail) bool {
 	return true
 }
 
+// Hash returns a hash of t that is the same for values that are Equal.
+func (t *Detail) Hash() uint64 {
+	h := _deepengine.NewHasher()
+	h.Int64(int64(t.Age))
+	h.String(t.Address)
+	return h.Sum()
+}
+
 // Clone returns a deep copy of t.
 func (t *Detail) Clone() *Detail {
 	res := &Detail{
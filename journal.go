@@ -0,0 +1,239 @@
+package deep
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// JournalEntry is a patch recorded in a [Journal].
+type JournalEntry[T any] struct {
+	// Seq numbers entries from 1 in the order they were appended.
+	Seq   uint64    `json:"seq"`
+	Time  time.Time `json:"time"`
+	Patch Patch[T]  `json:"patch"`
+}
+
+// JournalSnapshot is the state of a [Journal] after the entry numbered Seq,
+// or its initial state if Seq is 0.
+type JournalSnapshot[T any] struct {
+	Seq   uint64    `json:"seq"`
+	Time  time.Time `json:"time"`
+	Value T         `json:"value"`
+}
+
+// JournalStorage stores the entries and snapshots of a [Journal]. See
+// [NewMemoryJournalStorage] and [NewFileJournalStorage].
+type JournalStorage[T any] interface {
+	// Append stores e. Entries are appended in increasing Seq order.
+	Append(e JournalEntry[T]) error
+	// Entries returns the stored entries with from <= Seq <= to, in order.
+	Entries(from, to uint64) ([]JournalEntry[T], error)
+	// SaveSnapshot stores s.
+	SaveSnapshot(s JournalSnapshot[T]) error
+	// Snapshot returns the stored snapshot with the highest Seq not greater
+	// than seq, or false if there is none.
+	Snapshot(seq uint64) (JournalSnapshot[T], bool, error)
+}
+
+type journalConfig struct {
+	snapshotEvery uint64
+	now           func() time.Time
+}
+
+// JournalOption configures a [Journal].
+type JournalOption func(*journalConfig)
+
+// SnapshotEvery makes a [Journal] save a snapshot after every n entries.
+// The default is 100; 0 disables periodic snapshots, so reconstructing a state
+// replays every entry since the initial value.
+func SnapshotEvery(n int) JournalOption {
+	return func(c *journalConfig) {
+		if n < 0 {
+			n = 0
+		}
+		c.snapshotEvery = uint64(n)
+	}
+}
+
+// JournalClock sets the function a [Journal] uses to timestamp entries. The
+// default is time.Now.
+func JournalClock(now func() time.Time) JournalOption {
+	return func(c *journalConfig) { c.now = now }
+}
+
+// Journal is an append-only log of patches to a value of type T. Every
+// appended patch is applied to the journal's current value and recorded with
+// a sequence number and time; periodic snapshots, made with [Clone], bound how
+// many patches must be replayed to reconstruct the value at any point.
+type Journal[T any] struct {
+	mu      sync.Mutex
+	storage JournalStorage[T]
+	cfg     journalConfig
+	value   T
+	seq     uint64
+}
+
+// OpenJournal returns a journal kept in storage. If storage is empty, initial
+// becomes the state at sequence 0; otherwise initial is ignored and the
+// current state is rebuilt from the latest snapshot and the entries after it.
+func OpenJournal[T any](storage JournalStorage[T], initial T, opts ...JournalOption) (*Journal[T], error) {
+	cfg := journalConfig{snapshotEvery: 100, now: time.Now}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	j := &Journal[T]{storage: storage, cfg: cfg}
+
+	snap, ok, err := storage.Snapshot(math.MaxUint64)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		snap = JournalSnapshot[T]{Time: cfg.now(), Value: Clone(initial)}
+		if err := storage.SaveSnapshot(snap); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := storage.Entries(snap.Seq+1, math.MaxUint64)
+	if err != nil {
+		return nil, err
+	}
+	j.value, j.seq = Clone(snap.Value), snap.Seq
+	if err := replay(&j.value, entries); err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		j.seq = entries[len(entries)-1].Seq
+	}
+	return j, nil
+}
+
+// replay applies the patches of entries to v in order. Each patch is copied
+// first, so that v does not share maps, slices or pointers with the values
+// held by the storage.
+func replay[T any](v *T, entries []JournalEntry[T]) error {
+	for _, e := range entries {
+		if err := Apply(v, Clone(e.Patch)); err != nil {
+			return fmt.Errorf("journal: replaying entry %d: %w", e.Seq, err)
+		}
+	}
+	return nil
+}
+
+// Append applies p to the current value and records a copy of it as the next
+// entry, returning its sequence number. p is applied to a copy first; if it
+// fails, or the entry cannot be stored, the journal is left unchanged.
+func (j *Journal[T]) Append(p Patch[T]) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	next := Clone(j.value)
+	if err := Apply(&next, Clone(p)); err != nil {
+		return 0, err
+	}
+
+	e := JournalEntry[T]{Seq: j.seq + 1, Time: j.cfg.now(), Patch: Clone(p)}
+	if err := j.storage.Append(e); err != nil {
+		return 0, err
+	}
+	j.value, j.seq = next, e.Seq
+
+	if j.cfg.snapshotEvery > 0 && j.seq%j.cfg.snapshotEvery == 0 {
+		snap := JournalSnapshot[T]{Seq: j.seq, Time: e.Time, Value: Clone(j.value)}
+		if err := j.storage.SaveSnapshot(snap); err != nil {
+			return e.Seq, fmt.Errorf("journal: saving snapshot %d: %w", j.seq, err)
+		}
+	}
+	return e.Seq, nil
+}
+
+// Seq returns the sequence number of the last entry, or 0 if there is none.
+func (j *Journal[T]) Seq() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.seq
+}
+
+// Current returns a copy of the value after the last entry.
+func (j *Journal[T]) Current() T {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return Clone(j.value)
+}
+
+// At reconstructs the value after the entry numbered seq, starting from the
+// closest snapshot before it. At(0) returns the initial value. The result
+// shares no memory with the journal or its storage.
+func (j *Journal[T]) At(seq uint64) (T, error) {
+	j.mu.Lock()
+	cur := j.seq
+	j.mu.Unlock()
+	if seq > cur {
+		var zero T
+		return zero, fmt.Errorf("journal: sequence %d is after the last entry %d", seq, cur)
+	}
+
+	snap, ok, err := j.storage.Snapshot(seq)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("journal: no snapshot at or before sequence %d", seq)
+	}
+	res := Clone(snap.Value)
+	if snap.Seq == seq {
+		return res, nil
+	}
+
+	entries, err := j.storage.Entries(snap.Seq+1, seq)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if err := replay(&res, entries); err != nil {
+		var zero T
+		return zero, err
+	}
+	return res, nil
+}
+
+// AtTime reconstructs the value as it was at t: after the last entry recorded
+// at or before t. It returns the initial value if t is before every entry.
+func (j *Journal[T]) AtTime(t time.Time) (T, error) {
+	seq, err := j.SeqAt(t)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return j.At(seq)
+}
+
+// SeqAt returns the sequence number of the last entry recorded at or before t,
+// or 0 if there is none.
+func (j *Journal[T]) SeqAt(t time.Time) (uint64, error) {
+	j.mu.Lock()
+	cur := j.seq
+	j.mu.Unlock()
+
+	entries, err := j.storage.Entries(1, cur)
+	if err != nil {
+		return 0, err
+	}
+	var seq uint64
+	for _, e := range entries {
+		if e.Time.After(t) {
+			break
+		}
+		seq = e.Seq
+	}
+	return seq, nil
+}
+
+// Entries returns the recorded entries with from <= Seq <= to.
+func (j *Journal[T]) Entries(from, to uint64) ([]JournalEntry[T], error) {
+	return j.storage.Entries(from, to)
+}
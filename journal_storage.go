@@ -0,0 +1,199 @@
+package deep
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MemoryJournalStorage is a [JournalStorage] that keeps everything in memory.
+type MemoryJournalStorage[T any] struct {
+	mu        sync.RWMutex
+	entries   []JournalEntry[T]
+	snapshots []JournalSnapshot[T]
+}
+
+// NewMemoryJournalStorage returns an empty in-memory journal storage.
+func NewMemoryJournalStorage[T any]() *MemoryJournalStorage[T] {
+	return &MemoryJournalStorage[T]{}
+}
+
+func (s *MemoryJournalStorage[T]) Append(e JournalEntry[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *MemoryJournalStorage[T]) Entries(from, to uint64) ([]JournalEntry[T], error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var res []JournalEntry[T]
+	for _, e := range s.entries {
+		if e.Seq >= from && e.Seq <= to {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func (s *MemoryJournalStorage[T]) SaveSnapshot(snap JournalSnapshot[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := sort.Search(len(s.snapshots), func(i int) bool { return s.snapshots[i].Seq >= snap.Seq })
+	if i < len(s.snapshots) && s.snapshots[i].Seq == snap.Seq {
+		s.snapshots[i] = snap
+		return nil
+	}
+	s.snapshots = append(s.snapshots, JournalSnapshot[T]{})
+	copy(s.snapshots[i+1:], s.snapshots[i:])
+	s.snapshots[i] = snap
+	return nil
+}
+
+func (s *MemoryJournalStorage[T]) Snapshot(seq uint64) (JournalSnapshot[T], bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := sort.Search(len(s.snapshots), func(i int) bool { return s.snapshots[i].Seq > seq })
+	if i == 0 {
+		return JournalSnapshot[T]{}, false, nil
+	}
+	return s.snapshots[i-1], true, nil
+}
+
+// FileJournalStorage is a [JournalStorage] that keeps a journal in a
+// directory: entries are appended as JSON lines to journal.jsonl and each
+// snapshot is written as JSON to its own snapshot-<seq>.json file.
+type FileJournalStorage[T any] struct {
+	mu  sync.Mutex
+	dir string
+}
+
+const journalFile = "journal.jsonl"
+
+// NewFileJournalStorage returns a storage kept in dir, creating the directory
+// if needed. An existing journal in dir is reused.
+func NewFileJournalStorage[T any](dir string) (*FileJournalStorage[T], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileJournalStorage[T]{dir: dir}, nil
+}
+
+func (s *FileJournalStorage[T]) Append(e JournalEntry[T]) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(filepath.Join(s.dir, journalFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *FileJournalStorage[T]) Entries(from, to uint64) ([]JournalEntry[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.Open(filepath.Join(s.dir, journalFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var res []JournalEntry[T]
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64<<20)
+	for line := 1; sc.Scan(); line++ {
+		// Decode the sequence number first so that only the requested
+		// entries pay for decoding their patch.
+		var head struct {
+			Seq uint64 `json:"seq"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &head); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", journalFile, line, err)
+		}
+		if head.Seq < from {
+			continue
+		}
+		if head.Seq > to {
+			break
+		}
+		var e JournalEntry[T]
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", journalFile, line, err)
+		}
+		res = append(res, e)
+	}
+	return res, sc.Err()
+}
+
+func (s *FileJournalStorage[T]) SaveSnapshot(snap JournalSnapshot[T]) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Write to a temporary file first so a crash never leaves a partial
+	// snapshot behind.
+	name := filepath.Join(s.dir, fmt.Sprintf("snapshot-%d.json", snap.Seq))
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+func (s *FileJournalStorage[T]) Snapshot(seq uint64) (JournalSnapshot[T], bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return JournalSnapshot[T]{}, false, err
+	}
+
+	var best uint64
+	found := false
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasPrefix(name, "snapshot-") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, "snapshot-"), ".json"), 10, 64)
+		if err != nil || n > seq || (found && n <= best) {
+			continue
+		}
+		best, found = n, true
+	}
+	if !found {
+		return JournalSnapshot[T]{}, false, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, fmt.Sprintf("snapshot-%d.json", best)))
+	if err != nil {
+		return JournalSnapshot[T]{}, false, err
+	}
+	var snap JournalSnapshot[T]
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return JournalSnapshot[T]{}, false, err
+	}
+	return snap, true, nil
+}
@@ -0,0 +1,140 @@
+package deep_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+func testJournal(t *testing.T, storage deep.JournalStorage[testmodels.User]) {
+	t.Helper()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := base
+	clock := deep.JournalClock(func() time.Time { return now })
+
+	j, err := deep.OpenJournal(storage, testmodels.User{ID: 1, Name: "v0"}, deep.SnapshotEvery(3), clock)
+	if err != nil {
+		t.Fatalf("OpenJournal failed: %v", err)
+	}
+	namePath := deep.Field(func(u *testmodels.User) *string { return &u.Name })
+	agePath := deep.Field(func(u *testmodels.User) *int { return &u.Info.Age })
+	for i := 1; i <= 7; i++ {
+		now = base.Add(time.Duration(i) * time.Minute)
+		p := deep.Edit(new(testmodels.User)).
+			With(deep.Set(namePath, fmt.Sprint("v", i))).
+			With(deep.Set(agePath, i)).
+			Build()
+		seq, err := j.Append(p)
+		if err != nil {
+			t.Fatalf("Append %d failed: %v", i, err)
+		}
+		if seq != uint64(i) {
+			t.Fatalf("Append %d returned seq %d", i, seq)
+		}
+	}
+
+	// A failing patch is not recorded.
+	bad := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/missing", New: 1},
+	}}
+	if _, err := j.Append(bad); err == nil {
+		t.Fatal("expected error for failing patch")
+	}
+	if j.Seq() != 7 || j.Current().Name != "v7" {
+		t.Fatalf("unexpected journal state: seq %d, %+v", j.Seq(), j.Current())
+	}
+
+	for seq := uint64(0); seq <= 7; seq++ {
+		u, err := j.At(seq)
+		if err != nil {
+			t.Fatalf("At(%d) failed: %v", seq, err)
+		}
+		if u.Name != fmt.Sprint("v", seq) || u.Info.Age != int(seq) {
+			t.Errorf("At(%d) = %+v", seq, u)
+		}
+	}
+	if _, err := j.At(8); err == nil {
+		t.Error("expected error for a sequence after the last entry")
+	}
+
+	u, err := j.AtTime(base.Add(4*time.Minute + 30*time.Second))
+	if err != nil || u.Name != "v4" {
+		t.Errorf("AtTime = %+v, %v", u, err)
+	}
+	if u, err := j.AtTime(base.Add(-time.Hour)); err != nil || u.Name != "v0" {
+		t.Errorf("AtTime before first entry = %+v, %v", u, err)
+	}
+
+	// Reopening rebuilds the current state from storage.
+	j2, err := deep.OpenJournal(storage, testmodels.User{}, deep.SnapshotEvery(3), clock)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if j2.Seq() != 7 || j2.Current().Name != "v7" || j2.Current().Info.Age != 7 {
+		t.Errorf("reopened journal: seq %d, %+v", j2.Seq(), j2.Current())
+	}
+}
+
+func TestJournalMemory(t *testing.T) {
+	testJournal(t, deep.NewMemoryJournalStorage[testmodels.User]())
+}
+
+func TestJournalFile(t *testing.T) {
+	storage, err := deep.NewFileJournalStorage[testmodels.User](t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testJournal(t, storage)
+}
+
+func TestJournalValuesNotShared(t *testing.T) {
+	type Doc struct {
+		M map[string]int
+	}
+	j, err := deep.OpenJournal(deep.NewMemoryJournalStorage[Doc](), Doc{}, deep.SnapshotEvery(0))
+	if err != nil {
+		t.Fatalf("OpenJournal failed: %v", err)
+	}
+	m := map[string]int{"a": 1}
+	p := deep.Patch[Doc]{Operations: []deep.Operation{
+		{Kind: deep.OpAdd, Path: "/M", New: m},
+	}}
+	if _, err := j.Append(p); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if _, err := j.Append(deep.Patch[Doc]{Operations: []deep.Operation{
+		{Kind: deep.OpAdd, Path: "/M/b", New: 2},
+	}}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	// Changing the appended map, the values returned, or replaying later
+	// entries leaves the recorded entries alone.
+	m["x"] = 9
+	if _, err := j.At(2); err != nil {
+		t.Fatalf("At(2) failed: %v", err)
+	}
+	d, err := j.At(1)
+	if err != nil {
+		t.Fatalf("At(1) failed: %v", err)
+	}
+	d.M["c"] = 3
+	j.Current().M["d"] = 4
+
+	for seq, want := range []map[string]int{nil, {"a": 1}, {"a": 1, "b": 2}} {
+		d, err := j.At(uint64(seq))
+		if err != nil {
+			t.Fatalf("At(%d) failed: %v", seq, err)
+		}
+		if !deep.Equal(d.M, want) {
+			t.Errorf("At(%d) = %v, want %v", seq, d.M, want)
+		}
+	}
+	if got := j.Current().M; !deep.Equal(got, map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("Current = %v", got)
+	}
+}
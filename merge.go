@@ -0,0 +1,203 @@
+package deep
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	icore "github.com/brunoga/deep/v5/internal/core"
+	"github.com/brunoga/deep/v5/internal/engine"
+)
+
+// Conflict describes a path changed incompatibly by both sides of a
+// three-way merge. Base, Local and Remote hold the value at Path in each
+// version, or nil where the path does not exist.
+type Conflict struct {
+	Path   string
+	Base   any
+	Local  any
+	Remote any
+}
+
+// Merge3 performs a three-way merge of local and remote against their common
+// ancestor base. It returns a patch that, applied to base, carries every
+// non-conflicting change from both sides, together with one [Conflict] for
+// each path that both sides changed differently.
+//
+// Two changes overlap when their paths are equal or one is a parent of the
+// other. Elements of keyed slices (deep:"key") are addressed by key, so edits
+// to different elements never overlap. Inserts and removes in non-keyed slices
+// shift the indices of their siblings, so they overlap with any other change
+// to the same slice. Identical changes made on both sides are merged once.
+//
+// Conflicting paths are left at their base value in the returned patch.
+func Merge3[T any](base, local, remote T) (Patch[T], []Conflict, error) {
+	lp, err := Diff(base, local)
+	if err != nil {
+		return Patch[T]{}, nil, err
+	}
+	rp, err := Diff(base, remote)
+	if err != nil {
+		return Patch[T]{}, nil, err
+	}
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	lops := mergeTouches(typ, lp.Operations)
+	rops := mergeTouches(typ, rp.Operations)
+
+	duplicate := make(map[int]bool)
+	conflicts := make(map[string]string) // canonical path -> reported path
+	for _, l := range lops {
+		for j, r := range rops {
+			orig, canon, ok := l.overlap(r)
+			if !ok {
+				continue
+			}
+			if sameOperation(l.op, r.op) {
+				duplicate[j] = true
+				continue
+			}
+			conflicts[canon] = orig
+		}
+	}
+
+	// Only report the outermost conflicting path.
+	for canon := range conflicts {
+		for other := range conflicts {
+			if other != canon && isSubPath(canon, other) {
+				delete(conflicts, canon)
+				break
+			}
+		}
+	}
+
+	var canonConflicts []string
+	for canon := range conflicts {
+		canonConflicts = append(canonConflicts, canon)
+	}
+
+	res := Patch[T]{}
+	keep := func(t mergeTouch) bool {
+		for _, c := range t.canon {
+			for _, cc := range canonConflicts {
+				if pathsOverlap(c, cc) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for _, l := range lops {
+		if keep(l) {
+			res.Operations = append(res.Operations, l.op)
+		}
+	}
+	for j, r := range rops {
+		if !duplicate[j] && keep(r) {
+			res.Operations = append(res.Operations, r.op)
+		}
+	}
+
+	var out []Conflict
+	for _, path := range conflicts {
+		out = append(out, Conflict{
+			Path:   path,
+			Base:   valueAtPath(&base, path),
+			Local:  valueAtPath(&local, path),
+			Remote: valueAtPath(&remote, path),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+
+	return res, out, nil
+}
+
+// mergeTouch records the paths an operation affects for conflict detection,
+// in both their original spelling and canonical (Go field name) form.
+type mergeTouch struct {
+	op    Operation
+	orig  []string
+	canon []string
+}
+
+func mergeTouches(typ reflect.Type, ops []Operation) []mergeTouch {
+	res := make([]mergeTouch, 0, len(ops))
+	for _, op := range ops {
+		t := mergeTouch{op: op}
+		paths := []string{op.Path}
+		if op.Kind == OpMove || op.Kind == OpCopy {
+			if from, ok := op.Old.(string); ok {
+				paths = append(paths, from)
+			}
+		}
+		for _, p := range paths {
+			anchor := p
+			if op.Kind != OpReplace {
+				anchor = shiftAnchor(typ, p)
+			}
+			t.orig = append(t.orig, anchor)
+			t.canon = append(t.canon, icore.CanonicalPath(typ, anchor))
+		}
+		res = append(res, t)
+	}
+	return res
+}
+
+// shiftAnchor widens path to its parent when it addresses an element of a
+// non-keyed slice, since inserting or removing there renumbers the siblings.
+func shiftAnchor(typ reflect.Type, path string) string {
+	idx := strings.LastIndexByte(path, '/')
+	if idx < 0 {
+		return path
+	}
+	parent := path[:idx]
+	if parent == "" {
+		parent = "/"
+	}
+	pt, err := icore.TypeAtPath(typ, parent)
+	if err != nil {
+		return path
+	}
+	for pt.Kind() == reflect.Pointer {
+		pt = pt.Elem()
+	}
+	if pt.Kind() == reflect.Slice && !icore.IsKeyedSlice(pt) {
+		return parent
+	}
+	return path
+}
+
+func (t mergeTouch) overlap(other mergeTouch) (orig, canon string, ok bool) {
+	for i, a := range t.canon {
+		for j, b := range other.canon {
+			if !pathsOverlap(a, b) {
+				continue
+			}
+			if len(b) < len(a) {
+				return other.orig[j], b, true
+			}
+			return t.orig[i], a, true
+		}
+	}
+	return "", "", false
+}
+
+func sameOperation(a, b Operation) bool {
+	return a.Kind == b.Kind && a.Path == b.Path &&
+		engine.Equal(a.New, b.New) && engine.Equal(a.Old, b.Old)
+}
+
+// pathsOverlap reports whether p1 and p2 are equal or one contains the other.
+func pathsOverlap(p1, p2 string) bool {
+	return isSubPath(p1, p2) || isSubPath(p2, p1)
+}
+
+// valueAtPath returns a copy of the value at path within *v, or nil if it does
+// not exist.
+func valueAtPath[T any](v *T, path string) any {
+	val, err := icore.DeepPath(path).Resolve(reflect.ValueOf(v).Elem())
+	if err != nil || !val.IsValid() {
+		return nil
+	}
+	return icore.ValueToInterface(icore.DeepCopyValue(val))
+}
@@ -0,0 +1,132 @@
+package deep_test
+
+import (
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+func TestMerge3NonOverlapping(t *testing.T) {
+	base := testmodels.User{ID: 1, Name: "Alice", Info: testmodels.Detail{Age: 30}}
+	local := base
+	local.Name = "Alicia"
+	remote := base
+	remote.Info.Age = 31
+
+	p, conflicts, err := deep.Merge3(base, local, remote)
+	if err != nil {
+		t.Fatalf("Merge3 failed: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %+v", conflicts)
+	}
+
+	merged := base
+	if err := deep.Apply(&merged, p); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if merged.Name != "Alicia" || merged.Info.Age != 31 {
+		t.Errorf("merge mismatch: %+v", merged)
+	}
+}
+
+func TestMerge3Conflicts(t *testing.T) {
+	base := testmodels.User{ID: 1, Name: "Alice", Score: map[string]int{"a": 1}}
+	local := deep.Clone(base)
+	local.Name = "Alicia"
+	local.ID = 2
+	local.Score["a"] = 2
+	remote := deep.Clone(base)
+	remote.Name = "Allie"
+	remote.ID = 2
+	remote.Score["b"] = 5
+
+	p, conflicts, err := deep.Merge3(base, local, remote)
+	if err != nil {
+		t.Fatalf("Merge3 failed: %v", err)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %+v", conflicts)
+	}
+	c := conflicts[0]
+	if c.Path != "/full_name" || c.Base != "Alice" || c.Local != "Alicia" || c.Remote != "Allie" {
+		t.Errorf("unexpected conflict: %+v", c)
+	}
+
+	merged := deep.Clone(base)
+	if err := deep.Apply(&merged, p); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	// Identical change (ID) merged once; the conflicting name stays at base.
+	if merged.ID != 2 || merged.Name != "Alice" || merged.Score["a"] != 2 || merged.Score["b"] != 5 {
+		t.Errorf("merge mismatch: %+v", merged)
+	}
+}
+
+func TestMerge3Slices(t *testing.T) {
+	type Item struct {
+		SKU string `deep:"key"`
+		Qty int
+	}
+	type Doc struct {
+		Items []Item
+		Tags  []string
+		Meta  *Item
+	}
+	base := Doc{
+		Items: []Item{{SKU: "a", Qty: 1}, {SKU: "b", Qty: 1}},
+		Tags:  []string{"x", "y"},
+	}
+
+	// Keyed elements: edits to different keys merge cleanly.
+	local := deep.Clone(base)
+	local.Items[0].Qty = 5
+	remote := deep.Clone(base)
+	remote.Items = append(remote.Items, Item{SKU: "c", Qty: 3})
+
+	p, conflicts, err := deep.Merge3(base, local, remote)
+	if err != nil {
+		t.Fatalf("Merge3 failed: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %+v", conflicts)
+	}
+	merged := deep.Clone(base)
+	if err := deep.Apply(&merged, p); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := []Item{{SKU: "a", Qty: 5}, {SKU: "b", Qty: 1}, {SKU: "c", Qty: 3}}
+	if !deep.Equal(merged.Items, want) {
+		t.Errorf("got %+v, want %+v", merged.Items, want)
+	}
+
+	// Non-keyed slices: an insert conflicts with an edit of a sibling.
+	local = deep.Clone(base)
+	local.Tags = []string{"w", "x", "y"}
+	remote = deep.Clone(base)
+	remote.Tags[1] = "z"
+
+	_, conflicts, err = deep.Merge3(base, local, remote)
+	if err != nil {
+		t.Fatalf("Merge3 failed: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Path != "/Tags" {
+		t.Fatalf("expected conflict at /Tags, got %+v", conflicts)
+	}
+
+	// Parent/child: replacing a parent conflicts with a change below it.
+	base.Meta = &Item{SKU: "m", Qty: 1}
+	local = deep.Clone(base)
+	local.Meta = nil
+	remote = deep.Clone(base)
+	remote.Meta.Qty = 2
+
+	_, conflicts, err = deep.Merge3(base, local, remote)
+	if err != nil {
+		t.Fatalf("Merge3 failed: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Path != "/Meta" || conflicts[0].Local != nil {
+		t.Fatalf("expected conflict at /Meta, got %+v", conflicts)
+	}
+}
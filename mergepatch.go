@@ -0,0 +1,340 @@
+package deep
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	icore "github.com/brunoga/deep/v5/internal/core"
+)
+
+// ErrNotMergeable is wrapped by the error [Patch.ToMergePatch] returns for a
+// patch that has no JSON Merge Patch (RFC 7386) form.
+var ErrNotMergeable = errors.New("not representable as a JSON merge patch")
+
+// ParseMergePatch converts a JSON Merge Patch (RFC 7386) document into a
+// Patch[T] that turns current into the merged value. Members are matched to
+// struct fields by their json names; objects are merged recursively into
+// structs and maps, null removes a map entry or resets a field to its zero
+// value, and any other value (arrays included) replaces the value at its path.
+// Members that do not change current produce no operation, and members that
+// name no field of T are an error.
+//
+// Removals and replacements record the value they overwrite in Operation.Old,
+// so the result can be reversed.
+func ParseMergePatch[T any](data []byte, current T) (Patch[T], error) {
+	res := Patch[T]{}
+	if err := parseMerge("", reflect.ValueOf(&current).Elem(), data, &res.Operations); err != nil {
+		return Patch[T]{}, fmt.Errorf("ParseMergePatch: %w", err)
+	}
+	return res, nil
+}
+
+// parseMerge appends to ops the operations that merge raw into cur, the value
+// at path.
+func parseMerge(path string, cur reflect.Value, raw json.RawMessage, ops *[]Operation) error {
+	target := cur
+	for target.Kind() == reflect.Pointer && !target.IsNil() {
+		target = target.Elem()
+	}
+
+	var members map[string]json.RawMessage
+	isObject := json.Unmarshal(raw, &members) == nil && members != nil
+	switch {
+	case isObject && target.Kind() == reflect.Struct:
+		fields := mergeFields(target.Type())
+		for _, name := range sortedKeys(members) {
+			idx, ok := fields[name]
+			if !ok {
+				return fmt.Errorf("unknown field %q at %s", name, rootPath(path))
+			}
+			if err := mergeStructField(path+"/"+icore.EscapeKey(name), target.Field(idx), members[name], ops); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case isObject && target.Kind() == reflect.Map && !target.IsNil():
+		for _, name := range sortedKeys(members) {
+			key, err := mergeMapKey(target.Type().Key(), name)
+			if err != nil {
+				return fmt.Errorf("at %s: %w", rootPath(path), err)
+			}
+			child := path + "/" + icore.EscapeKey(name)
+			existing := target.MapIndex(key)
+			if existing.IsValid() {
+				if string(members[name]) == "null" {
+					// The entry exists, even if it holds a zero value.
+					*ops = append(*ops, Operation{Kind: OpRemove, Path: child, Old: existing.Interface()})
+					continue
+				}
+				if err := parseMerge(child, existing, members[name], ops); err != nil {
+					return err
+				}
+				continue
+			}
+			if string(members[name]) == "null" {
+				continue
+			}
+			val := reflect.New(target.Type().Elem())
+			if err := json.Unmarshal(members[name], val.Interface()); err != nil {
+				return fmt.Errorf("decoding value at %s: %w", child, err)
+			}
+			*ops = append(*ops, Operation{Kind: OpAdd, Path: child, New: val.Elem().Interface()})
+		}
+		return nil
+	}
+
+	// Anything else replaces the value as a whole.
+	val := reflect.New(cur.Type())
+	if err := json.Unmarshal(raw, val.Interface()); err != nil {
+		return fmt.Errorf("decoding value at %s: %w", rootPath(path), err)
+	}
+	if icore.Equal(cur.Interface(), val.Elem().Interface()) {
+		return nil
+	}
+	*ops = append(*ops, Operation{Kind: OpReplace, Path: rootPath(path), Old: cur.Interface(), New: val.Elem().Interface()})
+	return nil
+}
+
+// mergeStructField merges raw into the struct field cur at path, where a null
+// resets it to its zero value.
+func mergeStructField(path string, cur reflect.Value, raw json.RawMessage, ops *[]Operation) error {
+	if string(raw) != "null" {
+		return parseMerge(path, cur, raw, ops)
+	}
+	if !cur.IsZero() {
+		*ops = append(*ops, Operation{Kind: OpRemove, Path: path, Old: cur.Interface()})
+	}
+	return nil
+}
+
+// mergeFields maps the json names of the fields of typ, as encoding/json
+// sees them, to their indexes.
+func mergeFields(typ reflect.Type) map[string]int {
+	res := make(map[string]int)
+	for _, f := range icore.GetTypeInfo(typ).Fields {
+		if name, ok := jsonFieldName(typ, f); ok {
+			res[name] = f.Index
+		}
+	}
+	return res
+}
+
+// jsonFieldName returns the name of f in JSON, or false if encoding/json
+// skips it.
+func jsonFieldName(typ reflect.Type, f icore.FieldInfo) (string, bool) {
+	if !typ.Field(f.Index).IsExported() || f.JSONTag == "-" {
+		return "", false
+	}
+	if f.JSONTag != "" {
+		return f.JSONTag, true
+	}
+	return f.Name, true
+}
+
+func mergeMapKey(typ reflect.Type, name string) (reflect.Value, error) {
+	switch typ.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(name).Convert(typ), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid map key %q: %w", name, err)
+		}
+		return reflect.ValueOf(n).Convert(typ), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid map key %q: %w", name, err)
+		}
+		return reflect.ValueOf(n).Convert(typ), nil
+	}
+	return reflect.Value{}, fmt.Errorf("unsupported map key type %v", typ)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func rootPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// ToMergePatch returns a JSON Merge Patch (RFC 7386) representation of the
+// patch, using json field names. Removals become null members and other
+// values are written out in full; an object value that replaces one recorded
+// in Operation.Old is written as the difference between the two, so that
+// merging it removes the members that are gone.
+//
+// A merge patch can only set and remove object members, so patches with a
+// guard, strict mode, conditions, operations other than add, replace and
+// remove, or operations on slice or array elements return an error wrapping
+// [ErrNotMergeable]. Replace the whole slice instead, for example by tagging
+// the field deep:"atomic" so that [Diff] does.
+func (p Patch[T]) ToMergePatch() ([]byte, error) {
+	if p.Guard != nil || p.Strict {
+		return nil, fmt.Errorf("ToMergePatch: guards and strict mode: %w", ErrNotMergeable)
+	}
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	var doc any = map[string]any{}
+	for i, op := range p.Operations {
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("ToMergePatch: ops[%d]: %s %s: %s: %w", i, op.Kind, op.Path, fmt.Sprintf(format, args...), ErrNotMergeable)
+		}
+		switch op.Kind {
+		case OpAdd, OpReplace, OpRemove:
+		default:
+			return nil, fail("unsupported operation")
+		}
+		if op.If != nil || op.Unless != nil {
+			return nil, fail("conditions")
+		}
+		keys, err := mergeKeys(typ, op.Path)
+		if err != nil {
+			return nil, fail("%v", err)
+		}
+
+		var val any
+		if op.Kind != OpRemove {
+			if val, err = mergeValue(op.Old, op.New); err != nil {
+				return nil, fmt.Errorf("ToMergePatch: ops[%d]: %w", i, err)
+			}
+		}
+		if len(keys) == 0 {
+			doc = val
+			continue
+		}
+		node, ok := doc.(map[string]any)
+		for _, k := range keys[:len(keys)-1] {
+			if !ok {
+				break
+			}
+			child, exists := node[k]
+			if !exists {
+				child = map[string]any{}
+				node[k] = child
+			}
+			node, ok = child.(map[string]any)
+		}
+		if !ok {
+			return nil, fail("conflicts with an earlier operation")
+		}
+		node[keys[len(keys)-1]] = val
+	}
+	return json.Marshal(doc)
+}
+
+// mergeKeys returns the member names along path in a value of type typ.
+func mergeKeys(typ reflect.Type, path string) ([]string, error) {
+	var keys []string
+	for _, part := range icore.ParsePath(path) {
+		key := part.Key
+		if key == "" && part.IsIndex {
+			key = strconv.Itoa(part.Index)
+		}
+		for typ != nil && typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		switch {
+		case typ == nil || typ.Kind() == reflect.Interface:
+			// Below an interface the path is taken as it is.
+			typ = nil
+		case typ.Kind() == reflect.Struct:
+			var next reflect.Type
+			for _, f := range icore.GetTypeInfo(typ).Fields {
+				if f.Name == key || (f.JSONTag != "" && f.JSONTag == key) {
+					name, ok := jsonFieldName(typ, f)
+					if !ok {
+						return nil, fmt.Errorf("field %s is not encoded in JSON", f.Name)
+					}
+					key, next = name, typ.Field(f.Index).Type
+					break
+				}
+			}
+			if next == nil {
+				return nil, fmt.Errorf("field %s not found in %v", key, typ)
+			}
+			typ = next
+		case typ.Kind() == reflect.Map:
+			typ = typ.Elem()
+		case typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array:
+			return nil, fmt.Errorf("%v elements cannot be addressed; replace the whole value", typ)
+		default:
+			return nil, fmt.Errorf("cannot navigate into %v at %q", typ, key)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// mergeValue returns the JSON form of newVal. If it is an object replacing
+// the object oldVal, only the members that differ are kept and removed
+// members are set to null.
+func mergeValue(oldVal, newVal any) (any, error) {
+	nv, err := toJSONValue(newVal)
+	if err != nil || oldVal == nil {
+		return nv, err
+	}
+	newObj, ok := nv.(map[string]any)
+	if !ok {
+		return nv, nil
+	}
+	ov, err := toJSONValue(oldVal)
+	if err != nil {
+		return nil, err
+	}
+	oldObj, ok := ov.(map[string]any)
+	if !ok {
+		return nv, nil
+	}
+	return mergeDiff(oldObj, newObj), nil
+}
+
+// mergeDiff returns the merge patch that turns oldObj into newObj.
+func mergeDiff(oldObj, newObj map[string]any) map[string]any {
+	res := map[string]any{}
+	for k := range oldObj {
+		if _, ok := newObj[k]; !ok {
+			res[k] = nil
+		}
+	}
+	for k, v := range newObj {
+		ov, ok := oldObj[k]
+		switch {
+		case !ok:
+			res[k] = v
+		case reflect.DeepEqual(ov, v):
+		default:
+			om, oIsObj := ov.(map[string]any)
+			nm, nIsObj := v.(map[string]any)
+			if oIsObj && nIsObj {
+				res[k] = mergeDiff(om, nm)
+			} else {
+				res[k] = v
+			}
+		}
+	}
+	return res
+}
+
+func toJSONValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var res any
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
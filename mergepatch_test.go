@@ -0,0 +1,141 @@
+package deep_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+func TestParseMergePatch(t *testing.T) {
+	current := testmodels.User{
+		ID:    1,
+		Name:  "Alice",
+		Info:  testmodels.Detail{Age: 30, Address: "Rome"},
+		Roles: []string{"admin", "user"},
+		Score: map[string]int{"a": 1, "b": 2},
+	}
+	data := `{
+		"id": 1,
+		"full_name": "Bob",
+		"info": {"addr": "Paris", "Age": null},
+		"roles": ["user"],
+		"score": {"a": null, "b": 3, "c": 4, "missing": null}
+	}`
+	p, err := deep.ParseMergePatch([]byte(data), current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unchanged members produce no operation; the rest are sorted by name.
+	want := []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/full_name", Old: "Alice", New: "Bob"},
+		{Kind: deep.OpRemove, Path: "/info/Age", Old: 30},
+		{Kind: deep.OpReplace, Path: "/info/addr", Old: "Rome", New: "Paris"},
+		{Kind: deep.OpReplace, Path: "/roles", Old: []string{"admin", "user"}, New: []string{"user"}},
+		{Kind: deep.OpRemove, Path: "/score/a", Old: 1},
+		{Kind: deep.OpReplace, Path: "/score/b", Old: 2, New: 3},
+		{Kind: deep.OpAdd, Path: "/score/c", New: 4},
+	}
+	if !reflect.DeepEqual(p.Operations, want) {
+		t.Fatalf("operations:\n got %v\nwant %v", p.Operations, want)
+	}
+
+	u := deep.Clone(current)
+	if err := deep.Apply(&u, p); err != nil {
+		t.Fatal(err)
+	}
+	expected := testmodels.User{
+		ID:    1,
+		Name:  "Bob",
+		Info:  testmodels.Detail{Address: "Paris"},
+		Roles: []string{"user"},
+		Score: map[string]int{"b": 3, "c": 4},
+	}
+	if !deep.Equal(u, expected) {
+		t.Errorf("got %+v, want %+v", u, expected)
+	}
+
+	// Reversible, since removals and replacements record the old value.
+	if err := deep.Apply(&u, p.Reverse()); err != nil || !deep.Equal(u, current) {
+		t.Errorf("reverse: %v, %+v", err, u)
+	}
+
+	// null removes a map entry even if it holds the zero value.
+	zero := testmodels.User{Score: map[string]int{"z": 0}}
+	p, err = deep.ParseMergePatch([]byte(`{"score": {"z": null}}`), zero)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []deep.Operation{{Kind: deep.OpRemove, Path: "/score/z", Old: 0}}; !reflect.DeepEqual(p.Operations, want) {
+		t.Errorf("zero entry: got %v, want %v", p.Operations, want)
+	}
+	if err := deep.Apply(&zero, p); err != nil || len(zero.Score) != 0 {
+		t.Errorf("zero entry: %v, %v", err, zero.Score)
+	}
+
+	for _, bad := range []string{
+		`{"nope": 1}`,
+		`{"id": "one"}`,
+		`{"info": {"City": "x"}}`,
+		`[1, 2]`,
+	} {
+		if _, err := deep.ParseMergePatch([]byte(bad), current); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestToMergePatch(t *testing.T) {
+	a := testmodels.User{ID: 1, Name: "Alice", Info: testmodels.Detail{Age: 30}, Score: map[string]int{"a": 1, "b": 2}}
+	b := testmodels.User{ID: 2, Name: "Alice", Info: testmodels.Detail{Age: 31, Address: "Paris"}, Score: map[string]int{"b": 3}}
+
+	p := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/ID", Old: 1, New: 2},
+		{Kind: deep.OpReplace, Path: "/Info/Age", Old: 30, New: 31},
+		{Kind: deep.OpReplace, Path: "/info/addr", Old: "", New: "Paris"},
+		{Kind: deep.OpReplace, Path: "/score", Old: a.Score, New: b.Score},
+	}}
+	data, err := p.ToMergePatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"id":2,"info":{"Age":31,"addr":"Paris"},"score":{"a":null,"b":3}}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// Merging the document into a gives b.
+	back, err := deep.ParseMergePatch(data, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := deep.Clone(a)
+	if err := deep.Apply(&u, back); err != nil || !deep.Equal(u, b) {
+		t.Errorf("round trip: %v, got %+v, want %+v", err, u, b)
+	}
+
+	removal := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpRemove, Path: "/score/a"},
+	}}
+	if data, err := removal.ToMergePatch(); err != nil || string(data) != `{"score":{"a":null}}` {
+		t.Errorf("removal: %s, %v", data, err)
+	}
+
+	idPath := deep.Field(func(u *testmodels.User) *int { return &u.ID })
+	for name, bad := range map[string]deep.Patch[testmodels.User]{
+		"move":    {Operations: []deep.Operation{{Kind: deep.OpMove, Path: "/full_name", Old: "/info/addr"}}},
+		"element": {Operations: []deep.Operation{{Kind: deep.OpReplace, Path: "/roles/0", New: "x"}}},
+		"test":    {Operations: []deep.Operation{{Kind: deep.OpTest, Path: "/id", New: 1}}},
+		"guard":   deep.Patch[testmodels.User]{}.WithGuard(deep.Eq(idPath, 1)),
+		"if":      {Operations: []deep.Operation{{Kind: deep.OpReplace, Path: "/id", New: 2, If: deep.Eq(idPath, 1)}}},
+		"conflict": {Operations: []deep.Operation{
+			{Kind: deep.OpRemove, Path: "/score"},
+			{Kind: deep.OpAdd, Path: "/score/a", New: 1},
+		}},
+	} {
+		if _, err := bad.ToMergePatch(); !errors.Is(err, deep.ErrNotMergeable) {
+			t.Errorf("%s: expected ErrNotMergeable, got %v", name, err)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package deep
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/brunoga/deep/v5/condition"
+	icore "github.com/brunoga/deep/v5/internal/core"
 	"github.com/brunoga/deep/v5/internal/engine"
 )
 
@@ -42,8 +44,14 @@ const (
 	OpMove    = engine.OpMove
 	OpCopy    = engine.OpCopy
 	OpLog     = engine.OpLog
+	OpTest    = engine.OpTest
 )
 
+// ErrTestFailed is wrapped by the error of an [OpTest] operation whose path
+// does not hold the expected value. As in RFC 6902, a failed test fails the
+// whole patch: [Apply] rolls back the operations already applied.
+var ErrTestFailed = engine.ErrTestFailed
+
 // Patch is a pure data structure representing a set of changes to type T.
 // It is designed to be easily serializable and manipulatable.
 type Patch[T any] struct {
@@ -61,13 +69,98 @@ type Patch[T any] struct {
 
 	// Strict mode enables Old value verification.
 	Strict bool `json:"strict,omitempty"`
+
+	// Signature authenticates the patch. Set via Sign; checked by Verify and
+	// by Apply with RequireSignature.
+	Signature *Signature `json:"sig,omitempty"`
 }
 
 // Operation is an alias for the internal engine operation type.
 //
-// Note: after JSON round-trip, numeric Old/New values become float64.
+// Note: an Operation decoded on its own from JSON holds float64, map[string]any
+// and []any values. Decoding a whole Patch[T] restores the Go types instead;
+// see [Patch.UnmarshalJSON].
 type Operation = engine.Operation
 
+// UnmarshalJSON decodes a patch, using the structure of T to decode the Old
+// and New values of each operation into the Go type found at its path, so
+// that a decoded patch behaves exactly like the original. Values at paths
+// that cannot be resolved statically, such as paths below an interface or
+// paths that do not exist in T, decode as they would with encoding/json.
+func (p *Patch[T]) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Guard      *condition.Condition `json:"cond"`
+		Operations []struct {
+			Kind   OpKind               `json:"k"`
+			Path   string               `json:"p"`
+			Old    json.RawMessage      `json:"o"`
+			New    json.RawMessage      `json:"n"`
+			If     *condition.Condition `json:"if"`
+			Unless *condition.Condition `json:"un"`
+		} `json:"ops"`
+		Strict    bool       `json:"strict"`
+		Signature *Signature `json:"sig"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	res := Patch[T]{Guard: raw.Guard, Strict: raw.Strict, Signature: raw.Signature}
+	for _, r := range raw.Operations {
+		op := Operation{Kind: r.Kind, Path: r.Path, If: r.If, Unless: r.Unless}
+		var err error
+		switch op.Kind {
+		case OpMove, OpCopy:
+			err = decodeRaw(r.Old, &op.Old)
+		case OpLog:
+			err = decodeRaw(r.New, &op.New)
+		default:
+			if op.Old, err = decodeValue(typ, op.Path, r.Old); err == nil {
+				op.New, err = decodeValue(typ, op.Path, r.New)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		res.Operations = append(res.Operations, op)
+	}
+	*p = res
+	return nil
+}
+
+// decodeValue decodes raw into a value of the type found at path within typ.
+// An explicit null decodes as the typed nil of a slice, map or pointer.
+func decodeValue(typ reflect.Type, path string, raw json.RawMessage) (any, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	target, err := icore.TypeAtPath(typ, path)
+	if err != nil {
+		var v any
+		return v, decodeRaw(raw, &v)
+	}
+	if string(raw) == "null" {
+		switch target.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Pointer:
+			return reflect.Zero(target).Interface(), nil
+		}
+		return nil, nil
+	}
+	v := reflect.New(target)
+	if err := json.Unmarshal(raw, v.Interface()); err != nil {
+		return nil, fmt.Errorf("decoding value at %s: %w", path, err)
+	}
+	return v.Elem().Interface(), nil
+}
+
+func decodeRaw(raw json.RawMessage, v *any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, v)
+}
+
 // IsEmpty reports whether the patch contains no operations.
 func (p Patch[T]) IsEmpty() bool {
 	return len(p.Operations) == 0
@@ -110,6 +203,8 @@ func (p Patch[T]) String() string {
 			b.WriteString(fmt.Sprintf("Copy %v to %s", op.Old, op.Path))
 		case OpLog:
 			b.WriteString(fmt.Sprintf("Log %s: %v", op.Path, op.New))
+		case OpTest:
+			b.WriteString(fmt.Sprintf("Test %s: %v", op.Path, op.New))
 		}
 	}
 	return b.String()
@@ -146,6 +241,11 @@ func (p Patch[T]) Reverse() Patch[T] {
 			// Undoing a copy means removing the copied value at the target path
 			rev.Kind = OpRemove
 			rev.Old = op.New
+		case OpTest:
+			// Once the later operations are undone the value is back to what
+			// the test saw, so the test still holds at the same point.
+			rev.Kind = OpTest
+			rev.New = op.New
 		}
 		res.Operations = append(res.Operations, rev)
 	}
@@ -180,6 +280,8 @@ func (p Patch[T]) ToJSONPatch() ([]byte, error) {
 			m["from"] = op.Old
 		case OpLog:
 			m["value"] = op.New // log message
+		case OpTest:
+			m["value"] = op.New
 		}
 
 		if op.If != nil {
@@ -196,21 +298,34 @@ func (p Patch[T]) ToJSONPatch() ([]byte, error) {
 }
 
 // ParseJSONPatch parses a JSON Patch document (RFC 6902 plus deep extensions)
-// back into a Patch[T]. This is the inverse of Patch.ToJSONPatch().
+// back into a Patch[T]. This is the inverse of Patch.ToJSONPatch(). Values are
+// decoded into the Go type found at their path in T, as with
+// [Patch.UnmarshalJSON].
 func ParseJSONPatch[T any](data []byte) (Patch[T], error) {
-	var ops []map[string]any
+	var ops []map[string]json.RawMessage
 	if err := json.Unmarshal(data, &ops); err != nil {
 		return Patch[T]{}, fmt.Errorf("ParseJSONPatch: %w", err)
 	}
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	predicate := func(raw json.RawMessage) *condition.Condition {
+		var m map[string]any
+		if json.Unmarshal(raw, &m) != nil || m == nil {
+			return nil
+		}
+		return condition.FromPredicate(m)
+	}
+
 	res := Patch[T]{}
 	for _, m := range ops {
-		opStr, _ := m["op"].(string)
-		path, _ := m["path"].(string)
-
-		// Global condition is encoded as a test op on "/" with an "if" predicate.
-		if opStr == "test" && path == "/" {
-			if ifPred, ok := m["if"].(map[string]any); ok {
-				res.Guard = condition.FromPredicate(ifPred)
+		var opStr, path string
+		_ = json.Unmarshal(m["op"], &opStr)
+		_ = json.Unmarshal(m["path"], &path)
+
+		// Global condition is encoded as a test op on "/" with an "if"
+		// predicate and no value; any other test is an OpTest.
+		if _, hasValue := m["value"]; opStr == "test" && path == "/" && m["if"] != nil && !hasValue {
+			if c := predicate(m["if"]); c != nil {
+				res.Guard = c
 			}
 			continue
 		}
@@ -218,34 +333,41 @@ func ParseJSONPatch[T any](data []byte) (Patch[T], error) {
 		op := Operation{Path: path}
 
 		// Per-op conditions
-		if ifPred, ok := m["if"].(map[string]any); ok {
-			op.If = condition.FromPredicate(ifPred)
-		}
-		if unlessPred, ok := m["unless"].(map[string]any); ok {
-			op.Unless = condition.FromPredicate(unlessPred)
-		}
+		op.If = predicate(m["if"])
+		op.Unless = predicate(m["unless"])
 
+		var err error
 		switch opStr {
 		case "add":
 			op.Kind = OpAdd
-			op.New = m["value"]
+			op.New, err = decodeValue(typ, path, m["value"])
 		case "remove":
 			op.Kind = OpRemove
 		case "replace":
 			op.Kind = OpReplace
-			op.New = m["value"]
+			op.New, err = decodeValue(typ, path, m["value"])
 		case "move":
 			op.Kind = OpMove
-			op.Old = m["from"]
+			err = decodeRaw(m["from"], &op.Old)
 		case "copy":
 			op.Kind = OpCopy
-			op.Old = m["from"]
+			err = decodeRaw(m["from"], &op.Old)
 		case "log":
 			op.Kind = OpLog
-			op.New = m["value"]
+			err = decodeRaw(m["value"], &op.New)
+		case "test":
+			op.Kind = OpTest
+			if _, ok := m["value"]; !ok {
+				err = fmt.Errorf("test at %s has no value", path)
+				break
+			}
+			op.New, err = decodeValue(typ, path, m["value"])
 		default:
 			continue // unknown op, skip
 		}
+		if err != nil {
+			return Patch[T]{}, fmt.Errorf("ParseJSONPatch: %w", err)
+		}
 
 		res.Operations = append(res.Operations, op)
 	}
@@ -260,7 +382,7 @@ func Edit[T any](_ *T) *Builder[T] {
 }
 
 // Op is a pending patch operation. Obtain one from [Set], [Add], [Remove],
-// [Move], or [Copy]; attach per-operation conditions with [Op.If] or
+// [Move], [Copy] or [Test]; attach per-operation conditions with [Op.If] or
 // [Op.Unless] before passing to [Builder.With].
 type Op struct {
 	op Operation
@@ -305,6 +427,12 @@ func Copy[T, V any](from, to Path[T, V]) Op {
 	return Op{op: Operation{Kind: OpCopy, Path: to.String(), Old: from.String()}}
 }
 
+// Test returns a type-safe test operation that fails the patch unless the
+// value at p equals val (see [ErrTestFailed]).
+func Test[T, V any](p Path[T, V], val V) Op {
+	return Op{op: Operation{Kind: OpTest, Path: p.String(), New: val}}
+}
+
 // Builder constructs a [Patch] via a fluent chain.
 type Builder[T any] struct {
 	global *condition.Condition
@@ -325,7 +453,7 @@ func (b *Builder[T]) Guard(c *condition.Condition) *Builder[T] {
 
 // With appends one or more operations to the patch being built.
 // Obtain operations from the typed constructors [Set], [Add], [Remove],
-// [Move], and [Copy]; per-operation conditions can be attached with
+// [Move], [Copy] and [Test]; per-operation conditions can be attached with
 // [Op.If] and [Op.Unless] before passing here.
 func (b *Builder[T]) With(ops ...Op) *Builder[T] {
 	for _, o := range ops {
@@ -402,6 +530,46 @@ func Type[T, V any](p Path[T, V], typeName string) *condition.Condition {
 	return &condition.Condition{Path: p.String(), Op: condition.Type, Value: typeName}
 }
 
+// StartsWith creates a condition that checks if a string starts with prefix.
+func StartsWith[T any, V ~string](p Path[T, V], prefix string) *condition.Condition {
+	return &condition.Condition{Path: p.String(), Op: condition.StartsWith, Value: prefix}
+}
+
+// EndsWith creates a condition that checks if a string ends with suffix.
+func EndsWith[T any, V ~string](p Path[T, V], suffix string) *condition.Condition {
+	return &condition.Condition{Path: p.String(), Op: condition.EndsWith, Value: suffix}
+}
+
+// Contains creates a condition that checks if a string contains substr.
+func Contains[T any, V ~string](p Path[T, V], substr string) *condition.Condition {
+	return &condition.Condition{Path: p.String(), Op: condition.Contains, Value: substr}
+}
+
+// Includes creates a condition that checks if a slice contains an element
+// equal to e.
+func Includes[T any, S ~[]E, E any](p Path[T, S], e E) *condition.Condition {
+	return &condition.Condition{Path: p.String(), Op: condition.Contains, Value: e}
+}
+
+// Between creates a condition that checks if low <= value <= high.
+func Between[T, V any](p Path[T, V], low, high V) *condition.Condition {
+	return &condition.Condition{Path: p.String(), Op: condition.Between, Value: []any{low, high}}
+}
+
+// Len creates a condition that compares the length of a string, slice or map
+// with n. op is one of the comparison operators ([condition.Eq],
+// [condition.Gt], ...).
+func Len[T, V any](p Path[T, V], op string, n int) *condition.Condition {
+	return &condition.Condition{Path: p.String(), Op: "len" + op, Value: n}
+}
+
+// CompareFields creates a condition that compares the values at two paths,
+// such as a ship date that must not precede the order date. op is one of the
+// comparison operators ([condition.Eq], [condition.Gt], ...).
+func CompareFields[T, V any](p Path[T, V], op string, other Path[T, V]) *condition.Condition {
+	return &condition.Condition{Path: p.String(), Op: op, Ref: other.String()}
+}
+
 // And combines multiple conditions with logical AND.
 func And(conds ...*condition.Condition) *condition.Condition {
 	return &condition.Condition{Op: condition.And, Sub: conds}
@@ -416,3 +584,21 @@ func Or(conds ...*condition.Condition) *condition.Condition {
 func Not(c *condition.Condition) *condition.Condition {
 	return &condition.Condition{Op: condition.Not, Sub: []*condition.Condition{c}}
 }
+
+// Any creates a condition that holds if c holds for some element matched by
+// the first wildcard in its paths (see [Each] and [EachValue]).
+func Any(c *condition.Condition) *condition.Condition {
+	return &condition.Condition{Op: condition.Any, Sub: []*condition.Condition{c}}
+}
+
+// All creates a condition that holds if c holds for every element matched by
+// the first wildcard in its paths.
+func All(c *condition.Condition) *condition.Condition {
+	return &condition.Condition{Op: condition.All, Sub: []*condition.Condition{c}}
+}
+
+// None creates a condition that holds if c holds for no element matched by
+// the first wildcard in its paths.
+func None(c *condition.Condition) *condition.Condition {
+	return &condition.Condition{Op: condition.None, Sub: []*condition.Condition{c}}
+}
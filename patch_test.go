@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"encoding/gob"
 	"encoding/json"
+	"errors"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/brunoga/deep/v5"
 	"github.com/brunoga/deep/v5/condition"
@@ -245,6 +248,71 @@ func TestParseJSONPatchRoundTrip(t *testing.T) {
 	}
 }
 
+func TestPatchUnmarshalJSONTypes(t *testing.T) {
+	type Part struct {
+		Name string
+		Qty  uint32
+	}
+	type Order struct {
+		ID      uint32
+		Created time.Time
+		Part    Part
+		Parts   []Part
+		Prices  map[string]float32
+		Meta    any
+	}
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	p := deep.Patch[Order]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/ID", Old: uint32(1), New: uint32(2)},
+		{Kind: deep.OpReplace, Path: "/Created", Old: time.Time{}, New: created},
+		{Kind: deep.OpReplace, Path: "/Part", Old: Part{}, New: Part{Name: "bolt", Qty: 3}},
+		{Kind: deep.OpReplace, Path: "/Parts", Old: []Part(nil), New: []Part{{Name: "nut", Qty: 1}}},
+		{Kind: deep.OpAdd, Path: "/Parts/1", New: Part{Name: "washer", Qty: 9}},
+		{Kind: deep.OpAdd, Path: "/Prices/bolt", New: float32(1.5)},
+		{Kind: deep.OpReplace, Path: "/Meta", New: map[string]any{"k": "v"}},
+		{Kind: deep.OpMove, Path: "/Prices/nut", Old: "/Prices/bolt"},
+	}}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got deep.Patch[Order]
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	for i, op := range got.Operations {
+		want := p.Operations[i]
+		if !reflect.DeepEqual(op.Old, want.Old) || !reflect.DeepEqual(op.New, want.New) {
+			t.Errorf("op %d: got (%T %v, %T %v), want (%T %v, %T %v)",
+				i, op.Old, op.Old, op.New, op.New, want.Old, want.Old, want.New, want.New)
+		}
+	}
+
+	// Strict mode works on the decoded patch.
+	o := Order{ID: 1}
+	if err := deep.Apply(&o, got.AsStrict()); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if o.ID != 2 || !o.Created.Equal(created) || len(o.Parts) != 2 || o.Prices["nut"] != 1.5 {
+		t.Errorf("unexpected result: %+v", o)
+	}
+
+	// ParseJSONPatch decodes values the same way.
+	jp, err := p.ToJSONPatch()
+	if err != nil {
+		t.Fatalf("ToJSONPatch failed: %v", err)
+	}
+	parsed, err := deep.ParseJSONPatch[Order](jp)
+	if err != nil {
+		t.Fatalf("ParseJSONPatch failed: %v", err)
+	}
+	if v, ok := parsed.Operations[0].New.(uint32); !ok || v != 2 {
+		t.Errorf("ParseJSONPatch value: %T %v", parsed.Operations[0].New, parsed.Operations[0].New)
+	}
+}
+
 func TestGeLeConditions(t *testing.T) {
 	type S struct{ X int }
 	xPath := deep.Field[S, int](func(s *S) *int { return &s.X })
@@ -305,3 +373,331 @@ func TestLWWSet(t *testing.T) {
 		t.Error("LWW.Set should reject older timestamp")
 	}
 }
+
+func TestQuantifierConditions(t *testing.T) {
+	// Generated evaluator.
+	u := testmodels.User{ID: 1, Roles: []string{"dev", "admin"}, Score: map[string]int{"a": 1, "b": 5}}
+	rolesPath := deep.Field(func(u *testmodels.User) *[]string { return &u.Roles })
+	scorePath := deep.Field(func(u *testmodels.User) *map[string]int { return &u.Score })
+	idPath := deep.Field(func(u *testmodels.User) *int { return &u.ID })
+
+	for _, tt := range []struct {
+		c    *condition.Condition
+		want bool
+	}{
+		{deep.Any(deep.Eq(deep.Each(rolesPath), "admin")), true},
+		{deep.All(deep.Eq(deep.Each(rolesPath), "admin")), false},
+		{deep.None(deep.Eq(deep.Each(rolesPath), "root")), true},
+		{deep.All(deep.Gt(deep.EachValue(scorePath), 0)), true},
+		{deep.Any(deep.Gt(deep.EachValue(scorePath), 3)), true},
+		{deep.Gt(deep.EachValue(scorePath), 3), false}, // unquantified: every value
+	} {
+		p := deep.Edit(&u).With(deep.Set(idPath, 2).If(tt.c)).Build()
+		v := u
+		if err := deep.Apply(&v, p); err != nil {
+			t.Fatal(err)
+		}
+		if got := v.ID == 2; got != tt.want {
+			t.Errorf("%s %+v: applied = %v, want %v", tt.c.Op, tt.c.Sub, got, tt.want)
+		}
+	}
+
+	// Reflection evaluator, with paths bound to the same element.
+	type Item struct {
+		SKU string `deep:"key"`
+		Qty int
+		Ok  bool
+	}
+	type Order struct {
+		Items []Item
+		Note  string
+	}
+	itemsPath := deep.Field(func(o *Order) *[]Item { return &o.Items })
+	qtyPath := deep.Field(func(i *Item) *int { return &i.Qty })
+	okPath := deep.Field(func(i *Item) *bool { return &i.Ok })
+	notePath := deep.Field(func(o *Order) *string { return &o.Note })
+	each := deep.Each(itemsPath)
+
+	o := Order{Items: []Item{{SKU: "a", Qty: 0, Ok: true}, {SKU: "b", Qty: 3}}}
+	guard := deep.None(deep.And(
+		deep.Gt(deep.Join(each, qtyPath), 0),
+		deep.Eq(deep.Join(each, okPath), true),
+	))
+	p := deep.Edit(&o).Guard(guard).With(deep.Set(notePath, "checked")).Build()
+	if err := deep.Validate(p); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := deep.Apply(&o, p); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if o.Note != "checked" {
+		t.Error("guard should hold: no item has both Qty > 0 and Ok")
+	}
+
+	o.Items[1].Ok = true
+	if err := deep.Apply(&o, p); err == nil {
+		t.Error("guard should fail: item b has Qty > 0 and Ok")
+	}
+
+	// Quantifiers survive the predicate wire format.
+	data, err := p.ToJSONPatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := deep.ParseJSONPatch[Order](data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(back.Guard.ToPredicate())
+	want, _ := json.Marshal(guard.ToPredicate())
+	if !bytes.Equal(got, want) {
+		t.Errorf("round trip: %s, want %s", got, want)
+	}
+}
+
+func TestRichConditions(t *testing.T) {
+	u := testmodels.User{ID: 5, Name: "Alice Smith", Roles: []string{"dev", "admin"}}
+	idPath := deep.Field(func(u *testmodels.User) *int { return &u.ID })
+	namePath := deep.Field(func(u *testmodels.User) *string { return &u.Name })
+	rolesPath := deep.Field(func(u *testmodels.User) *[]string { return &u.Roles })
+
+	for _, tt := range []struct {
+		c    *condition.Condition
+		want bool
+	}{
+		{deep.StartsWith(namePath, "Alice"), true},
+		{deep.EndsWith(namePath, "Jones"), false},
+		{deep.Contains(namePath, "ce Sm"), true},
+		{deep.Includes(rolesPath, "admin"), true},
+		{deep.Includes(rolesPath, "root"), false},
+		{deep.Between(idPath, 1, 5), true},
+		{deep.Between(idPath, 6, 9), false},
+		{deep.Len(namePath, condition.Eq, 11), true},
+		{deep.Len(rolesPath, condition.Ge, 3), false},
+		{deep.CompareFields(idPath, condition.Lt, idPath), false},
+	} {
+		// Generated and reflection evaluators agree.
+		p := deep.Edit(&u).With(deep.Set(idPath, 2).If(tt.c)).Build()
+		v := u
+		if err := deep.Apply(&v, p); err != nil {
+			t.Fatal(err)
+		}
+		got, err := condition.Evaluate(reflect.ValueOf(u), tt.c)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.c.Op, tt.c.Path, err)
+		}
+		if applied := v.ID == 2; applied != tt.want || got != tt.want {
+			t.Errorf("%s %s: applied = %v, evaluated = %v, want %v", tt.c.Op, tt.c.Path, applied, got, tt.want)
+		}
+	}
+
+	type Order struct {
+		OrderDate time.Time
+		ShipDate  time.Time
+		Status    string
+	}
+	orderPath := deep.Field(func(o *Order) *time.Time { return &o.OrderDate })
+	shipPath := deep.Field(func(o *Order) *time.Time { return &o.ShipDate })
+	statusPath := deep.Field(func(o *Order) *string { return &o.Status })
+
+	now := time.Now()
+	o := Order{OrderDate: now, ShipDate: now.Add(time.Hour)}
+	p := deep.Edit(&o).
+		Guard(deep.CompareFields(shipPath, condition.Ge, orderPath)).
+		With(deep.Set(statusPath, "shipped")).
+		Build()
+
+	// The reference survives both wire formats.
+	data, err := p.ToBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromBinary deep.Patch[Order]
+	if err := fromBinary.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	data, err = p.ToJSONPatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := deep.ParseJSONPatch[Order](data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []deep.Patch[Order]{fromBinary, fromJSON} {
+		if q.Guard == nil || q.Guard.Ref != "/OrderDate" {
+			t.Fatalf("guard lost its reference: %+v", q.Guard)
+		}
+	}
+
+	if err := deep.Apply(&o, fromJSON); err != nil || o.Status != "shipped" {
+		t.Fatalf("Apply: %v, status %q", err, o.Status)
+	}
+	o.ShipDate = now.Add(-time.Hour)
+	o.Status = ""
+	if err := deep.Apply(&o, p); err == nil {
+		t.Error("guard should fail when shipping before ordering")
+	}
+}
+
+func TestPatchJSONTextConditions(t *testing.T) {
+	data := `{
+		"cond": "/full_name startsWith 'A' && len(/roles) > 0",
+		"ops": [
+			{"k": 2, "p": "/id", "n": 7, "if": "any(/roles/* == 'admin')"},
+			{"k": 2, "p": "/full_name", "n": "Bob", "un": "/id == 7"}
+		]
+	}`
+	var p deep.Patch[testmodels.User]
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Guard.String(); got != `/full_name startsWith 'A' && len(/roles) > 0` {
+		t.Errorf("guard = %s", got)
+	}
+
+	u := testmodels.User{ID: 1, Name: "Alice", Roles: []string{"admin"}}
+	if err := deep.Apply(&u, p); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 7 || u.Name != "Alice" {
+		t.Errorf("got %+v", u)
+	}
+}
+
+func TestTestOperation(t *testing.T) {
+	idPath := deep.Field(func(u *testmodels.User) *int { return &u.ID })
+	namePath := deep.Field(func(u *testmodels.User) *string { return &u.Name })
+	cityPath := deep.Field(func(u *testmodels.User) *string { return &u.Info.Address })
+
+	// Generated fast path.
+	u := testmodels.User{ID: 1, Name: "Alice"}
+	p := deep.Edit(&u).With(
+		deep.Test(idPath, 1),
+		deep.Set(namePath, "Bob"),
+		deep.Test(namePath, "Bob"),
+	).Build()
+	if err := deep.Apply(&u, p); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if u.Name != "Bob" {
+		t.Errorf("Name = %q, want Bob", u.Name)
+	}
+
+	// A failed test rolls back the operations before it and stops the rest.
+	failing := deep.Edit(&u).With(
+		deep.Set(namePath, "Carol"),
+		deep.Test(idPath, 2),
+		deep.Set(cityPath, "Paris"),
+	).Build()
+	err := deep.Apply(&u, failing)
+	if !errors.Is(err, deep.ErrTestFailed) {
+		t.Fatalf("expected ErrTestFailed, got %v", err)
+	}
+	if u.Name != "Bob" || u.Info.Address != "" {
+		t.Errorf("patch not rolled back: %+v", u)
+	}
+
+	// Reflection path, including paths the generated code delegates.
+	type Line struct {
+		Qty int
+	}
+	type Doc struct {
+		Lines []Line
+		Tags  map[string]string
+		Note  *string
+	}
+	d := Doc{Lines: []Line{{Qty: 2}}, Tags: map[string]string{"a": "x"}}
+	ok := deep.Patch[Doc]{Operations: []deep.Operation{
+		{Kind: deep.OpTest, Path: "/Lines/0/Qty", New: 2.0},
+		{Kind: deep.OpTest, Path: "/Tags/a", New: "x"},
+		{Kind: deep.OpTest, Path: "/Note", New: nil},
+		{Kind: deep.OpReplace, Path: "/Lines/0/Qty", New: 3},
+	}}
+	if err := deep.Apply(&d, ok); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	for _, op := range []deep.Operation{
+		{Kind: deep.OpTest, Path: "/Lines/0/Qty", New: 2},
+		{Kind: deep.OpTest, Path: "/Tags/missing", New: "x"},
+		{Kind: deep.OpTest, Path: "/Lines/5/Qty", New: 3},
+	} {
+		bad := deep.Patch[Doc]{Operations: []deep.Operation{
+			{Kind: deep.OpReplace, Path: "/Lines/0/Qty", New: 9},
+			op,
+		}}
+		rep, err := deep.ApplyWithReport(&d, bad)
+		if !errors.Is(err, deep.ErrTestFailed) {
+			t.Errorf("%s: expected ErrTestFailed, got %v", op.Path, err)
+		}
+		if d.Lines[0].Qty != 3 {
+			t.Errorf("%s: patch not rolled back: %+v", op.Path, d)
+		}
+		if len(rep.Results) != 2 || rep.Results[0].Status != deep.StatusRolledBack || rep.Results[1].Status != deep.StatusFailed {
+			t.Errorf("%s: unexpected report %+v", op.Path, rep.Results)
+		}
+	}
+
+	// Reverse keeps the test where it is relative to the other operations.
+	fwd := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpTest, Path: "/full_name", New: "Bob"},
+		{Kind: deep.OpReplace, Path: "/full_name", Old: "Bob", New: "Dan"},
+		{Kind: deep.OpTest, Path: "/id", New: 1},
+	}}
+	if err := deep.Apply(&u, fwd); err != nil || u.Name != "Dan" {
+		t.Fatalf("forward: %v, %+v", err, u)
+	}
+	rev := fwd.Reverse()
+	if len(rev.Operations) != 3 || rev.Operations[0].Kind != deep.OpTest || rev.Operations[2].Kind != deep.OpTest {
+		t.Errorf("unexpected reverse: %v", rev)
+	}
+	if err := deep.Apply(&u, rev); err != nil || u.Name != "Bob" {
+		t.Errorf("reverse: %v, %+v", err, u)
+	}
+}
+
+func TestParseJSONPatchTest(t *testing.T) {
+	// Standard RFC 6902 tests are kept; a test on "/" carrying only an "if"
+	// predicate is still the global guard.
+	data := `[
+		{"op": "test", "path": "/", "if": {"op": "defined", "path": "/id"}},
+		{"op": "test", "path": "/id", "value": 1},
+		{"op": "test", "path": "/roles", "value": ["admin"]},
+		{"op": "replace", "path": "/full_name", "value": "Bob"}
+	]`
+	p, err := deep.ParseJSONPatch[testmodels.User]([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Guard == nil || len(p.Operations) != 3 || p.Operations[0].Kind != deep.OpTest || p.Operations[1].Kind != deep.OpTest {
+		t.Fatalf("unexpected patch: guard %v, ops %v", p.Guard, p)
+	}
+	if _, ok := p.Operations[1].New.([]string); !ok {
+		t.Errorf("test value decoded as %T, want []string", p.Operations[1].New)
+	}
+
+	u := testmodels.User{ID: 1, Name: "Alice", Roles: []string{"admin"}}
+	if err := deep.Apply(&u, p); err != nil || u.Name != "Bob" {
+		t.Fatalf("Apply: %v, %+v", err, u)
+	}
+	u = testmodels.User{ID: 1, Name: "Alice", Roles: []string{"user"}}
+	if err := deep.Apply(&u, p); !errors.Is(err, deep.ErrTestFailed) || u.Name != "Alice" {
+		t.Errorf("Apply with failing test: %v, %+v", err, u)
+	}
+
+	out, err := p.ToJSONPatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := deep.ParseJSONPatch[testmodels.User](out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rt.Operations, p.Operations) {
+		t.Errorf("round trip:\n got %v\nwant %v", rt.Operations, p.Operations)
+	}
+
+	if _, err := deep.ParseJSONPatch[testmodels.User]([]byte(`[{"op": "test", "path": "/id"}]`)); err == nil {
+		t.Error("expected an error for a test without a value")
+	}
+}
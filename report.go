@@ -0,0 +1,276 @@
+package deep
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// OpStatus is the outcome of a single operation in an [ApplyWithReport] call.
+type OpStatus int
+
+const (
+	// StatusApplied means the operation was applied.
+	StatusApplied OpStatus = iota
+	// StatusSkipped means the operation's If/Unless condition did not hold,
+	// or could not be evaluated under [SkipOnConditionError].
+	StatusSkipped
+	// StatusFailed means the operation, or the evaluation of its condition,
+	// returned an error.
+	StatusFailed
+	// StatusRolledBack means the operation was applied but then undone because
+	// a later operation failed under [Atomic] or [AbortOnConditionError].
+	StatusRolledBack
+)
+
+func (s OpStatus) String() string {
+	switch s {
+	case StatusApplied:
+		return "applied"
+	case StatusSkipped:
+		return "skipped"
+	case StatusFailed:
+		return "failed"
+	case StatusRolledBack:
+		return "rolled back"
+	default:
+		return fmt.Sprintf("OpStatus(%d)", int(s))
+	}
+}
+
+// OpResult records what happened to one operation of a patch.
+type OpResult struct {
+	// Op is the operation as it appears in the patch.
+	Op Operation
+
+	Status OpStatus
+
+	// Conditional reports whether the operation has an If or Unless
+	// condition. ConditionMet is the combined outcome of both; it is false if
+	// evaluation failed, in which case Err holds the evaluation error.
+	Conditional  bool
+	ConditionMet bool
+
+	// Err is the error that caused the operation to fail or be skipped.
+	Err error
+
+	// Old is the value at Op.Path before the operation ran, or nil if there
+	// was none.
+	Old any
+}
+
+// Report lists the outcome of every operation considered by
+// [ApplyWithReport], in patch order.
+type Report struct {
+	Results []OpResult
+}
+
+// Applied returns the operations that were applied and not rolled back.
+func (r Report) Applied() []Operation {
+	var res []Operation
+	for _, op := range r.Results {
+		if op.Status == StatusApplied {
+			res = append(res, op.Op)
+		}
+	}
+	return res
+}
+
+// ConditionErrorPolicy selects how an error while evaluating a per-operation
+// If/Unless condition is handled.
+type ConditionErrorPolicy int
+
+const (
+	// SkipOnConditionError skips the operation, as if its condition did not
+	// hold. This is the default.
+	SkipOnConditionError ConditionErrorPolicy = iota
+	// FailOnConditionError fails the operation; the remaining operations are
+	// still applied unless [Atomic] is set.
+	FailOnConditionError
+	// AbortOnConditionError fails the whole patch: no further operation is
+	// applied and those already applied are rolled back.
+	AbortOnConditionError
+)
+
+// OnConditionError sets the policy for condition evaluation errors in [Apply]
+// and [ApplyWithReport].
+func OnConditionError(p ConditionErrorPolicy) ApplyOption {
+	return func(c *applyConfig) { c.condPolicy = p }
+}
+
+// ApplyWithReport applies p to target like [Apply] and additionally reports,
+// for each operation, whether it was applied, skipped by its condition or
+// failed, together with the value it overwrote.
+//
+// Per-operation conditions are evaluated one at a time so that their outcome
+// can be recorded; both they and the operations use the generated fast path
+// when available. If the guard is not met no operation is considered and the
+// returned report is empty.
+func ApplyWithReport[T any](target *T, p Patch[T], opts ...ApplyOption) (Report, error) {
+	if target == nil {
+		return Report{}, fmt.Errorf("target must be a non-nil pointer")
+	}
+	return applySteps(target, p, newApplyConfig(opts...))
+}
+
+// Preview returns the value target would have after applying p, with a
+// report of what each operation would do, without modifying target. The guard
+// and every per-operation condition are evaluated as [ApplyWithReport] would.
+// The copy is made with [Clone], so generated types stay on the fast path.
+func Preview[T any](target T, p Patch[T], opts ...ApplyOption) (T, Report, error) {
+	res := Clone(target)
+	rep, err := applySteps(&res, p, newApplyConfig(opts...))
+	return res, rep, err
+}
+
+// applySteps applies the operations of p one at a time, recording the outcome
+// of each. When the configuration requires it, the values each operation
+// overwrites are saved so that a failure can roll back the whole patch.
+func applySteps[T any](target *T, p Patch[T], cfg applyConfig) (Report, error) {
+	var rep Report
+	if err := verifyPatch(p, cfg); err != nil {
+		return rep, err
+	}
+	if err := checkGuard(target, p.Guard, cfg.logger); err != nil {
+		return rep, err
+	}
+
+	root := reflect.ValueOf(target).Elem()
+	typ := root.Type()
+	transactional := cfg.atomic || cfg.condPolicy == AbortOnConditionError || hasTest(p)
+
+	var undo []undoEntry
+	var errs []error
+	abort := func(err error) (Report, error) {
+		rollback(root, undo)
+		for i := range rep.Results {
+			if rep.Results[i].Status == StatusApplied {
+				rep.Results[i].Status = StatusRolledBack
+			}
+		}
+		return rep, &ApplyError{Errors: []error{err}}
+	}
+
+	for _, op := range p.Operations {
+		op.Strict = p.Strict
+		res := OpResult{Op: op}
+		if op.Kind != OpLog {
+			res.Old = valueAtPath(target, op.Path)
+		}
+
+		if op.If != nil || op.Unless != nil {
+			res.Conditional = true
+			ok, err := evaluateOpCondition(target, op)
+			res.ConditionMet = ok
+			if err != nil {
+				err = fmt.Errorf("condition evaluation failed at %s: %w", op.Path, err)
+				res.Err = err
+				switch cfg.condPolicy {
+				case FailOnConditionError:
+					res.Status = StatusFailed
+					rep.Results = append(rep.Results, res)
+					if cfg.atomic {
+						return abort(err)
+					}
+					errs = append(errs, err)
+				case AbortOnConditionError:
+					res.Status = StatusFailed
+					rep.Results = append(rep.Results, res)
+					return abort(err)
+				default:
+					res.Status = StatusSkipped
+					rep.Results = append(rep.Results, res)
+				}
+				continue
+			}
+			if !ok {
+				res.Status = StatusSkipped
+				rep.Results = append(rep.Results, res)
+				continue
+			}
+			op.If, op.Unless = nil, nil
+		}
+
+		old := res.Old
+		if len(cfg.before) > 0 {
+			var ok bool
+			var err error
+			op, old, ok, err = runBeforeHooks(cfg.before, target, op, old)
+			if err != nil {
+				res.Status = StatusFailed
+				res.Err = err
+				rep.Results = append(rep.Results, res)
+				if cfg.atomic {
+					return abort(err)
+				}
+				errs = append(errs, err)
+				continue
+			}
+			if !ok {
+				res.Status = StatusSkipped
+				res.Err = ErrSkipOp
+				rep.Results = append(rep.Results, res)
+				continue
+			}
+		}
+
+		var saved []undoEntry
+		if transactional || len(cfg.after) > 0 {
+			saved = snapshotOp(root, typ, op)
+		}
+		if transactional {
+			undo = append(undo, saved...)
+		}
+		err := applyStep(target, op, cfg.logger)
+		if err == nil {
+			if err = runAfterHooks(cfg.after, target, op, old); err != nil {
+				rollback(root, saved)
+			}
+		}
+		if err != nil {
+			res.Status = StatusFailed
+			res.Err = err
+			rep.Results = append(rep.Results, res)
+			if cfg.atomic || errors.Is(err, ErrTestFailed) {
+				return abort(err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		res.Status = StatusApplied
+		rep.Results = append(rep.Results, res)
+	}
+
+	if len(errs) > 0 {
+		return rep, &ApplyError{Errors: errs}
+	}
+	return rep, nil
+}
+
+// hasTest reports whether p has an [OpTest] operation.
+func hasTest[T any](p Patch[T]) bool {
+	for _, op := range p.Operations {
+		if op.Kind == OpTest {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateOpCondition reports whether op's If condition holds and its Unless
+// condition does not.
+func evaluateOpCondition[T any](target *T, op Operation) (bool, error) {
+	if op.If != nil {
+		ok, err := evaluate(target, op.If)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if op.Unless != nil {
+		ok, err := evaluate(target, op.Unless)
+		if err != nil || ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,155 @@
+package deep_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/condition"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+func reportPatch() deep.Patch[testmodels.User] {
+	idIs1 := &condition.Condition{Path: "/id", Op: condition.Eq, Value: 1}
+	broken := &condition.Condition{Path: "/nope", Op: condition.Eq, Value: 1}
+	return deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/full_name", New: "Bob", If: idIs1},
+		{Kind: deep.OpReplace, Path: "/info/Age", New: 99, Unless: idIs1},
+		{Kind: deep.OpReplace, Path: "/info/addr", New: "Work", If: broken},
+		{Kind: deep.OpReplace, Path: "/missing", New: 1},
+	}}
+}
+
+func TestApplyWithReport(t *testing.T) {
+	u := testmodels.User{ID: 1, Name: "Alice", Info: testmodels.Detail{Age: 30, Address: "Home"}}
+
+	rep, err := deep.ApplyWithReport(&u, reportPatch())
+	if err == nil {
+		t.Fatal("expected error from failing operation")
+	}
+	if len(rep.Results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(rep.Results))
+	}
+
+	want := []deep.OpStatus{deep.StatusApplied, deep.StatusSkipped, deep.StatusSkipped, deep.StatusFailed}
+	for i, r := range rep.Results {
+		if r.Status != want[i] {
+			t.Errorf("result %d: status %v, want %v", i, r.Status, want[i])
+		}
+	}
+
+	r := rep.Results[0]
+	if !r.Conditional || !r.ConditionMet || r.Old != "Alice" {
+		t.Errorf("unexpected result for applied op: %+v", r)
+	}
+	if r := rep.Results[1]; !r.Conditional || r.ConditionMet || r.Err != nil {
+		t.Errorf("unexpected result for unmet condition: %+v", r)
+	}
+	if r := rep.Results[2]; r.Err == nil {
+		t.Errorf("expected condition evaluation error: %+v", r)
+	}
+	if r := rep.Results[3]; r.Conditional || r.Err == nil {
+		t.Errorf("unexpected result for failed op: %+v", r)
+	}
+	if u.Name != "Bob" || u.Info.Age != 30 || u.Info.Address != "Home" {
+		t.Errorf("unexpected target: %+v", u)
+	}
+	if ops := rep.Applied(); len(ops) != 1 || ops[0].Path != "/full_name" {
+		t.Errorf("Applied() = %v", ops)
+	}
+}
+
+func TestApplyConditionErrorPolicy(t *testing.T) {
+	orig := testmodels.User{ID: 1, Name: "Alice", Info: testmodels.Detail{Age: 30, Address: "Home"}}
+
+	u := deep.Clone(orig)
+	rep, err := deep.ApplyWithReport(&u, reportPatch(), deep.OnConditionError(deep.FailOnConditionError))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if rep.Results[2].Status != deep.StatusFailed || rep.Results[3].Status != deep.StatusFailed {
+		t.Errorf("unexpected statuses: %v, %v", rep.Results[2].Status, rep.Results[3].Status)
+	}
+	if u.Name != "Bob" {
+		t.Errorf("earlier operation should stay applied: %+v", u)
+	}
+
+	u = deep.Clone(orig)
+	rep, err = deep.ApplyWithReport(&u, reportPatch(), deep.OnConditionError(deep.AbortOnConditionError))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(rep.Results) != 3 || rep.Results[0].Status != deep.StatusRolledBack || rep.Results[2].Status != deep.StatusFailed {
+		t.Errorf("unexpected report: %+v", rep.Results)
+	}
+	if !deep.Equal(u, orig) {
+		t.Errorf("target modified after aborted patch: %+v", u)
+	}
+
+	// The policy also applies to plain Apply.
+	u = deep.Clone(orig)
+	if err := deep.Apply(&u, reportPatch(), deep.OnConditionError(deep.AbortOnConditionError)); err == nil {
+		t.Fatal("expected error from Apply")
+	}
+	if !deep.Equal(u, orig) {
+		t.Errorf("target modified after aborted Apply: %+v", u)
+	}
+}
+
+// evalCounter counts the conditions evaluated through its generated-style
+// EvaluateCondition method.
+type evalCounter struct {
+	N     int
+	calls int
+}
+
+func (c *evalCounter) EvaluateCondition(cond condition.Condition) (bool, error) {
+	c.calls++
+	return condition.Evaluate(reflect.ValueOf(c).Elem(), &cond)
+}
+
+func TestApplyWithReportUsesEvaluateCondition(t *testing.T) {
+	nIs1 := &condition.Condition{Path: "/N", Op: condition.Eq, Value: 1}
+	v := evalCounter{N: 1}
+	rep, err := deep.ApplyWithReport(&v, deep.Patch[evalCounter]{
+		Guard: nIs1,
+		Operations: []deep.Operation{
+			{Kind: deep.OpReplace, Path: "/N", New: 2, If: nIs1},
+			{Kind: deep.OpReplace, Path: "/N", New: 3, Unless: nIs1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.calls != 3 || v.N != 3 || rep.Results[0].Status != deep.StatusApplied {
+		t.Errorf("calls = %d, N = %d, report %+v", v.calls, v.N, rep.Results)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	u := testmodels.User{ID: 1, Name: "Alice", Score: map[string]int{"a": 1}}
+	p := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/full_name", New: "Bob"},
+		{Kind: deep.OpAdd, Path: "/score/b", New: 2},
+		{Kind: deep.OpReplace, Path: "/id", New: 2, If: &condition.Condition{Path: "/id", Op: condition.Eq, Value: 5}},
+	}}
+
+	got, rep, err := deep.Preview(u, p)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if got.Name != "Bob" || got.Score["b"] != 2 || got.ID != 1 {
+		t.Errorf("unexpected preview: %+v", got)
+	}
+	if u.Name != "Alice" || len(u.Score) != 1 {
+		t.Errorf("target modified by Preview: %+v", u)
+	}
+	if len(rep.Results) != 3 || rep.Results[2].Status != deep.StatusSkipped {
+		t.Errorf("unexpected report: %+v", rep.Results)
+	}
+
+	guarded := p.WithGuard(&condition.Condition{Path: "/full_name", Op: condition.Eq, Value: "Carol"})
+	if _, _, err := deep.Preview(u, guarded); err == nil {
+		t.Error("expected guard error")
+	}
+}
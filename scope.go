@@ -0,0 +1,211 @@
+package deep
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/brunoga/deep/v5/condition"
+	icore "github.com/brunoga/deep/v5/internal/core"
+)
+
+// ScopeError is returned by [Scope] when operations that affect the value at
+// the scoped path cannot be expressed as a patch for that value. The patch
+// returned alongside it holds the operations that could.
+type ScopeError struct {
+	Dropped []Operation
+}
+
+func (e *ScopeError) Error() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("deep.Scope: %d operation(s) dropped:\n", len(e.Dropped)))
+	for _, op := range e.Dropped {
+		b.WriteString(fmt.Sprintf("- %s %s\n", op.Kind, op.Path))
+	}
+	return b.String()
+}
+
+// Scope returns the part of p that affects the value at path, as a patch for
+// that value: operations at or below path are kept with the path prefix
+// stripped, and a replace or add of an ancestor of path becomes a replace of
+// the root with the corresponding part of its values. Paths are matched using
+// either Go field names or json tags.
+//
+// A move or copy is kept if both its source and destination lie below path,
+// and a move out of path becomes a remove. Operations that affect the value
+// but cannot be expressed relative to it are left out and reported in a
+// *[ScopeError]; the returned patch is valid in that case as well. These are:
+//
+//   - moves and copies into path from outside it, whose value is not part of
+//     the patch;
+//   - removals of an ancestor of path, and replacements of one whose new
+//     value has nothing at path;
+//   - operations whose If or Unless condition refers to values outside path,
+//     since it could not be evaluated against V;
+//   - every operation, if the guard of p or one of its test operations refers
+//     to values outside path.
+func Scope[T, V any](p Patch[T], path Path[T, V]) (Patch[V], error) {
+	t := transformer{typ: reflect.TypeOf((*T)(nil)).Elem()}
+	prefix := t.split(path.String())
+	rebase := func(s string) (string, bool) {
+		sp := t.split(s)
+		if !sp.under(prefix) {
+			return "", false
+		}
+		return "/" + strings.Join(sp.orig[len(prefix.orig):], "/"), true
+	}
+
+	res := Patch[V]{Strict: p.Strict, Guard: mapCondition(p.Guard, rebase)}
+	guarded := p.Guard != nil && res.Guard == nil
+	for _, op := range p.Operations {
+		if _, ok := rebase(op.Path); op.Kind == OpTest && !ok {
+			guarded = true
+		}
+	}
+
+	var dropped []Operation
+	for _, op := range p.Operations {
+		scoped, ok := scopeOp(t, prefix, op, rebase)
+		if !ok {
+			if affectsScope(t, prefix, op) {
+				dropped = append(dropped, op)
+			}
+			continue
+		}
+		scoped.If = mapCondition(op.If, rebase)
+		scoped.Unless = mapCondition(op.Unless, rebase)
+		if guarded || (op.If != nil && scoped.If == nil) || (op.Unless != nil && scoped.Unless == nil) {
+			dropped = append(dropped, op)
+			continue
+		}
+		res.Operations = append(res.Operations, scoped)
+	}
+	if guarded {
+		res.Guard = nil
+	}
+
+	if len(dropped) > 0 {
+		return res, &ScopeError{Dropped: dropped}
+	}
+	return res, nil
+}
+
+// scopeOp rebases a single operation onto prefix. It reports false if op does
+// not affect the value at prefix or cannot be expressed relative to it.
+func scopeOp(t transformer, prefix transformPath, op Operation, rebase func(string) (string, bool)) (Operation, bool) {
+	res := Operation{Kind: op.Kind, Old: op.Old, New: op.New}
+	if path, ok := rebase(op.Path); ok {
+		res.Path = path
+		if op.Kind != OpMove && op.Kind != OpCopy {
+			return res, true
+		}
+		from, _ := op.Old.(string)
+		if res.Old, ok = rebase(from); !ok {
+			return Operation{}, false
+		}
+		return res, true
+	}
+
+	switch op.Kind {
+	case OpMove:
+		from, _ := op.Old.(string)
+		if path, ok := rebase(from); ok {
+			return Operation{Kind: OpRemove, Path: path}, true
+		}
+	case OpAdd, OpReplace:
+		// op replaces an ancestor of prefix: keep the part of its values that
+		// lies at prefix.
+		sp := t.split(op.Path)
+		if !prefix.under(sp) {
+			return Operation{}, false
+		}
+		rel := "/" + strings.Join(prefix.orig[len(sp.orig):], "/")
+		newVal, ok := valueBelow(op.New, rel)
+		if !ok {
+			return Operation{}, false
+		}
+		oldVal, _ := valueBelow(op.Old, rel)
+		return Operation{Kind: OpReplace, Path: "/", Old: oldVal, New: newVal}, true
+	}
+	return Operation{}, false
+}
+
+// affectsScope reports whether op writes to the value at prefix, one of its
+// children or one of its ancestors.
+func affectsScope(t transformer, prefix transformPath, op Operation) bool {
+	if op.Kind == OpLog || op.Kind == OpTest {
+		return false
+	}
+	related := func(p string) bool {
+		sp := t.split(p)
+		return sp.under(prefix) || prefix.under(sp)
+	}
+	if from, ok := op.Old.(string); ok && op.Kind == OpMove && related(from) {
+		return true
+	}
+	return related(op.Path)
+}
+
+// valueBelow returns a copy of the value at rel within val.
+func valueBelow(val any, rel string) (any, bool) {
+	if val == nil {
+		return nil, false
+	}
+	v, err := icore.DeepPath(rel).Resolve(reflect.ValueOf(val))
+	if err != nil || !v.IsValid() || !v.CanInterface() {
+		return nil, false
+	}
+	return icore.DeepCopyValue(v).Interface(), true
+}
+
+// Lift re-roots p, a patch for the value at path, as a patch for T. Operation
+// paths, move and copy sources, and condition paths are all prefixed with
+// path. Lift is the inverse of [Scope] for operations that lie below path.
+func Lift[T, V any](p Patch[V], path Path[T, V]) Patch[T] {
+	prefix := path.String()
+	lift := func(s string) (string, bool) {
+		return icore.JoinPath(prefix, s), true
+	}
+
+	res := Patch[T]{Strict: p.Strict, Guard: mapCondition(p.Guard, lift)}
+	for _, op := range p.Operations {
+		op.Path, _ = lift(op.Path)
+		if from, ok := op.Old.(string); ok && (op.Kind == OpMove || op.Kind == OpCopy) {
+			op.Old, _ = lift(from)
+		}
+		op.If = mapCondition(op.If, lift)
+		op.Unless = mapCondition(op.Unless, lift)
+		res.Operations = append(res.Operations, op)
+	}
+	return res
+}
+
+// mapCondition returns a copy of c with every path rewritten by f, or nil if
+// f rejects any of them.
+func mapCondition(c *condition.Condition, f func(string) (string, bool)) *condition.Condition {
+	if c == nil {
+		return nil
+	}
+	res := *c
+	switch c.Op {
+	case condition.And, condition.Or, condition.Not, condition.Any, condition.All, condition.None:
+		res.Sub = make([]*condition.Condition, len(c.Sub))
+		for i, sub := range c.Sub {
+			if res.Sub[i] = mapCondition(sub, f); res.Sub[i] == nil && sub != nil {
+				return nil
+			}
+		}
+		return &res
+	}
+	path, ok := f(c.Path)
+	if !ok {
+		return nil
+	}
+	res.Path = path
+	if c.Ref != "" {
+		if res.Ref, ok = f(c.Ref); !ok {
+			return nil
+		}
+	}
+	return &res
+}
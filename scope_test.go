@@ -0,0 +1,130 @@
+package deep_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/condition"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+func TestScope(t *testing.T) {
+	infoPath := deep.Field(func(u *testmodels.User) *testmodels.Detail { return &u.Info })
+
+	ageIs30 := &condition.Condition{Path: "/info/Age", Op: condition.Eq, Value: 30}
+	idIs1 := &condition.Condition{Path: "/id", Op: condition.Eq, Value: 1}
+	p := deep.Patch[testmodels.User]{
+		Guard: &condition.Condition{Path: "/info/addr", Op: condition.Eq, Value: "Home"},
+		Operations: []deep.Operation{
+			{Kind: deep.OpReplace, Path: "/full_name", Old: "Alice", New: "Bob", If: idIs1},
+			{Kind: deep.OpReplace, Path: "/Info/Age", Old: 30, New: 31, If: ageIs30},
+			{Kind: deep.OpReplace, Path: "/info/addr", Old: "Home", New: "Work"},
+			{Kind: deep.OpMove, Path: "/roles/0", Old: "/info/addr"},
+			{Kind: deep.OpLog, Path: "/info", New: "moved"},
+		},
+	}
+
+	scoped, err := deep.Scope(p, infoPath)
+	if err != nil {
+		t.Fatalf("Scope failed: %v", err)
+	}
+	want := deep.Patch[testmodels.Detail]{
+		Guard: &condition.Condition{Path: "/addr", Op: condition.Eq, Value: "Home"},
+		Operations: []deep.Operation{
+			{Kind: deep.OpReplace, Path: "/Age", Old: 30, New: 31,
+				If: &condition.Condition{Path: "/Age", Op: condition.Eq, Value: 30}},
+			{Kind: deep.OpReplace, Path: "/addr", Old: "Home", New: "Work"},
+			{Kind: deep.OpRemove, Path: "/addr"},
+			{Kind: deep.OpLog, Path: "/", New: "moved"},
+		},
+	}
+	if !reflect.DeepEqual(scoped, want) {
+		t.Fatalf("Scope:\ngot  %+v\nwant %+v", scoped, want)
+	}
+
+	d := testmodels.Detail{Age: 30, Address: "Home"}
+	if err := deep.Apply(&d, scoped); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if d.Age != 31 || d.Address != "" {
+		t.Errorf("unexpected result: %+v", d)
+	}
+
+	// A replace of an ancestor keeps only the scoped part of its values.
+	root := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/",
+			Old: testmodels.User{Info: testmodels.Detail{Age: 1}},
+			New: testmodels.User{Info: testmodels.Detail{Age: 2}}},
+	}}
+	scoped, err = deep.Scope(root, infoPath)
+	got := scoped.Operations
+	if err != nil || len(got) != 1 || got[0].Path != "/" || got[0].New != (testmodels.Detail{Age: 2}) || got[0].Old != (testmodels.Detail{Age: 1}) {
+		t.Errorf("unexpected scoped ancestor replace: %+v, %v", got, err)
+	}
+}
+
+func TestScopeDropped(t *testing.T) {
+	infoPath := deep.Field(func(u *testmodels.User) *testmodels.Detail { return &u.Info })
+	idIs1 := &condition.Condition{Path: "/id", Op: condition.Eq, Value: 1}
+
+	// Conditions outside the scope drop their operation, and a move from
+	// outside cannot be scoped; neither is silently made unconditional.
+	p := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/info/Age", Old: 30, New: 31},
+		{Kind: deep.OpReplace, Path: "/info/addr", Old: "Home", New: "Work", Unless: idIs1},
+		{Kind: deep.OpMove, Path: "/info/addr", Old: "/full_name"},
+		{Kind: deep.OpReplace, Path: "/full_name", New: "Bob", If: idIs1},
+	}}
+	scoped, err := deep.Scope(p, infoPath)
+	var se *deep.ScopeError
+	if !errors.As(err, &se) || len(se.Dropped) != 2 || se.Dropped[0].Path != "/info/addr" || se.Dropped[1].Kind != deep.OpMove {
+		t.Fatalf("expected the conditional replace and the move to be dropped, got %v", err)
+	}
+	if len(scoped.Operations) != 1 || scoped.Operations[0].Path != "/Age" {
+		t.Errorf("unexpected scoped patch: %+v", scoped)
+	}
+
+	// A guard, or a test, outside the scope drops every operation.
+	for name, p := range map[string]deep.Patch[testmodels.User]{
+		"guard": {Guard: idIs1, Operations: p.Operations[:1]},
+		"test": {Operations: []deep.Operation{
+			{Kind: deep.OpTest, Path: "/id", New: 1},
+			p.Operations[0],
+		}},
+	} {
+		scoped, err := deep.Scope(p, infoPath)
+		if !errors.As(err, &se) || len(se.Dropped) != 1 || !scoped.IsEmpty() || scoped.Guard != nil {
+			t.Errorf("%s: got %+v, %v", name, scoped, err)
+		}
+	}
+}
+
+func TestLift(t *testing.T) {
+	infoPath := deep.Field(func(u *testmodels.User) *testmodels.Detail { return &u.Info })
+	agePath := deep.Field(func(d *testmodels.Detail) *int { return &d.Age })
+
+	d := testmodels.Detail{Age: 30}
+	inner := deep.Edit(&d).
+		Guard(deep.Eq(agePath, 30)).
+		With(deep.Set(agePath, 31)).
+		Build()
+
+	lifted := deep.Lift(inner, infoPath)
+	if lifted.Guard.Path != "/info/Age" || lifted.Operations[0].Path != "/info/Age" {
+		t.Fatalf("unexpected lifted patch: %+v", lifted)
+	}
+
+	u := testmodels.User{ID: 1, Info: testmodels.Detail{Age: 30}}
+	if err := deep.Apply(&u, lifted); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if u.Info.Age != 31 {
+		t.Errorf("unexpected result: %+v", u)
+	}
+
+	if back, err := deep.Scope(lifted, infoPath); err != nil || !reflect.DeepEqual(back, inner) {
+		t.Errorf("Scope(Lift(p)) = %+v, %v, want %+v", back, err, inner)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+
+	icore "github.com/brunoga/deep/v5/internal/core"
 )
 
 // selector is a function that retrieves a field from a struct of type T.
@@ -44,6 +46,23 @@ func MapKey[T any, M ~map[K]V, K comparable, V any](p Path[T, M], k K) Path[T, V
 	return Path[T, V]{path: fmt.Sprintf("%s/%v", p.String(), k)}
 }
 
+// Each returns a wildcard path to every element of a slice field, for use in
+// conditions; see [Any], [All] and [None].
+func Each[T any, S ~[]E, E any](p Path[T, S]) Path[T, E] {
+	return Path[T, E]{path: p.String() + "/*"}
+}
+
+// EachValue returns a wildcard path to every value of a map field, for use in
+// conditions; see [Any], [All] and [None].
+func EachValue[T any, M ~map[K]V, K comparable, V any](p Path[T, M]) Path[T, V] {
+	return Path[T, V]{path: p.String() + "/*"}
+}
+
+// Join returns the path to the value at q within the value at p.
+func Join[T, U, V any](p Path[T, U], q Path[U, V]) Path[T, V] {
+	return Path[T, V]{path: icore.JoinPath(p.String(), q.String())}
+}
+
 // pathCache stores resolved paths keyed by selector function pointer.
 var pathCache sync.Map // map[uintptr]string
 
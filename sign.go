@@ -0,0 +1,166 @@
+package deep
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnsigned is returned when a signature is required but the patch or
+	// delta carries none.
+	ErrUnsigned = errors.New("missing signature")
+	// ErrInvalidSignature is returned when a signature does not verify.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Signature authenticates a patch or delta. It is computed over the binary
+// encoding of the signed value without its signature (see [Patch.ToBinary]),
+// which records every value exactly, including unexported fields and fields
+// that JSON leaves out. Map entries are encoded in a fixed order; values that
+// are encoded with encoding/gob must themselves encode deterministically.
+type Signature struct {
+	// KeyID names the key that produced the signature, so that a verifier
+	// holding several keys knows which one to use.
+	KeyID string `json:"kid"`
+	Value []byte `json:"v"`
+}
+
+// Signer signs data with a key identified by KeyID.
+type Signer interface {
+	KeyID() string
+	Sign(data []byte) ([]byte, error)
+}
+
+// Verifier checks a signature produced by a [Signer]. Verify returns nil if
+// sig is a valid signature of data by the key named keyID, and an error
+// wrapping [ErrInvalidSignature] otherwise, including for unknown keys.
+type Verifier interface {
+	Verify(keyID string, data, sig []byte) error
+}
+
+// Sign returns a copy of p signed by s.
+func (p Patch[T]) Sign(s Signer) (Patch[T], error) {
+	data, err := p.signingPayload()
+	if err != nil {
+		return p, err
+	}
+	sig, err := s.Sign(data)
+	if err != nil {
+		return p, err
+	}
+	p.Signature = &Signature{KeyID: s.KeyID(), Value: sig}
+	return p, nil
+}
+
+// Verify checks the signature of p with v. It returns [ErrUnsigned] if p has
+// no signature.
+func (p Patch[T]) Verify(v Verifier) error {
+	if p.Signature == nil {
+		return ErrUnsigned
+	}
+	data, err := p.signingPayload()
+	if err != nil {
+		return err
+	}
+	return v.Verify(p.Signature.KeyID, data, p.Signature.Value)
+}
+
+// signingPayload returns the bytes a signature of p covers.
+func (p Patch[T]) signingPayload() ([]byte, error) {
+	p.Signature = nil
+	return p.ToBinary()
+}
+
+// RequireSignature makes [Apply], [ApplyWithReport] and [Preview] check the
+// patch signature with v before applying anything, and fail with an error
+// wrapping [ErrUnsigned] or [ErrInvalidSignature] if it does not verify.
+func RequireSignature(v Verifier) ApplyOption {
+	return func(c *applyConfig) { c.verifier = v }
+}
+
+// verifyPatch checks the signature of p if cfg requires one.
+func verifyPatch[T any](p Patch[T], cfg applyConfig) error {
+	if cfg.verifier == nil {
+		return nil
+	}
+	if err := p.Verify(cfg.verifier); err != nil {
+		return fmt.Errorf("patch rejected: %w", err)
+	}
+	return nil
+}
+
+type hmacKey struct {
+	id  string
+	key []byte
+}
+
+// NewHMACSigner returns a [Signer] that signs with HMAC-SHA256.
+func NewHMACSigner(keyID string, key []byte) Signer {
+	return hmacKey{id: keyID, key: key}
+}
+
+func (k hmacKey) KeyID() string { return k.id }
+
+func (k hmacKey) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, k.key)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+type hmacVerifier map[string][]byte
+
+// NewHMACVerifier returns a [Verifier] for HMAC-SHA256 signatures made with
+// the given keys, indexed by key ID.
+func NewHMACVerifier(keys map[string][]byte) Verifier {
+	return hmacVerifier(keys)
+}
+
+func (v hmacVerifier) Verify(keyID string, data, sig []byte) error {
+	key, ok := v[keyID]
+	if !ok {
+		return fmt.Errorf("%w: unknown key %q", ErrInvalidSignature, keyID)
+	}
+	want, _ := hmacKey{key: key}.Sign(data)
+	if !hmac.Equal(sig, want) {
+		return fmt.Errorf("%w: key %q", ErrInvalidSignature, keyID)
+	}
+	return nil
+}
+
+type ed25519Signer struct {
+	id  string
+	key ed25519.PrivateKey
+}
+
+// NewEd25519Signer returns a [Signer] that signs with an ed25519 private key.
+func NewEd25519Signer(keyID string, key ed25519.PrivateKey) Signer {
+	return ed25519Signer{id: keyID, key: key}
+}
+
+func (s ed25519Signer) KeyID() string { return s.id }
+
+func (s ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, data), nil
+}
+
+type ed25519Verifier map[string]ed25519.PublicKey
+
+// NewEd25519Verifier returns a [Verifier] for ed25519 signatures made with
+// the private keys matching the given public keys, indexed by key ID.
+func NewEd25519Verifier(keys map[string]ed25519.PublicKey) Verifier {
+	return ed25519Verifier(keys)
+}
+
+func (v ed25519Verifier) Verify(keyID string, data, sig []byte) error {
+	key, ok := v[keyID]
+	if !ok {
+		return fmt.Errorf("%w: unknown key %q", ErrInvalidSignature, keyID)
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return fmt.Errorf("%w: key %q", ErrInvalidSignature, keyID)
+	}
+	return nil
+}
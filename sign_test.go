@@ -0,0 +1,151 @@
+package deep_test
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+func TestSignHMAC(t *testing.T) {
+	u := testmodels.User{ID: 1, Name: "a"}
+	namePath := deep.Field(func(u *testmodels.User) *string { return &u.Name })
+	p := deep.Edit(&u).With(deep.Set(namePath, "b")).Build()
+
+	key := []byte("secret")
+	v := deep.NewHMACVerifier(map[string][]byte{"k1": key})
+
+	if err := deep.Apply(&u, p, deep.RequireSignature(v)); !errors.Is(err, deep.ErrUnsigned) {
+		t.Fatalf("unsigned: err = %v, want ErrUnsigned", err)
+	}
+
+	signed, err := p.Sign(deep.NewHMACSigner("k1", key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signed.Verify(v); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// Wrong key and unknown key ID.
+	bad, _ := p.Sign(deep.NewHMACSigner("k1", []byte("other")))
+	if err := bad.Verify(v); !errors.Is(err, deep.ErrInvalidSignature) {
+		t.Errorf("wrong key: err = %v", err)
+	}
+	bad, _ = p.Sign(deep.NewHMACSigner("k2", key))
+	if err := bad.Verify(v); !errors.Is(err, deep.ErrInvalidSignature) {
+		t.Errorf("unknown key: err = %v", err)
+	}
+
+	// Tampering with an operation invalidates the signature, even through
+	// the atomic path.
+	forged := signed
+	forged.Operations = append([]deep.Operation(nil), signed.Operations...)
+	forged.Operations[0].New = "evil"
+	err = deep.Apply(&u, forged, deep.RequireSignature(v), deep.Atomic())
+	if !errors.Is(err, deep.ErrInvalidSignature) {
+		t.Fatalf("forged: err = %v, want ErrInvalidSignature", err)
+	}
+	if u.Name != "a" {
+		t.Fatalf("forged patch was applied: %q", u.Name)
+	}
+
+	// JSON round trip keeps the signature.
+	data, err := json.Marshal(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded deep.Patch[testmodels.User]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := deep.Apply(&u, decoded, deep.RequireSignature(v)); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if u.Name != "b" {
+		t.Errorf("got %q, want b", u.Name)
+	}
+}
+
+func TestSignEd25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := testmodels.User{ID: 1}
+	idPath := deep.Field(func(u *testmodels.User) *int { return &u.ID })
+	p := deep.Edit(&u).With(deep.Set(idPath, 2)).Build()
+
+	signed, err := p.Sign(deep.NewEd25519Signer("node", priv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Binary round trip keeps the signature.
+	data, err := signed.ToBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded deep.Patch[testmodels.User]
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	v := deep.NewEd25519Verifier(map[string]ed25519.PublicKey{"node": pub})
+	if err := deep.Apply(&u, decoded, deep.RequireSignature(v)); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if u.ID != 2 {
+		t.Errorf("got ID %d, want 2", u.ID)
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	v = deep.NewEd25519Verifier(map[string]ed25519.PublicKey{"node": otherPub})
+	if err := decoded.Verify(v); !errors.Is(err, deep.ErrInvalidSignature) {
+		t.Errorf("Verify = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestSignCoversExactValues(t *testing.T) {
+	key := []byte("secret")
+	v := deep.NewHMACVerifier(map[string][]byte{"k1": key})
+	s := deep.NewHMACSigner("k1", key)
+
+	// Integers beyond 2^53 that share a float64.
+	p := deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/id", New: 9007199254740993},
+	}}
+	signed, err := p.Sign(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := signed
+	forged.Operations = []deep.Operation{{Kind: deep.OpReplace, Path: "/id", New: 9007199254740992}}
+	if err := forged.Verify(v); !errors.Is(err, deep.ErrInvalidSignature) {
+		t.Errorf("forged integer: err = %v, want ErrInvalidSignature", err)
+	}
+
+	// Unexported data, which JSON leaves out.
+	withAge := func(age int) testmodels.User {
+		u := testmodels.User{ID: 1, Name: "a"}
+		*u.AgePtr() = age
+		return u
+	}
+	p = deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/", New: withAge(30)},
+	}}
+	if signed, err = p.Sign(s); err != nil {
+		t.Fatal(err)
+	}
+	forged = signed
+	forged.Operations = []deep.Operation{{Kind: deep.OpReplace, Path: "/", New: withAge(99)}}
+	if err := forged.Verify(v); !errors.Is(err, deep.ErrInvalidSignature) {
+		t.Errorf("forged unexported field: err = %v, want ErrInvalidSignature", err)
+	}
+	if err := signed.Verify(v); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
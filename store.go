@@ -0,0 +1,214 @@
+package deep
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+
+	icore "github.com/brunoga/deep/v5/internal/core"
+)
+
+// Store owns a value of type T, applies patches to it under a lock and
+// notifies subscribers whose part of the value a patch changed. Register
+// subscribers with [Subscribe].
+type Store[T any] struct {
+	mu    sync.RWMutex
+	value T
+	subs  []*storeSub[T]
+	t     transformer
+
+	// notifyMu serializes notifications so subscribers see changes in the
+	// order they were applied.
+	notifyMu sync.Mutex
+}
+
+// storeSub is a subscription to the value at path.
+type storeSub[T any] struct {
+	path   transformPath
+	get    func(*T) any
+	equal  func(old, new any) bool
+	notify func(old, new any)
+}
+
+// storeChange is a pending notification for one subscriber.
+type storeChange[T any] struct {
+	sub      *storeSub[T]
+	old, new any
+}
+
+// NewStore returns a store holding initial.
+func NewStore[T any](initial T) *Store[T] {
+	return &Store[T]{
+		value: initial,
+		t:     transformer{typ: reflect.TypeOf((*T)(nil)).Elem()},
+	}
+}
+
+// View returns a deep copy of the current value.
+func (s *Store[T]) View() T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Clone(s.value)
+}
+
+// Apply applies p to the stored value with [Apply] and then notifies the
+// subscribers whose value was changed by an applied operation. As with
+// [Apply], an error may leave some operations applied; subscribers are
+// notified of those.
+func (s *Store[T]) Apply(p Patch[T], opts ...ApplyOption) error {
+	s.mu.Lock()
+	changes, err := s.applyLocked(p, opts)
+	s.unlockAndNotify(changes)
+	return err
+}
+
+// Edit applies fn to a copy of the current value and applies the patch from
+// the current value to the result, computed with [Diff], as [Store.Apply]
+// does. It returns that patch.
+func (s *Store[T]) Edit(fn func(*T), opts ...DiffOption) (Patch[T], error) {
+	s.mu.Lock()
+	next := Clone(s.value)
+	fn(&next)
+	p, err := Diff(s.value, next, opts...)
+	if err != nil || p.IsEmpty() {
+		s.mu.Unlock()
+		return p, err
+	}
+	changes, err := s.applyLocked(p, nil)
+	s.unlockAndNotify(changes)
+	return p, err
+}
+
+// applyLocked applies p and returns the notifications it causes. s.mu must be
+// held for writing.
+func (s *Store[T]) applyLocked(p Patch[T], opts []ApplyOption) ([]storeChange[T], error) {
+	// Only subscribers the patch may touch need their value saved.
+	var touched []*storeSub[T]
+	var olds []any
+	for _, sub := range s.subs {
+		if s.touches(p.Operations, sub.path) {
+			touched = append(touched, sub)
+			olds = append(olds, sub.get(&s.value))
+		}
+	}
+	if len(touched) == 0 {
+		return nil, Apply(&s.value, p, opts...)
+	}
+
+	rep, err := applySteps(&s.value, p, newApplyConfig(opts...))
+	applied := rep.Applied()
+
+	var changes []storeChange[T]
+	for i, sub := range touched {
+		if !s.touches(applied, sub.path) {
+			continue
+		}
+		if cur := sub.get(&s.value); !sub.equal(olds[i], cur) {
+			changes = append(changes, storeChange[T]{sub, olds[i], cur})
+		}
+	}
+	return changes, err
+}
+
+// unlockAndNotify releases s.mu and delivers changes. Notifications are
+// ordered by taking notifyMu before the store is unlocked.
+func (s *Store[T]) unlockAndNotify(changes []storeChange[T]) {
+	if len(changes) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.notifyMu.Lock()
+	s.mu.Unlock()
+	defer s.notifyMu.Unlock()
+	for _, c := range changes {
+		c.sub.notify(c.old, c.new)
+	}
+}
+
+// touches reports whether any of ops writes to path, one of its children or
+// one of its ancestors. Removing an element of a non-keyed slice, or moving it
+// away, also touches every later element, whose index it shifts; an add sets
+// or appends by index and shifts nothing.
+func (s *Store[T]) touches(ops []Operation, path transformPath) bool {
+	related := func(p string) bool {
+		sp := s.t.split(p)
+		return sp.under(path) || path.under(sp)
+	}
+	shifts := func(p string) bool {
+		slice, i, ok := s.t.sliceIndex(p)
+		if !ok || len(path.canon) <= len(slice.canon) || !path.under(slice) {
+			return false
+		}
+		j, err := strconv.Atoi(path.canon[len(slice.canon)])
+		return err == nil && j >= i
+	}
+	for _, op := range ops {
+		switch op.Kind {
+		case OpLog, OpTest:
+			continue
+		case OpReplace:
+			if related(op.Path) {
+				return true
+			}
+			continue
+		case OpMove:
+			if from, ok := op.Old.(string); ok && (related(from) || shifts(from)) {
+				return true
+			}
+		}
+		if related(op.Path) || (op.Kind == OpRemove && shifts(op.Path)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribe registers fn to be called after a [Store.Apply] or [Store.Edit]
+// that changed the value at path, with the value before and after the patch.
+// Operations on path, on its children and on its ancestors all count, as do
+// removals of earlier elements of the same non-keyed slice, which shift the
+// element at path. A patch that leaves the value equal, as
+// reported by [Equal], does not notify. A value that does not exist, such as
+// a missing map entry, is reported as the zero V.
+//
+// Subscribers are called in the order patches were applied, after the store's
+// lock is released. They may read the store but must not modify it. The
+// returned function cancels the subscription.
+func Subscribe[T, V any](s *Store[T], path Path[T, V], fn func(old, new V)) (cancel func()) {
+	p := path.String()
+	as := func(x any) V {
+		v, _ := x.(V)
+		return v
+	}
+	sub := &storeSub[T]{
+		path: s.t.split(p),
+		get: func(v *T) any {
+			val, err := icore.DeepPath(p).Resolve(reflect.ValueOf(v).Elem())
+			if err != nil || !val.IsValid() {
+				return nil
+			}
+			return icore.ValueToInterface(icore.DeepCopyValue(val))
+		},
+		equal: func(old, new any) bool {
+			return Equal(as(old), as(new))
+		},
+		notify: func(old, new any) {
+			fn(as(old), as(new))
+		},
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs = append(s.subs, sub)
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, x := range s.subs {
+			if x == sub {
+				s.subs = append(s.subs[:i:i], s.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package deep_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/internal/testmodels"
+)
+
+func TestStoreSubscribe(t *testing.T) {
+	s := deep.NewStore(testmodels.User{ID: 1, Name: "Alice", Info: testmodels.Detail{Age: 30}})
+
+	namePath := deep.Field(func(u *testmodels.User) *string { return &u.Name })
+	infoPath := deep.Field(func(u *testmodels.User) *testmodels.Detail { return &u.Info })
+	agePath := deep.Field(func(u *testmodels.User) *int { return &u.Info.Age })
+	scorePath := deep.Field(func(u *testmodels.User) *map[string]int { return &u.Score })
+
+	var events []string
+	deep.Subscribe(s, namePath, func(old, new string) {
+		events = append(events, fmt.Sprintf("name %s->%s", old, new))
+	})
+	deep.Subscribe(s, infoPath, func(old, new testmodels.Detail) {
+		events = append(events, fmt.Sprintf("info %d->%d", old.Age, new.Age))
+	})
+	cancel := deep.Subscribe(s, deep.MapKey(scorePath, "x"), func(old, new int) {
+		events = append(events, fmt.Sprintf("score %d->%d", old, new))
+	})
+
+	// A child of /info notifies /info; /full_name is untouched.
+	if err := s.Apply(deep.Edit(new(testmodels.User)).With(deep.Set(agePath, 31)).Build()); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	// Edit diffs and notifies; a missing map entry reads as zero.
+	if _, err := s.Edit(func(u *testmodels.User) {
+		u.Name = "Bob"
+		u.Score = map[string]int{"x": 5}
+	}); err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	// A replace of the root reaches every subscriber whose value changed.
+	cancel()
+	root := s.View()
+	root.Info.Age = 40
+	root.Score["x"] = 6
+	if err := s.Apply(deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/", New: root},
+	}}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	want := []string{"info 30->31", "name Alice->Bob", "score 0->5", "info 31->40"}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+	if v := s.View(); v.Info.Age != 40 || v.Name != "Bob" {
+		t.Errorf("unexpected value: %+v", v)
+	}
+}
+
+func TestStoreSubscribeSliceShift(t *testing.T) {
+	s := deep.NewStore(testmodels.User{Roles: []string{"a", "b", "c", "d"}})
+	rolesPath := deep.Field(func(u *testmodels.User) *[]string { return &u.Roles })
+
+	var events []string
+	for _, i := range []int{0, 2} {
+		i := i
+		deep.Subscribe(s, deep.At(rolesPath, i), func(old, new string) {
+			events = append(events, fmt.Sprintf("%d %s->%s", i, old, new))
+		})
+	}
+
+	// Removing /roles/1 shifts /roles/2 but leaves /roles/0 alone.
+	if err := s.Apply(deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpRemove, Path: "/roles/1", Old: "b"},
+	}}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	// Removing /roles/0 shifts both.
+	if err := s.Apply(deep.Patch[testmodels.User]{Operations: []deep.Operation{
+		{Kind: deep.OpRemove, Path: "/roles/0", Old: "a"},
+	}}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	want := []string{"2 c->d", "0 a->c", "2 d->"}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
+func TestStoreConcurrent(t *testing.T) {
+	s := deep.NewStore(testmodels.User{})
+	idPath := deep.Field(func(u *testmodels.User) *int { return &u.ID })
+
+	var mu sync.Mutex
+	last := 0
+	deep.Subscribe(s, idPath, func(old, new int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if old != last {
+			t.Errorf("notification out of order: old %d, last %d", old, last)
+		}
+		last = new
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Edit(func(u *testmodels.User) { u.ID++ }); err != nil {
+				t.Errorf("Edit failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := s.View().ID; got != 50 || last != 50 {
+		t.Errorf("ID = %d, last notified = %d", got, last)
+	}
+}
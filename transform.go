@@ -0,0 +1,263 @@
+package deep
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	icore "github.com/brunoga/deep/v5/internal/core"
+)
+
+// TransformError is returned by [Transform] when operations had to be dropped
+// because the patch they were transformed against removed or replaced the
+// value they target. The patch returned alongside it is still valid.
+type TransformError struct {
+	Dropped []Operation
+}
+
+func (e *TransformError) Error() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("deep.Transform: %d operation(s) dropped:\n", len(e.Dropped)))
+	for _, op := range e.Dropped {
+		b.WriteString(fmt.Sprintf("- %s %s\n", op.Kind, op.Path))
+	}
+	return b.String()
+}
+
+// Transform rebases p over against, where both patches were produced from the
+// same version of a value. The returned patch has the effect of p when applied
+// after against:
+//
+//   - indices into non-keyed slices are shifted past elements appended or
+//     removed by against; an add to a non-keyed slice is taken to append, and
+//     elements of keyed slices (deep:"key") are addressed by key and never
+//     shift;
+//   - paths below a value moved by against are redirected to its new location;
+//   - a replace or remove of a path that against also replaced takes the value
+//     written by against as its Old value.
+//
+// Each operation of p is rebased over against as brought forward past the
+// operations of p before it, so both patches may hold several operations on
+// the same slice. Operations on a value that against removed or replaced as a
+// whole, or moved another value over, cannot be rebased. They are left out of
+// the result and reported in a *[TransformError]; the returned patch is valid
+// in that case as well. Conditional operations in against are assumed to have
+// been applied.
+func Transform[T any](p, against Patch[T]) (Patch[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	t := transformer{typ: typ}
+	ahead := transformer{typ: typ, ahead: true}
+
+	res := Patch[T]{Guard: p.Guard, Strict: p.Strict}
+	base := append([]Operation(nil), against.Operations...)
+	var dropped []Operation
+	for _, op := range p.Operations {
+		// Rebase op over each operation of against in turn, and bring that
+		// operation past op as rebased so far: the next operation of p was
+		// written after op. Operations of against on values op removed no
+		// longer affect anything p addresses.
+		out, ok := op, true
+		next := base[:0]
+		for _, a := range base {
+			if !ok {
+				next = append(next, a)
+				continue
+			}
+			if a, keep := ahead.transform(a, out); keep {
+				next = append(next, a)
+			}
+			out, ok = t.transform(out, a)
+		}
+		base = next
+
+		if ok {
+			res.Operations = append(res.Operations, out)
+		} else {
+			dropped = append(dropped, op)
+		}
+	}
+
+	if len(dropped) > 0 {
+		return res, &TransformError{Dropped: dropped}
+	}
+	return res, nil
+}
+
+// transformer rebases operations over others on a value of type typ. ahead
+// reports whether the operations it rebases come first when both sides append
+// at the same index of a slice.
+type transformer struct {
+	typ   reflect.Type
+	ahead bool
+}
+
+// transformPath is a path split into segments, in both its original spelling
+// and its canonical (Go field name) form.
+type transformPath struct {
+	orig  []string
+	canon []string
+}
+
+func (t transformer) split(path string) transformPath {
+	return transformPath{
+		orig:  splitPath(path),
+		canon: splitPath(icore.CanonicalPath(t.typ, path)),
+	}
+}
+
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func (p transformPath) String() string {
+	return "/" + strings.Join(p.orig, "/")
+}
+
+// under reports whether p equals prefix or lies below it.
+func (p transformPath) under(prefix transformPath) bool {
+	if len(p.canon) < len(prefix.canon) {
+		return false
+	}
+	for i, s := range prefix.canon {
+		if p.canon[i] != s {
+			return false
+		}
+	}
+	return true
+}
+
+// sliceIndex reports the containing slice and index of path when it addresses
+// an element of a non-keyed slice.
+func (t transformer) sliceIndex(path string) (transformPath, int, bool) {
+	parent := shiftAnchor(t.typ, path)
+	if parent == path {
+		return transformPath{}, 0, false
+	}
+	p := t.split(path)
+	idx, err := strconv.Atoi(p.orig[len(p.orig)-1])
+	if err != nil {
+		return transformPath{}, 0, false
+	}
+	return t.split(parent), idx, true
+}
+
+// transform rebases op over a single operation a. It reports false if op
+// cannot be applied after a.
+func (t transformer) transform(op, a Operation) (Operation, bool) {
+	if a.Kind == OpLog || a.Kind == OpTest {
+		return op, true
+	}
+
+	path, ok := t.transformPath(op.Path, op.Kind == OpAdd, a)
+	if !ok {
+		return op, false
+	}
+	op.Path = path
+
+	switch op.Kind {
+	case OpMove, OpCopy:
+		from, isPath := op.Old.(string)
+		if !isPath {
+			break
+		}
+		if from, ok = t.transformPath(from, false, a); !ok {
+			return op, false
+		}
+		op.Old = from
+
+	case OpReplace, OpRemove:
+		// Keep Old in line with the value op now overwrites.
+		target, aPath := t.split(op.Path), t.split(a.Path)
+		if !aPath.under(target) {
+			break
+		}
+		if len(aPath.canon) == len(target.canon) {
+			if a.Kind == OpReplace || a.Kind == OpAdd {
+				op.Old = a.New
+			}
+		} else if a.Kind == OpAdd || a.Kind == OpReplace || a.Kind == OpRemove {
+			rel := "/" + strings.Join(aPath.canon[len(target.canon):], "/")
+			if old, ok := applyRelative(op.Old, rel, a); ok {
+				op.Old = old
+			}
+		}
+	}
+	return op, true
+}
+
+// transformPath rebases path over a. isAdd reports whether path is the target
+// of an add, which may recreate a value that a removed.
+func (t transformer) transformPath(path string, isAdd bool, a Operation) (string, bool) {
+	p := t.split(path)
+	aPath := t.split(a.Path)
+
+	switch a.Kind {
+	case OpAdd:
+		if slice, i, ok := t.sliceIndex(a.Path); ok {
+			if t.ahead && isAdd {
+				i++
+			}
+			t.shift(&p, slice, i, 1)
+		}
+
+	case OpRemove:
+		if slice, i, ok := t.sliceIndex(a.Path); ok {
+			if p.under(aPath) && !(isAdd && len(p.canon) == len(aPath.canon)) {
+				return path, false
+			}
+			t.shift(&p, slice, i+1, -1)
+			break
+		}
+		if p.under(aPath) && !(isAdd && len(p.canon) == len(aPath.canon)) {
+			return path, false
+		}
+
+	case OpReplace:
+		if p.under(aPath) && len(p.canon) > len(aPath.canon) {
+			return path, false
+		}
+
+	case OpMove, OpCopy:
+		from, isPath := a.Old.(string)
+		if !isPath {
+			break
+		}
+		fromPath := t.split(from)
+		if a.Kind == OpMove {
+			if p.under(fromPath) {
+				p = transformPath{
+					orig:  append(append([]string(nil), aPath.orig...), p.orig[len(fromPath.orig):]...),
+					canon: append(append([]string(nil), aPath.canon...), p.canon[len(fromPath.canon):]...),
+				}
+				return p.String(), true
+			}
+			if slice, i, ok := t.sliceIndex(from); ok {
+				t.shift(&p, slice, i+1, -1)
+			}
+		}
+		if p.under(aPath) && !(isAdd && len(p.canon) == len(aPath.canon)) {
+			return path, false
+		}
+	}
+	return p.String(), true
+}
+
+// shift moves the index of p within slice by delta if it addresses an element
+// at or after index i.
+func (t transformer) shift(p *transformPath, slice transformPath, i, delta int) {
+	n := len(slice.canon)
+	if len(p.canon) <= n || !p.under(slice) {
+		return
+	}
+	j, err := strconv.Atoi(p.canon[n])
+	if err != nil || j < i {
+		return
+	}
+	p.orig[n] = strconv.Itoa(j + delta)
+	p.canon[n] = p.orig[n]
+}
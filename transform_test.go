@@ -0,0 +1,213 @@
+package deep_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+)
+
+type transformItem struct {
+	ID  int `deep:"key"`
+	Qty int
+}
+
+type transformDoc struct {
+	Name  string
+	Tags  []string
+	Items []transformItem
+	Pos   map[string]transformItem
+}
+
+func TestTransformSliceIndices(t *testing.T) {
+	base := transformDoc{Tags: []string{"a", "b", "c"}}
+
+	a := base
+	a.Tags = []string{"b", "c", "d"}
+	pa, err := deep.Diff(base, a)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	b := base
+	b.Tags = []string{"a", "B", "c"}
+	pb, err := deep.Diff(base, b)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	tb, err := deep.Transform(pb, pa)
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+
+	got := deep.Clone(base)
+	if err := deep.Apply(&got, pa); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if err := deep.Apply(&got, tb); err != nil {
+		t.Fatalf("Apply transformed failed: %v", err)
+	}
+	want := []string{"B", "c", "d"}
+	if !reflect.DeepEqual(got.Tags, want) {
+		t.Errorf("got %v, want %v", got.Tags, want)
+	}
+}
+
+func TestTransformSliceSequences(t *testing.T) {
+	base := transformDoc{Tags: []string{"a", "b", "c"}}
+	tests := []struct {
+		name     string
+		p        []deep.Operation
+		against  []deep.Operation
+		want     []string
+		wantDrop int
+	}{
+		{
+			// The replace was written after p removed "a", so it addresses
+			// "c" at index 1, not the "b" against removed.
+			name: "several in p",
+			p: []deep.Operation{
+				{Kind: deep.OpRemove, Path: "/Tags/0", Old: "a"},
+				{Kind: deep.OpReplace, Path: "/Tags/1", Old: "c", New: "C"},
+			},
+			against: []deep.Operation{
+				{Kind: deep.OpRemove, Path: "/Tags/1", Old: "b"},
+			},
+			want: []string{"C"},
+		},
+		{
+			name: "several in against",
+			p: []deep.Operation{
+				{Kind: deep.OpReplace, Path: "/Tags/2", Old: "c", New: "C"},
+			},
+			against: []deep.Operation{
+				{Kind: deep.OpRemove, Path: "/Tags/0", Old: "a"},
+				{Kind: deep.OpAdd, Path: "/Tags/2", New: "d"},
+			},
+			want: []string{"b", "C", "d"},
+		},
+		{
+			// Both sides append; the appends of against come first.
+			name: "several on both sides",
+			p: []deep.Operation{
+				{Kind: deep.OpRemove, Path: "/Tags/1", Old: "b"},
+				{Kind: deep.OpAdd, Path: "/Tags/2", New: "e"},
+				{Kind: deep.OpReplace, Path: "/Tags/0", Old: "a", New: "A"},
+			},
+			against: []deep.Operation{
+				{Kind: deep.OpRemove, Path: "/Tags/0", Old: "a"},
+				{Kind: deep.OpAdd, Path: "/Tags/2", New: "d"},
+			},
+			want:     []string{"c", "d", "e"},
+			wantDrop: 1,
+		},
+		{
+			// p removes the element against replaced; the replace p makes
+			// afterwards addresses the element that took its place.
+			name: "removed by p first",
+			p: []deep.Operation{
+				{Kind: deep.OpRemove, Path: "/Tags/1", Old: "b"},
+				{Kind: deep.OpReplace, Path: "/Tags/1", Old: "c", New: "C"},
+			},
+			against: []deep.Operation{
+				{Kind: deep.OpReplace, Path: "/Tags/1", Old: "b", New: "B"},
+				{Kind: deep.OpAdd, Path: "/Tags/3", New: "d"},
+			},
+			want: []string{"a", "C", "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := deep.Patch[transformDoc]{Operations: tt.p}
+			against := deep.Patch[transformDoc]{Operations: tt.against}
+			tp, err := deep.Transform(p, against)
+			var terr *deep.TransformError
+			if tt.wantDrop == 0 && err != nil {
+				t.Fatalf("Transform failed: %v", err)
+			}
+			if tt.wantDrop > 0 && (!errors.As(err, &terr) || len(terr.Dropped) != tt.wantDrop) {
+				t.Fatalf("expected %d dropped operation(s), got %v", tt.wantDrop, err)
+			}
+
+			got := deep.Clone(base)
+			if err := deep.Apply(&got, against); err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if err := deep.Apply(&got, tp.AsStrict()); err != nil {
+				t.Fatalf("Apply transformed failed: %v", err)
+			}
+			if !reflect.DeepEqual(got.Tags, tt.want) {
+				t.Errorf("got %v, want %v", got.Tags, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformRemovedTargets(t *testing.T) {
+	against := deep.Patch[transformDoc]{Operations: []deep.Operation{
+		{Kind: deep.OpRemove, Path: "/Tags/1", Old: "b"},
+		{Kind: deep.OpRemove, Path: "/Items/1", Old: transformItem{ID: 1, Qty: 1}},
+	}}
+	p := deep.Patch[transformDoc]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/Tags/1", Old: "b", New: "B"},
+		{Kind: deep.OpReplace, Path: "/Tags/2", Old: "c", New: "C"},
+		{Kind: deep.OpReplace, Path: "/Items/1/Qty", Old: 1, New: 5},
+		{Kind: deep.OpReplace, Path: "/Items/2/Qty", Old: 2, New: 6},
+	}}
+
+	got, err := deep.Transform(p, against)
+	var terr *deep.TransformError
+	if !errors.As(err, &terr) {
+		t.Fatalf("expected TransformError, got %v", err)
+	}
+	if len(terr.Dropped) != 2 || terr.Dropped[0].Path != "/Tags/1" || terr.Dropped[1].Path != "/Items/1/Qty" {
+		t.Errorf("unexpected dropped operations: %+v", terr.Dropped)
+	}
+
+	want := []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/Tags/1", Old: "c", New: "C"},
+		{Kind: deep.OpReplace, Path: "/Items/2/Qty", Old: 2, New: 6},
+	}
+	if !reflect.DeepEqual(got.Operations, want) {
+		t.Errorf("got %+v, want %+v", got.Operations, want)
+	}
+}
+
+func TestTransformMoveAndReplace(t *testing.T) {
+	base := transformDoc{
+		Name: "base",
+		Pos:  map[string]transformItem{"a": {ID: 1, Qty: 1}},
+	}
+	against := deep.Patch[transformDoc]{Operations: []deep.Operation{
+		{Kind: deep.OpMove, Path: "/Pos/b", Old: "/Pos/a"},
+		{Kind: deep.OpReplace, Path: "/Name", Old: "base", New: "theirs"},
+	}}
+	p := deep.Patch[transformDoc]{Operations: []deep.Operation{
+		{Kind: deep.OpReplace, Path: "/Pos/a/Qty", Old: 1, New: 7},
+		{Kind: deep.OpReplace, Path: "/Name", Old: "base", New: "ours"},
+	}}
+
+	tp, err := deep.Transform(p, against)
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if tp.Operations[0].Path != "/Pos/b/Qty" {
+		t.Errorf("move not followed: %s", tp.Operations[0].Path)
+	}
+	if tp.Operations[1].Old != "theirs" {
+		t.Errorf("Old not rebased: %v", tp.Operations[1].Old)
+	}
+
+	got := deep.Clone(base)
+	if err := deep.Apply(&got, against); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if err := deep.Apply(&got, tp.AsStrict()); err != nil {
+		t.Fatalf("Apply transformed failed: %v", err)
+	}
+	if got.Name != "ours" || got.Pos["b"].Qty != 7 {
+		t.Errorf("unexpected result: %+v", got)
+	}
+}
@@ -0,0 +1,176 @@
+package deep
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/brunoga/deep/v5/condition"
+	icore "github.com/brunoga/deep/v5/internal/core"
+)
+
+// PathError describes a problem with one path of a patch.
+type PathError struct {
+	// Op is the index of the operation the path belongs to, or -1 for the
+	// patch guard.
+	Op   int
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	if e.Op < 0 {
+		return fmt.Sprintf("guard: %s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("ops[%d]: %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError reports every problem found by [Validate], each as a
+// *[PathError].
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%d problems in patch:\n", len(e.Errors)))
+	for _, err := range e.Errors {
+		b.WriteString("- " + err.Error() + "\n")
+	}
+	return b.String()
+}
+
+// Unwrap allows errors.Is and errors.As to inspect individual problems.
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// Validate checks p against the structure of T without applying it. It
+// reports, for every operation, paths that do not exist in T (including keys
+// of keyed slices and map keys that do not parse as the key type), writes to
+// deep:"readonly" fields, operations on deep:"-" fields, Old and New values
+// that cannot be stored at their path, and If/Unless or guard conditions that
+// refer to paths that do not exist or do not type-check against T (see
+// [condition.Check]).
+//
+// Paths that continue below an interface-typed value cannot be checked
+// statically and are accepted. A nil error means no problem was found.
+func Validate[T any](p Patch[T]) error {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	var errs []error
+	report := func(op int, path string, err error) {
+		errs = append(errs, &PathError{Op: op, Path: path, Err: err})
+	}
+
+	validateCondition(typ, p.Guard, func(path string, err error) { report(-1, path, err) })
+
+	for i, op := range p.Operations {
+		reportOp := func(path string, err error) { report(i, path, err) }
+
+		switch op.Kind {
+		case OpAdd, OpRemove, OpReplace, OpLog, OpTest:
+			validatePath(typ, op.Path, op.Kind != OpLog && op.Kind != OpTest, reportOp)
+			if op.Kind != OpLog {
+				validateValue(typ, op.Path, "old", op.Old, reportOp)
+				validateValue(typ, op.Path, "new", op.New, reportOp)
+			}
+		case OpMove, OpCopy:
+			validatePath(typ, op.Path, true, reportOp)
+			if from, ok := op.Old.(string); ok {
+				validatePath(typ, from, op.Kind == OpMove, reportOp)
+			} else {
+				reportOp(op.Path, fmt.Errorf("%s requires a source path, got %T", op.Kind, op.Old))
+			}
+		default:
+			reportOp(op.Path, fmt.Errorf("unknown operation kind %d", int(op.Kind)))
+		}
+
+		validateCondition(typ, op.If, reportOp)
+		validateCondition(typ, op.Unless, reportOp)
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// validatePath checks that path exists in typ and, if write is set, that it
+// may be modified.
+func validatePath(typ reflect.Type, path string, write bool, report func(string, error)) {
+	fields, err := icore.FieldsAlongPath(typ, path)
+	if err != nil && !errors.Is(err, icore.ErrInterfacePath) {
+		report(path, err)
+		return
+	}
+	for _, f := range fields {
+		if f.Tag.Ignore {
+			report(path, fmt.Errorf("field %s is ignored (deep:\"-\")", f.Name))
+			return
+		}
+		if write && f.Tag.ReadOnly {
+			report(path, fmt.Errorf("field %s is read-only", f.Name))
+			return
+		}
+	}
+}
+
+// validateValue checks that v, the Old or New value of an operation, can be
+// stored at path. Problems with path itself are left to validatePath.
+func validateValue(typ reflect.Type, path, name string, v any, report func(string, error)) {
+	if v == nil {
+		return
+	}
+	at, err := icore.TypeAtPath(typ, path)
+	if err != nil {
+		return
+	}
+	if vt := reflect.TypeOf(v); !storable(vt, at) {
+		report(path, fmt.Errorf("%s value of type %v cannot be stored as %v", name, vt, at))
+	}
+}
+
+// storable reports whether a value of type vt can be stored in a value of type
+// at, as is or after the conversions Apply makes.
+func storable(vt, at reflect.Type) bool {
+	if vt.AssignableTo(at) {
+		return true
+	}
+	switch {
+	case at.Kind() == reflect.String && vt.Kind() >= reflect.Int && vt.Kind() <= reflect.Uintptr:
+		// Go converts integers to strings as code points, which is never
+		// what a patch means.
+		return false
+	case vt.ConvertibleTo(at):
+		return true
+	case at.Kind() == reflect.Pointer:
+		return vt.AssignableTo(at.Elem())
+	case at.Kind() == reflect.Struct:
+		// Structs decoded from JSON arrive as maps.
+		return vt.Kind() == reflect.Map && vt.Key().Kind() == reflect.String
+	}
+	return false
+}
+
+// validateCondition type-checks c and all its sub-conditions with
+// [condition.CheckAgainst].
+func validateCondition(typ reflect.Type, c *condition.Condition, report func(string, error)) {
+	err := condition.CheckAgainst(typ, c)
+	if err == nil {
+		return
+	}
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var ce *condition.CheckError
+		if errors.As(err, &ce) {
+			report(ce.Path, fmt.Errorf("condition %s: %w", ce.Op, ce.Err))
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package deep_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/brunoga/deep/v5"
+	"github.com/brunoga/deep/v5/condition"
+)
+
+type validateItem struct {
+	SKU int `deep:"key"`
+	Qty int
+}
+
+type validateDoc struct {
+	ID     string `deep:"readonly" json:"id"`
+	Secret string `deep:"-"`
+	Items  []validateItem
+	Tags   []string
+	Counts map[int]string
+	Extra  any
+	Name   string
+	N      int
+	Ref    *validateItem
+}
+
+func TestValidate(t *testing.T) {
+	valid := deep.Patch[validateDoc]{
+		Guard: &condition.Condition{Path: "/Items/1/Qty", Op: condition.Gt, Value: 0},
+		Operations: []deep.Operation{
+			{Kind: deep.OpReplace, Path: "/Items/42/Qty", New: 1},
+			{Kind: deep.OpAdd, Path: "/Tags/0", New: "a"},
+			{Kind: deep.OpAdd, Path: "/Counts/7", New: "seven"},
+			{Kind: deep.OpReplace, Path: "/Extra/anything", New: 1},
+			{Kind: deep.OpCopy, Path: "/Tags/1", Old: "/id"},
+			{Kind: deep.OpLog, Path: "/", New: "done"},
+			{Kind: deep.OpReplace, Path: "/N", Old: 1, New: 2.0},
+			{Kind: deep.OpReplace, Path: "/Ref", New: validateItem{SKU: 1}},
+			{Kind: deep.OpReplace, Path: "/Items/1", New: map[string]any{"Qty": 1.0}},
+			{Kind: deep.OpRemove, Path: "/Tags/0", Old: "a"},
+		},
+	}
+	if err := deep.Validate(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	invalid := deep.Patch[validateDoc]{
+		Guard: &condition.Condition{Op: condition.And, Sub: []*condition.Condition{
+			{Path: "/Missing", Op: condition.Exists},
+		}},
+		Operations: []deep.Operation{
+			{Kind: deep.OpReplace, Path: "/Items/abc/Qty", New: 1},
+			{Kind: deep.OpReplace, Path: "/Tags/x", New: "a"},
+			{Kind: deep.OpAdd, Path: "/Counts/seven", New: "seven"},
+			{Kind: deep.OpReplace, Path: "/id", New: "new"},
+			{Kind: deep.OpReplace, Path: "/Secret", New: "s"},
+			{Kind: deep.OpMove, Path: "/Tags/0", Old: "/id"},
+			{Kind: deep.OpReplace, Path: "/Tags/0", New: "b",
+				If: &condition.Condition{Path: "/Nope", Op: condition.Eq, Value: 1}},
+			{Kind: deep.OpReplace, Path: "/Tags/0", New: "c",
+				Unless: &condition.Condition{Path: "/Items/1/Qty", Op: condition.Gt, Value: "many"}},
+		},
+	}
+	err := deep.Validate(invalid)
+	var verr *deep.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+
+	wantPaths := []string{"/Missing", "/Items/abc/Qty", "/Tags/x", "/Counts/seven", "/id", "/Secret", "/id", "/Nope", "/Items/1/Qty"}
+	if len(verr.Errors) != len(wantPaths) {
+		t.Fatalf("expected %d problems, got %d:\n%v", len(wantPaths), len(verr.Errors), err)
+	}
+	for i, e := range verr.Errors {
+		var perr *deep.PathError
+		if !errors.As(e, &perr) || perr.Path != wantPaths[i] {
+			t.Errorf("problem %d: got %v, want path %s", i, e, wantPaths[i])
+		}
+	}
+	if !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("missing read-only problem in %v", err)
+	}
+}
+
+func TestValidateValueTypes(t *testing.T) {
+	p := deep.Patch[validateDoc]{
+		Operations: []deep.Operation{
+			{Kind: deep.OpReplace, Path: "/Name", New: 42},
+			{Kind: deep.OpReplace, Path: "/N", New: "abc"},
+			{Kind: deep.OpAdd, Path: "/Tags/0", New: []int{1}},
+			{Kind: deep.OpRemove, Path: "/Items/1", Old: "item"},
+			{Kind: deep.OpTest, Path: "/N", New: true},
+		},
+	}
+	err := deep.Validate(p)
+	var verr *deep.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if len(verr.Errors) != len(p.Operations) {
+		t.Fatalf("expected %d problems, got %d:\n%v", len(p.Operations), len(verr.Errors), err)
+	}
+	for i, e := range verr.Errors {
+		var perr *deep.PathError
+		if !errors.As(e, &perr) || perr.Op != i || !strings.Contains(perr.Error(), "cannot be stored") {
+			t.Errorf("problem %d: got %v", i, e)
+		}
+	}
+}